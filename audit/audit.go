@@ -0,0 +1,240 @@
+// Package audit 只追加的审计日志：记录谁在何时生成了什么（用户、offering key、prompt 哈希、结果 URL），
+// 并以哈希链防篡改。
+//
+// 每条记录带有连续的序号 Seq 和上一条记录的哈希 PrevHash，自身哈希 Hash 为除 Hash 外全部字段
+// 的 JSON 编码的 SHA-256。修改、删除或插入任何一条记录都会使之后的链校验失败，Verify 会指出
+// 第一条出问题的记录。截断末尾的记录无法由链本身发现，需要将 Verify 返回的 Report.LastHash
+// 定期保存到审计存储之外作为锚点进行比对。
+//
+// 记录由 Store 保存，内置 JSONL 文件（FileStore）和基于 gorm 的数据库表（GormStore）两种实现。
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/QingsiLiu/baseComponents/auth"
+	"github.com/QingsiLiu/baseComponents/log"
+	"github.com/QingsiLiu/baseComponents/utils"
+)
+
+// Action 审计动作
+type Action string
+
+const (
+	// ActionGenerate 生成内容（文生图、图生图、视频等）
+	ActionGenerate Action = "generate"
+	// ActionDownload 下载或分享生成结果
+	ActionDownload Action = "download"
+	// ActionDelete 删除生成结果
+	ActionDelete Action = "delete"
+)
+
+// appendAttempts Append 因其他实例抢先写入同一序号失败时的最大尝试次数
+const appendAttempts = 3
+
+var (
+	// ErrConflict 追加的记录序号不是当前最后一条的下一个
+	ErrConflict = errors.New("audit: sequence conflict")
+	// ErrGap 记录序号不连续，有记录被删除
+	ErrGap = errors.New("audit: sequence gap")
+	// ErrModified 记录内容与哈希不符，或无法解析
+	ErrModified = errors.New("audit: record modified")
+	// ErrBrokenLink 记录的 PrevHash 与上一条记录的哈希不符
+	ErrBrokenLink = errors.New("audit: broken hash link")
+)
+
+// Event 审计事件
+type Event struct {
+	ID          string            `json:"id"`                    // 事件唯一标识，为空时自动生成
+	Time        time.Time         `json:"time"`                  // 发生时间，为零值时取当前时间
+	Action      Action            `json:"action"`                // 动作
+	UserID      string            `json:"userID,omitempty"`      // 用户唯一标识
+	Username    string            `json:"username,omitempty"`    // 用户名
+	Role        string            `json:"role,omitempty"`        // 角色
+	OfferingKey string            `json:"offeringKey,omitempty"` // 计费项
+	PromptHash  string            `json:"promptHash,omitempty"`  // prompt 的 SHA-256，不保存原文
+	ResultURLs  []string          `json:"resultURLs,omitempty"`  // 生成结果的 URL
+	RequestID   string            `json:"requestID,omitempty"`   // 请求 ID
+	Metadata    map[string]string `json:"metadata,omitempty"`    // 其他数据(可选)
+}
+
+// GenerateEvent 创建生成内容的事件
+func GenerateEvent(prompt string, resultURLs ...string) Event {
+	return Event{
+		Action:     ActionGenerate,
+		PromptHash: HashPrompt(prompt),
+		ResultURLs: resultURLs,
+	}
+}
+
+// HashPrompt 计算 prompt 的哈希，审计记录只保存哈希以免泄露 prompt 原文
+func HashPrompt(prompt string) string {
+	if prompt == "" {
+		return ""
+	}
+
+	return utils.SHA256String(prompt)
+}
+
+// Record 哈希链上的一条记录
+type Record struct {
+	Seq uint64 `json:"seq"` // 序号，从 1 开始连续递增
+	Event
+	PrevHash string `json:"prevHash,omitempty"` // 上一条记录的哈希，第一条为空
+	Hash     string `json:"hash,omitempty"`     // 本条记录的哈希
+}
+
+// ComputeHash 计算记录的哈希：除 Hash 外全部字段的 JSON 编码的 SHA-256。
+// 时间统一为 UTC，空切片与空 map 视同未设置，保证经存储读写后哈希不变
+func (r Record) ComputeHash() string {
+	r.Hash = ""
+	r.Time = r.Time.UTC()
+	if len(r.ResultURLs) == 0 {
+		r.ResultURLs = nil
+	}
+	if len(r.Metadata) == 0 {
+		r.Metadata = nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		// 字段均为可编码类型，不会出错
+		panic(err)
+	}
+
+	return utils.SHA256(data)
+}
+
+// Filter 查询条件，零值字段不参与过滤
+type Filter struct {
+	UserID      string    // 用户唯一标识
+	Action      Action    // 动作
+	OfferingKey string    // 计费项
+	Since       time.Time // 发生时间下限（含）
+	Until       time.Time // 发生时间上限（不含）
+	Limit       int       // 最多返回条数，0 表示不限制
+}
+
+// Match 判断记录是否满足条件
+func (f Filter) Match(r Record) bool {
+	switch {
+	case f.UserID != "" && r.UserID != f.UserID:
+		return false
+	case f.Action != "" && r.Action != f.Action:
+		return false
+	case f.OfferingKey != "" && r.OfferingKey != f.OfferingKey:
+		return false
+	case !f.Since.IsZero() && r.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !r.Time.Before(f.Until):
+		return false
+	}
+
+	return true
+}
+
+// Store 审计记录存储
+type Store interface {
+	// Append 追加一条记录，rec.Seq 必须是当前最后一条记录的下一个
+	Append(ctx context.Context, rec Record) error
+	// Last 返回最后一条记录，没有记录时返回 Seq 为 0 的零值
+	Last(ctx context.Context) (Record, error)
+	// Scan 按序号升序遍历序号不小于 from 的记录，fn 返回错误时停止并返回该错误
+	Scan(ctx context.Context, from uint64, fn func(Record) error) error
+	// Query 按序号升序返回满足条件的记录
+	Query(ctx context.Context, filter Filter) ([]Record, error)
+}
+
+// Options 审计日志选项
+type Options struct {
+	Now func() time.Time // 当前时间，默认 time.Now
+}
+
+// Recorder 在 Store 上维护哈希链，并发安全
+type Recorder struct {
+	store Store
+	now   func() time.Time
+
+	mu   sync.Mutex
+	last Record
+}
+
+// NewRecorder 创建审计日志，从 store 中最后一条记录接续哈希链
+func NewRecorder(ctx context.Context, store Store, opts Options) (*Recorder, error) {
+	if store == nil {
+		return nil, errors.New("audit: requires a store")
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	last, err := store.Last(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("audit: load last record: %w", err)
+	}
+
+	return &Recorder{store: store, now: opts.Now, last: last}, nil
+}
+
+// Record 追加事件并返回写入的记录。
+// 事件未设置的用户信息取自 ctx 中的 auth.CustomClaims，请求 ID 和计费项取自 log 包写入 ctx 的值。
+// 时间截断到毫秒，与常见数据库的时间精度一致
+func (r *Recorder) Record(ctx context.Context, ev Event) (Record, error) {
+	if ev.Action == "" {
+		return Record{}, errors.New("audit: action is empty")
+	}
+	if ev.ID == "" {
+		ev.ID = uuid.New().String()
+	}
+	if ev.Time.IsZero() {
+		ev.Time = r.now()
+	}
+	ev.Time = ev.Time.UTC().Truncate(time.Millisecond)
+	if claims, ok := auth.FromContext(ctx); ok && ev.UserID == "" {
+		ev.UserID, ev.Username, ev.Role = claims.UserID, claims.Username, claims.Role
+	}
+	if ev.RequestID == "" {
+		ev.RequestID = log.RequestIDFrom(ctx)
+	}
+	if ev.OfferingKey == "" {
+		ev.OfferingKey, _ = ctx.Value(log.KeyOfferingKey).(string)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		rec := Record{Seq: r.last.Seq + 1, Event: ev, PrevHash: r.last.Hash}
+		rec.Hash = rec.ComputeHash()
+		err := r.store.Append(ctx, rec)
+		if err == nil {
+			r.last = rec
+
+			return rec, nil
+		}
+		if attempt == appendAttempts {
+			return Record{}, err
+		}
+		// 共用存储的其他实例可能已写入该序号，从存储接续后重试
+		last, lerr := r.store.Last(ctx)
+		if lerr != nil || last.Seq == r.last.Seq {
+			return Record{}, err
+		}
+		r.last = last
+	}
+}
+
+// Query 按序号升序返回满足条件的记录
+func (r *Recorder) Query(ctx context.Context, filter Filter) ([]Record, error) {
+	return r.store.Query(ctx, filter)
+}
+
+// Verify 校验存储中的哈希链
+func (r *Recorder) Verify(ctx context.Context) (Report, error) {
+	return Verify(ctx, r.store)
+}
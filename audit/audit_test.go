@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/QingsiLiu/baseComponents/auth"
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+func testClock() func() time.Time {
+	now := time.Date(2026, 5, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600))
+
+	return func() time.Time {
+		now = now.Add(time.Minute)
+
+		return now
+	}
+}
+
+func recordSamples(t *testing.T, store Store) *Recorder {
+	ctx := context.Background()
+	r, err := NewRecorder(ctx, store, Options{Now: testClock()})
+	assert.Nil(t, err)
+
+	alice := auth.WithUser(log.WithRequestID(ctx, "req-1"), &auth.CustomClaims{UserID: "u1", Username: "alice", Role: "admin"})
+	rec, err := r.Record(log.WithOfferingKey(alice, "image.hd"), GenerateEvent("a cat", "https://cdn/1.png", "https://cdn/2.png"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), rec.Seq)
+	assert.Empty(t, rec.PrevHash)
+	assert.Equal(t, "alice", rec.Username)
+	assert.Equal(t, "req-1", rec.RequestID)
+	assert.Equal(t, "image.hd", rec.OfferingKey)
+	assert.Equal(t, HashPrompt("a cat"), rec.PromptHash)
+	assert.Len(t, rec.ID, 36)
+
+	bob := auth.WithUser(ctx, &auth.CustomClaims{UserID: "u2", Username: "bob"})
+	second, err := r.Record(bob, Event{Action: ActionDownload, ResultURLs: []string{"https://cdn/3.png"}, Metadata: map[string]string{"ip": "10.0.0.1"}})
+	assert.Nil(t, err)
+	assert.Equal(t, rec.Hash, second.PrevHash)
+
+	_, err = r.Record(alice, Event{Action: ActionDelete})
+	assert.Nil(t, err)
+	_, err = r.Record(alice, Event{})
+	assert.NotNil(t, err)
+
+	return r
+}
+
+func assertQueries(t *testing.T, r *Recorder) {
+	ctx := context.Background()
+	records, err := r.Query(ctx, Filter{UserID: "u1"})
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, []string{"https://cdn/1.png", "https://cdn/2.png"}, records[0].ResultURLs)
+	assert.Equal(t, ActionDelete, records[1].Action)
+
+	start := time.Date(2026, 5, 1, 0, 2, 0, 0, time.UTC)
+	records, err = r.Query(ctx, Filter{Since: start, Until: start.Add(time.Minute)})
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "bob", records[0].Username)
+	assert.Equal(t, "10.0.0.1", records[0].Metadata["ip"])
+
+	records, err = r.Query(ctx, Filter{Action: ActionGenerate, OfferingKey: "image.hd", Limit: 1})
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+
+	records, err = r.Query(ctx, Filter{Limit: 2})
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	store, err := OpenFile(path)
+	assert.Nil(t, err)
+	r := recordSamples(t, store)
+	assertQueries(t, r)
+
+	report, err := r.Verify(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, report.Records)
+	assert.Equal(t, uint64(3), report.LastSeq)
+	assert.Nil(t, store.Close())
+
+	// reopening continues the chain
+	store, err = OpenFile(path)
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	r, err = NewRecorder(context.Background(), store, Options{})
+	assert.Nil(t, err)
+	rec, err := r.Record(context.Background(), Event{Action: ActionGenerate})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), rec.Seq)
+	assert.Equal(t, report.LastHash, rec.PrevHash)
+	assert.ErrorIs(t, store.Append(context.Background(), rec), ErrConflict)
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.SplitAfter(string(data), "\n")
+
+	verify := func(content string) error {
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+		_, err := Verify(context.Background(), store)
+
+		return err
+	}
+
+	err = verify(lines[0] + strings.Replace(lines[1], "bob", "eve", 1) + lines[2] + lines[3])
+	var verr *VerifyError
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, uint64(2), verr.Seq)
+	assert.ErrorIs(t, err, ErrModified)
+
+	err = verify(lines[0] + lines[2] + lines[3])
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, uint64(2), verr.Seq)
+	assert.ErrorIs(t, err, ErrGap)
+
+	err = verify(lines[0] + "{not json\n" + lines[2])
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, uint64(2), verr.Seq)
+	assert.ErrorIs(t, err, ErrModified)
+
+	// a consistently rehashed record still breaks the link to its successor
+	forged, err := OpenFile(filepath.Join(t.TempDir(), "forged.jsonl"))
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = forged.Close() })
+	assert.Nil(t, verify(strings.Join(lines, "")))
+	var recs []Record
+	assert.Nil(t, store.Scan(context.Background(), 1, func(rec Record) error {
+		recs = append(recs, rec)
+
+		return nil
+	}))
+	recs[1].Username = "eve"
+	recs[1].Hash = recs[1].ComputeHash()
+	for _, rec := range recs {
+		assert.Nil(t, forged.Append(context.Background(), rec))
+	}
+	_, err = Verify(context.Background(), forged)
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, uint64(3), verr.Seq)
+	assert.ErrorIs(t, err, ErrBrokenLink)
+}
+
+func TestGormStore(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "audit.db")), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	store, err := NewGormStore(db, "")
+	assert.Nil(t, err)
+	r := recordSamples(t, store)
+	assertQueries(t, r)
+
+	report, err := Verify(context.Background(), store)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, report.Records)
+
+	// another instance sharing the table appends first
+	other, err := NewRecorder(context.Background(), store, Options{})
+	assert.Nil(t, err)
+	_, err = other.Record(context.Background(), Event{Action: ActionGenerate})
+	assert.Nil(t, err)
+	rec, err := r.Record(context.Background(), Event{Action: ActionGenerate})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5), rec.Seq)
+	report, err = Verify(context.Background(), store)
+	assert.Nil(t, err)
+	assert.Equal(t, rec.Hash, report.LastHash)
+
+	assert.Nil(t, db.Table(DefaultTable).Where("seq = ?", 2).Update("user_id", "u1").Error)
+	_, err = Verify(context.Background(), store)
+	var verr *VerifyError
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, uint64(2), verr.Seq)
+	assert.ErrorIs(t, err, ErrModified)
+
+	assert.Nil(t, db.Table(DefaultTable).Where("seq = ?", 2).Delete(&recordModel{}).Error)
+	_, err = Verify(context.Background(), store)
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, uint64(2), verr.Seq)
+	assert.ErrorIs(t, err, ErrGap)
+}
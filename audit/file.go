@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// tailChunk 读取最后一条记录时每次向前读取的字节数
+const tailChunk = 4096
+
+// FileStore 以 JSONL 文件保存审计记录，每行一条。
+// 每次追加后同步落盘；同一文件只能由一个进程写入
+type FileStore struct {
+	path string
+
+	mu   sync.Mutex
+	f    *os.File
+	last Record
+}
+
+var _ Store = (*FileStore)(nil)
+
+// OpenFile 打开或创建 JSONL 审计文件
+func OpenFile(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{path: path, f: f}
+	line, err := lastLine(f)
+	if err == nil && len(line) > 0 {
+		if err = json.Unmarshal(line, &s.last); err != nil {
+			err = fmt.Errorf("%w: last line of %s: %v", ErrModified, path, err)
+		}
+	}
+	if err != nil {
+		_ = f.Close()
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// lastLine 从文件末尾向前读取最后一个非空行
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	for off := info.Size(); off > 0; {
+		n := min(int64(tailChunk), off)
+		off -= n
+		chunk := make([]byte, n)
+		if _, err := f.ReadAt(chunk, off); err != nil {
+			return nil, err
+		}
+		buf = append(chunk, buf...)
+		trimmed := bytes.TrimRight(buf, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+
+	return bytes.TrimRight(buf, "\n"), nil
+}
+
+// Append 追加一条记录
+func (s *FileStore) Append(_ context.Context, rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	if rec.Seq != s.last.Seq+1 {
+		return fmt.Errorf("%w: got %d, want %d", ErrConflict, rec.Seq, s.last.Seq+1)
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := s.write(append(data, '\n')); err != nil {
+		// 截断到写入前的大小，避免留下不完整的一行
+		if terr := s.f.Truncate(info.Size()); terr != nil {
+			return errors.Join(err, terr)
+		}
+
+		return err
+	}
+	s.last = rec
+
+	return nil
+}
+
+// write 写入一行并同步落盘
+func (s *FileStore) write(line []byte) error {
+	if _, err := s.f.Write(line); err != nil {
+		return err
+	}
+
+	return s.f.Sync()
+}
+
+// Last 返回最后一条记录
+func (s *FileStore) Last(context.Context) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.last, nil
+}
+
+// Scan 按序号升序遍历序号不小于 from 的记录。无法解析的行返回 ErrModified
+func (s *FileStore) Scan(ctx context.Context, from uint64, fn func(Record) error) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		data, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			if cerr := ctx.Err(); cerr != nil {
+				return cerr
+			}
+			var rec Record
+			if jerr := json.Unmarshal(data, &rec); jerr != nil {
+				return fmt.Errorf("%w: %s line %d: %v", ErrModified, s.path, line, jerr)
+			}
+			if rec.Seq >= from {
+				if ferr := fn(rec); ferr != nil {
+					return ferr
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Query 按序号升序返回满足条件的记录，需要遍历整个文件
+func (s *FileStore) Query(ctx context.Context, filter Filter) ([]Record, error) {
+	var records []Record
+	errStop := errors.New("stop")
+	err := s.Scan(ctx, 1, func(rec Record) error {
+		if !filter.Match(rec) {
+			return nil
+		}
+		records = append(records, rec)
+		if filter.Limit > 0 && len(records) >= filter.Limit {
+			return errStop
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// Close 关闭文件
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+
+	return err
+}
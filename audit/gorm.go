@@ -0,0 +1,210 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultTable GormStore 默认表名
+	DefaultTable = "audit_records"
+
+	scanBatch = 500
+)
+
+// recordModel 审计记录表结构，切片和 map 以 JSON 文本保存
+type recordModel struct {
+	Seq         uint64    `gorm:"primaryKey;autoIncrement:false"`
+	EventID     string    `gorm:"size:64;uniqueIndex"`
+	OccurredAt  time.Time `gorm:"index"`
+	Action      string    `gorm:"size:64;index"`
+	UserID      string    `gorm:"size:128;index"`
+	Username    string    `gorm:"size:255"`
+	Role        string    `gorm:"size:64"`
+	OfferingKey string    `gorm:"size:128;index"`
+	PromptHash  string    `gorm:"size:64"`
+	ResultURLs  string    `gorm:"type:text"`
+	RequestID   string    `gorm:"size:128"`
+	Metadata    string    `gorm:"type:text"`
+	PrevHash    string    `gorm:"size:64"`
+	Hash        string    `gorm:"size:64"`
+}
+
+// GormStore 以数据库表保存审计记录，序号为主键，多个实例可以共用同一张表
+type GormStore struct {
+	db    *gorm.DB
+	table string
+}
+
+var _ Store = (*GormStore)(nil)
+
+// NewGormStore 创建数据库审计存储并自动迁移表结构，table 为空时使用 DefaultTable
+func NewGormStore(db *gorm.DB, table string) (*GormStore, error) {
+	if db == nil {
+		return nil, errors.New("audit: requires a database")
+	}
+	if table == "" {
+		table = DefaultTable
+	}
+	if err := db.Table(table).AutoMigrate(&recordModel{}); err != nil {
+		return nil, fmt.Errorf("audit: migrate %s: %w", table, err)
+	}
+
+	return &GormStore{db: db, table: table}, nil
+}
+
+func (s *GormStore) session(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table(s.table)
+}
+
+// Append 追加一条记录，序号已存在时由主键约束拒绝
+func (s *GormStore) Append(ctx context.Context, rec Record) error {
+	m, err := toModel(rec)
+	if err != nil {
+		return err
+	}
+
+	return s.session(ctx).Create(&m).Error
+}
+
+// Last 返回最后一条记录
+func (s *GormStore) Last(ctx context.Context) (Record, error) {
+	var rows []recordModel
+	if err := s.session(ctx).Order("seq DESC").Limit(1).Find(&rows).Error; err != nil {
+		return Record{}, err
+	}
+	if len(rows) == 0 {
+		return Record{}, nil
+	}
+
+	return fromModel(rows[0])
+}
+
+// Scan 按序号升序分批遍历序号不小于 from 的记录
+func (s *GormStore) Scan(ctx context.Context, from uint64, fn func(Record) error) error {
+	for {
+		var rows []recordModel
+		if err := s.session(ctx).Where("seq >= ?", from).Order("seq").Limit(scanBatch).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			rec, err := fromModel(row)
+			if err != nil {
+				return err
+			}
+			if err := fn(rec); err != nil {
+				return err
+			}
+			from = row.Seq + 1
+		}
+		if len(rows) < scanBatch {
+			return nil
+		}
+	}
+}
+
+// Query 按序号升序返回满足条件的记录
+func (s *GormStore) Query(ctx context.Context, filter Filter) ([]Record, error) {
+	tx := s.session(ctx).Order("seq")
+	if filter.UserID != "" {
+		tx = tx.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Action != "" {
+		tx = tx.Where("action = ?", string(filter.Action))
+	}
+	if filter.OfferingKey != "" {
+		tx = tx.Where("offering_key = ?", filter.OfferingKey)
+	}
+	if !filter.Since.IsZero() {
+		tx = tx.Where("occurred_at >= ?", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		tx = tx.Where("occurred_at < ?", filter.Until.UTC())
+	}
+	if filter.Limit > 0 {
+		tx = tx.Limit(filter.Limit)
+	}
+	var rows []recordModel
+	if err := tx.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0, len(rows))
+	for _, row := range rows {
+		rec, err := fromModel(row)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+func toModel(rec Record) (recordModel, error) {
+	m := recordModel{
+		Seq:         rec.Seq,
+		EventID:     rec.ID,
+		OccurredAt:  rec.Time.UTC(),
+		Action:      string(rec.Action),
+		UserID:      rec.UserID,
+		Username:    rec.Username,
+		Role:        rec.Role,
+		OfferingKey: rec.OfferingKey,
+		PromptHash:  rec.PromptHash,
+		RequestID:   rec.RequestID,
+		PrevHash:    rec.PrevHash,
+		Hash:        rec.Hash,
+	}
+	if len(rec.ResultURLs) > 0 {
+		data, err := json.Marshal(rec.ResultURLs)
+		if err != nil {
+			return m, err
+		}
+		m.ResultURLs = string(data)
+	}
+	if len(rec.Metadata) > 0 {
+		data, err := json.Marshal(rec.Metadata)
+		if err != nil {
+			return m, err
+		}
+		m.Metadata = string(data)
+	}
+
+	return m, nil
+}
+
+func fromModel(m recordModel) (Record, error) {
+	rec := Record{
+		Seq: m.Seq,
+		Event: Event{
+			ID:          m.EventID,
+			Time:        m.OccurredAt.UTC(),
+			Action:      Action(m.Action),
+			UserID:      m.UserID,
+			Username:    m.Username,
+			Role:        m.Role,
+			OfferingKey: m.OfferingKey,
+			PromptHash:  m.PromptHash,
+			RequestID:   m.RequestID,
+		},
+		PrevHash: m.PrevHash,
+		Hash:     m.Hash,
+	}
+	if m.ResultURLs != "" {
+		if err := json.Unmarshal([]byte(m.ResultURLs), &rec.ResultURLs); err != nil {
+			return rec, fmt.Errorf("%w: result urls: %v", ErrModified, err)
+		}
+	}
+	if m.Metadata != "" {
+		if err := json.Unmarshal([]byte(m.Metadata), &rec.Metadata); err != nil {
+			return rec, fmt.Errorf("%w: metadata: %v", ErrModified, err)
+		}
+	}
+
+	return rec, nil
+}
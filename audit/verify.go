@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Report 哈希链校验结果
+type Report struct {
+	Records  int    // 校验通过的记录数
+	LastSeq  uint64 // 最后一条校验通过的记录序号
+	LastHash string // 最后一条校验通过的记录哈希，可保存到存储之外作为锚点
+}
+
+// VerifyError 哈希链校验失败，Err 为 ErrGap、ErrModified 或 ErrBrokenLink
+type VerifyError struct {
+	Seq uint64 // 第一条出问题的记录序号
+	Err error
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("audit: record %d: %v", e.Seq, e.Err)
+}
+
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// Verify 从第一条记录开始校验哈希链，检查序号连续、PrevHash 与上一条记录的哈希一致、
+// Hash 与内容一致。校验失败时返回 *VerifyError，Report 为出问题之前的部分
+func Verify(ctx context.Context, store Store) (Report, error) {
+	var report Report
+	err := store.Scan(ctx, 1, func(rec Record) error {
+		want := report.LastSeq + 1
+		switch {
+		case rec.Seq > want:
+			return &VerifyError{Seq: want, Err: ErrGap}
+		case rec.Seq < want:
+			return &VerifyError{Seq: rec.Seq, Err: fmt.Errorf("%w: duplicate sequence", ErrModified)}
+		case rec.PrevHash != report.LastHash:
+			return &VerifyError{Seq: rec.Seq, Err: ErrBrokenLink}
+		case rec.ComputeHash() != rec.Hash:
+			return &VerifyError{Seq: rec.Seq, Err: ErrModified}
+		}
+		report.Records++
+		report.LastSeq, report.LastHash = rec.Seq, rec.Hash
+
+		return nil
+	})
+
+	var verr *VerifyError
+	if err != nil && !errors.As(err, &verr) && errors.Is(err, ErrModified) {
+		// 存储中无法解析的记录
+		err = &VerifyError{Seq: report.LastSeq + 1, Err: err}
+	}
+
+	return report, err
+}
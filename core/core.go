@@ -26,13 +26,16 @@ type Response struct {
 // WriteResponse write an error or the response data into http response body.
 // It use errors.ParseCoder to parse any error into errors.Coder
 // errors.Coder contains error code, user-safe error message and http status code.
+// The message is localized by errors.Localize in the language set under
+// errors.LanguageKey on the gin or request context, or else the best match
+// of the Accept-Language header.
 func WriteResponse(c *gin.Context, err error, data interface{}) {
 	if err != nil {
 		log.L(c).Errorw("API Error", "error", fmt.Sprintf("%#+v", err))
 		coder := errors.ParseCoder(err)
 		c.JSON(coder.HTTPStatus(), Response{
 			Code:    coder.Code(),
-			Message: coder.String(),
+			Message: errors.Localize(coder, Language(c)),
 			Data:    nil,
 		})
 
@@ -45,3 +48,17 @@ func WriteResponse(c *gin.Context, err error, data interface{}) {
 		Data:    data,
 	})
 }
+
+// Language returns the response language of the request: the value set under
+// errors.LanguageKey on c or its request context, or else the best supported
+// match of the Accept-Language header.
+func Language(c *gin.Context) string {
+	if lang := c.GetString(errors.LanguageKey); lang != "" {
+		return lang
+	}
+	if lang := errors.LanguageFrom(c.Request.Context()); lang != "" {
+		return lang
+	}
+
+	return errors.MatchLanguage(c.GetHeader("Accept-Language"))
+}
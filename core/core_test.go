@@ -0,0 +1,57 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/QingsiLiu/baseComponents/errors"
+	"github.com/QingsiLiu/baseComponents/storage"
+)
+
+func TestWriteResponseLanguage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/missing", func(c *gin.Context) {
+		WriteResponse(c, storage.ErrNotFound, nil)
+	})
+	r.GET("/forced", func(c *gin.Context) {
+		c.Set(errors.LanguageKey, "en")
+		WriteResponse(c, storage.ErrNotFound, nil)
+	})
+	r.GET("/ctx", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(errors.WithLanguage(c.Request.Context(), "zh-CN"))
+		WriteResponse(c, storage.ErrNotFound, nil)
+	})
+
+	tests := []struct {
+		path   string
+		accept string
+		want   string
+	}{
+		{"/missing", "", "Object not found"},
+		{"/missing", "zh-CN,zh;q=0.9,en;q=0.8", "对象不存在"},
+		{"/missing", "fr, en;q=0.5", "Object not found"},
+		{"/forced", "zh-CN", "Object not found"},
+		{"/ctx", "en", "对象不存在"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept-Language", tt.accept)
+		}
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		var resp Response
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, storage.CodeNotFound, resp.Code)
+		assert.Equal(t, tt.want, resp.Message, tt.path+" "+tt.accept)
+	}
+}
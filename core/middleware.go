@@ -0,0 +1,37 @@
+package core
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+// XRequestIDKey defines the header used to propagate the request ID.
+const XRequestIDKey = "X-Request-ID"
+
+// RequestID is a middleware that reads the request ID from the X-Request-ID
+// header, or generates one, and echoes it in the response header.
+// The ID is stored on both the gin.Context and the request context, and a
+// request scoped logger carrying the registered context fields is attached
+// via WithContext, so log.L(c) and log.FromContext(c.Request.Context())
+// include them.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rid := c.GetHeader(XRequestIDKey)
+		if rid == "" {
+			rid = uuid.New().String()
+			c.Request.Header.Set(XRequestIDKey, rid)
+		}
+		c.Header(XRequestIDKey, rid)
+		c.Set(log.KeyRequestID, rid)
+
+		ctx := log.WithRequestID(c.Request.Context(), rid)
+		logger := log.L(ctx)
+		c.Request = c.Request.WithContext(logger.WithContext(ctx))
+		// gin.Context only resolves string keys without ContextWithFallback
+		c.Set(log.ContextLoggerKey, logger)
+
+		c.Next()
+	}
+}
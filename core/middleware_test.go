@@ -0,0 +1,60 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/QingsiLiu/baseComponents/errors"
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+func TestRequestID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "core.log")
+	opts := log.NewOptions()
+	opts.Format = "json"
+	opts.OutputPaths = []string{path}
+	log.Init(opts)
+	t.Cleanup(func() { log.Init(log.NewOptions()) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/fail", func(c *gin.Context) {
+		// values set by later middlewares are picked up as well
+		c.Set(log.KeyTenantID, "tenant-1")
+		assert.NotEmpty(t, log.RequestIDFrom(c.Request.Context()))
+		WriteResponse(c, errors.New("boom"), nil)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(XRequestIDKey, "req-42")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "req-42", w.Header().Get(XRequestIDKey))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	generated := w.Header().Get(XRequestIDKey)
+	assert.Len(t, generated, 36)
+
+	log.Flush()
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+	for i, want := range []string{"req-42", generated} {
+		var entry map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(lines[i]), &entry))
+		assert.Equal(t, want, entry[log.KeyRequestID])
+		assert.Equal(t, "tenant-1", entry[log.KeyTenantID])
+		assert.Equal(t, 1, strings.Count(lines[i], `"`+log.KeyRequestID+`"`))
+	}
+}
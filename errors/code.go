@@ -39,6 +39,12 @@ type defaultCoder struct {
 	Ref string
 }
 
+// NewCoder returns a Coder with the given code, HTTP status, external
+// message and reference document, ready for Register or MustRegister.
+func NewCoder(code, httpStatus int, ext, ref string) Coder {
+	return defaultCoder{C: code, HTTP: httpStatus, Ext: ext, Ref: ref}
+}
+
 // Code returns the integer code of the coder.
 func (coder defaultCoder) Code() int {
 	return coder.C
@@ -102,21 +108,51 @@ func MustRegister(coder Coder) {
 
 // ParseCoder parse any error into *withCode.
 // nil error will return nil direct.
-// None withStack error will be parsed as ErrUnknown.
+// Params attached by WithCodeParams are rendered into the returned Coder's
+// String and Localize results.
+// None withCode error will be parsed as ErrUnknown, including errors that
+// only wrap one; use ParseCoderChain to look through wrappers.
 func ParseCoder(err error) Coder {
 	if err == nil {
 		return nil
 	}
 
 	if v, ok := err.(*withCode); ok {
-		if coder, ok := codes[v.code]; ok {
-			return coder
-		}
+		return coderOf(v)
 	}
 
 	return unknownCoder
 }
 
+// ParseCoderChain is like ParseCoder, but uses the first *withCode in err's
+// chain, so coded errors wrapped with fmt.Errorf("%w") or a multi-error
+// Unwrap are still recognized.
+func ParseCoderChain(err error) Coder {
+	if err == nil {
+		return nil
+	}
+
+	var v *withCode
+	if As(err, &v) {
+		return coderOf(v)
+	}
+
+	return unknownCoder
+}
+
+// coderOf returns the registered coder of v with its params, or ErrUnknown.
+func coderOf(v *withCode) Coder {
+	coder, ok := codes[v.code]
+	if !ok {
+		return unknownCoder
+	}
+	if v.params != nil {
+		return paramCoder{Coder: coder, params: v.params}
+	}
+
+	return coder
+}
+
 // IsCode reports whether any error in err's chain contains the given error code.
 func IsCode(err error, code int) bool {
 	if v, ok := err.(*withCode); ok {
@@ -134,6 +170,18 @@ func IsCode(err error, code int) bool {
 	return false
 }
 
+// lookupCoder returns the registered coder of code, or ErrUnknown.
+func lookupCoder(code int) Coder {
+	codeMux.Lock()
+	defer codeMux.Unlock()
+
+	if coder, ok := codes[code]; ok {
+		return coder
+	}
+
+	return unknownCoder
+}
+
 func init() {
 	codes[unknownCoder.Code()] = unknownCoder
 }
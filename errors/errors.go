@@ -149,10 +149,11 @@ func WithStack(err error) error {
 
 	if e, ok := err.(*withCode); ok {
 		return &withCode{
-			err:   e.err,
-			code:  e.code,
-			cause: err,
-			stack: callers(),
+			err:    e.err,
+			code:   e.code,
+			params: e.params,
+			cause:  err,
+			stack:  callers(),
 		}
 	}
 
@@ -203,10 +204,11 @@ func Wrap(err error, message string) error {
 	}
 	if e, ok := err.(*withCode); ok {
 		return &withCode{
-			err:   fmt.Errorf("%s", message),
-			code:  e.code,
-			cause: err,
-			stack: callers(),
+			err:    fmt.Errorf("%s", message),
+			code:   e.code,
+			params: e.params,
+			cause:  err,
+			stack:  callers(),
 		}
 	}
 
@@ -230,10 +232,11 @@ func Wrapf(err error, format string, args ...interface{}) error {
 
 	if e, ok := err.(*withCode); ok {
 		return &withCode{
-			err:   fmt.Errorf(format, args...),
-			code:  e.code,
-			cause: err,
-			stack: callers(),
+			err:    fmt.Errorf(format, args...),
+			code:   e.code,
+			params: e.params,
+			cause:  err,
+			stack:  callers(),
 		}
 	}
 
@@ -297,9 +300,10 @@ func (w *withMessage) Format(s fmt.State, verb rune) {
 }
 
 type withCode struct {
-	err   error
-	code  int
-	cause error
+	err    error
+	code   int
+	params map[string]interface{}
+	cause  error
 	*stack
 }
 
@@ -324,6 +328,18 @@ func WrapC(err error, code int, format string, args ...interface{}) error {
 	}
 }
 
+// WithCodeParams returns an error with the given code carrying params for
+// the templated messages of the code, e.g. "File exceeds {{.limit}} MB".
+// The error text is the code's default message rendered with params.
+func WithCodeParams(code int, params map[string]interface{}) error {
+	return &withCode{
+		err:    fmt.Errorf("%s", render(lookupCoder(code).String(), params)),
+		code:   code,
+		params: params,
+		stack:  callers(),
+	}
+}
+
 // Error return the externally-safe error message.
 func (w *withCode) Error() string { return fmt.Sprintf("%v", w) }
 
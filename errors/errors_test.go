@@ -327,6 +327,8 @@ func TestErrorEquality(t *testing.T) {
 }
 
 func TestParseCoder(t *testing.T) {
+	Register(defaultCoder{999001, 404, "Resource not found", ""})
+
 	tests := []struct {
 		err           error
 		wantHTTPCode  int
@@ -336,6 +338,9 @@ func TestParseCoder(t *testing.T) {
 	}{
 		{fmt.Errorf("yes error"), 500, "An internal server error occurred", 1, "http://github.com/QingsiLiu/baseComponents/errors/README.md"},
 		{WithCode(unknownCoder.Code(), "internal error message"), 500, "An internal server error occurred", 1, "http://github.com/QingsiLiu/baseComponents/errors/README.md"},
+		// wrappers without a code of their own stay unknown
+		{fmt.Errorf("wrapped: %w", WithCode(999001, "not found")), 500, "An internal server error occurred", 1, "http://github.com/QingsiLiu/baseComponents/errors/README.md"},
+		{Wrap(WithCode(999001, "not found"), "wrapped"), 404, "Resource not found", 999001, ""},
 	}
 
 	for i, tt := range tests {
@@ -358,3 +363,26 @@ func TestParseCoder(t *testing.T) {
 	}
 
 }
+
+func TestParseCoderChain(t *testing.T) {
+	Register(defaultCoder{999001, 404, "Resource not found", ""})
+
+	tests := []struct {
+		err      error
+		wantCode int
+	}{
+		{fmt.Errorf("yes error"), 1},
+		{WithCode(999001, "not found"), 999001},
+		{fmt.Errorf("wrapped: %w", WithCode(999001, "not found")), 999001},
+		{errors.Join(fmt.Errorf("yes error"), WithCode(999001, "not found")), 999001},
+	}
+
+	for i, tt := range tests {
+		if got := ParseCoderChain(tt.err).Code(); got != tt.wantCode {
+			t.Errorf("TestParseCoderChain(%d): got %d, want: %d", i, got, tt.wantCode)
+		}
+	}
+	if ParseCoderChain(nil) != nil {
+		t.Error("TestParseCoderChain(nil): want nil")
+	}
+}
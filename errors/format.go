@@ -181,7 +181,7 @@ func buildFormatInfo(e error) *formatInfo {
 			coder = unknownCoder
 		}
 
-		extMsg := coder.String()
+		extMsg := render(coder.String(), err.params)
 		if extMsg == "" {
 			extMsg = err.err.Error()
 		}
@@ -0,0 +1,316 @@
+package errors
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLanguage is the language of Coder.String, used when no catalog
+// matches the requested language.
+const DefaultLanguage = "en"
+
+// LanguageKey is the context key carrying the preferred language of the
+// response. It is a plain string so that gin.Context.Set resolves it too.
+const LanguageKey = "language"
+
+//go:embed locales
+var builtinLocales embed.FS
+
+// catalogs maps a normalized language tag to the message templates by code.
+var catalogs = map[string]map[int]*template.Template{}
+var catalogMux = &sync.RWMutex{}
+
+// extTemplates caches the parsed templates of Coder.String messages.
+var extTemplates sync.Map
+
+// paramCoder is the Coder returned by ParseCoder for errors created with
+// WithCodeParams.
+type paramCoder struct {
+	Coder
+	params map[string]interface{}
+}
+
+// String returns the external error message rendered with the params.
+func (c paramCoder) String() string {
+	return render(c.Coder.String(), c.params)
+}
+
+// RegisterMessages adds the messages of lang to the catalog, overriding
+// existing messages of the same codes. Messages are text/template strings
+// executed with the params of WithCodeParams, e.g. "文件不能超过 {{.limit}} MB".
+func RegisterMessages(lang string, messages map[int]string) error {
+	lang = normalizeLanguage(lang)
+	if lang == "" {
+		return New("language is empty")
+	}
+
+	parsed := make(map[int]*template.Template, len(messages))
+	for code, msg := range messages {
+		tmpl, err := parseMessage(msg)
+		if err != nil {
+			return Wrapf(err, "parse message %d of %s", code, lang)
+		}
+		parsed[code] = tmpl
+	}
+
+	catalogMux.Lock()
+	defer catalogMux.Unlock()
+
+	catalog := catalogs[lang]
+	if catalog == nil {
+		catalog = make(map[int]*template.Template, len(parsed))
+		catalogs[lang] = catalog
+	}
+	for code, tmpl := range parsed {
+		catalog[code] = tmpl
+	}
+
+	return nil
+}
+
+// LoadMessages loads the catalogs matched by the glob patterns from fsys,
+// typically an embed.FS. Files are YAML (.yaml, .yml) or JSON (.json) maps
+// from code to message, named after their language: "zh.yaml",
+// "zh-TW.json", or "storage.zh.yaml" to split a language across files.
+func LoadMessages(fsys fs.FS, patterns ...string) error {
+	for _, pattern := range patterns {
+		files, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := loadMessageFile(fsys, file); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func loadMessageFile(fsys fs.FS, file string) error {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return err
+	}
+
+	ext := path.Ext(file)
+	stem := strings.TrimSuffix(path.Base(file), ext)
+	lang := stem[strings.LastIndexByte(stem, '.')+1:]
+
+	messages := map[int]string{}
+	switch ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &messages)
+	case ".json":
+		var raw map[string]string
+		if err = json.Unmarshal(data, &raw); err != nil {
+			break
+		}
+		for key, msg := range raw {
+			code, cerr := strconv.Atoi(key)
+			if cerr != nil {
+				return Errorf("%s: invalid code %q", file, key)
+			}
+			messages[code] = msg
+		}
+	default:
+		return Errorf("%s: unsupported message file", file)
+	}
+	if err != nil {
+		return Wrapf(err, "decode %s", file)
+	}
+
+	return RegisterMessages(lang, messages)
+}
+
+// Languages returns the languages with a catalog, plus DefaultLanguage.
+func Languages() []string {
+	catalogMux.RLock()
+	defer catalogMux.RUnlock()
+
+	langs := []string{DefaultLanguage}
+	for lang := range catalogs {
+		if lang != DefaultLanguage {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs[1:])
+
+	return langs
+}
+
+// Localize returns the external error message of coder in lang. A regional
+// tag such as "zh-CN" falls back to "zh", and a language without a message
+// for the code falls back to coder.String(). Params attached by
+// WithCodeParams are rendered into the message; a message referring to a
+// missing param falls back to coder.String() as well.
+func Localize(coder Coder, lang string) string {
+	if coder == nil {
+		return ""
+	}
+
+	var params map[string]interface{}
+	if c, ok := coder.(paramCoder); ok {
+		params = c.params
+	}
+
+	catalogMux.RLock()
+	tmpl := lookupMessage(lang, coder.Code())
+	catalogMux.RUnlock()
+	if tmpl == nil {
+		return coder.String()
+	}
+
+	msg, err := execute(tmpl, params)
+	if err != nil {
+		return coder.String()
+	}
+
+	return msg
+}
+
+// lookupMessage returns the template of code for lang or its parent tags.
+// The caller must hold catalogMux.
+func lookupMessage(lang string, code int) *template.Template {
+	for tag := normalizeLanguage(lang); tag != ""; tag = parentLanguage(tag) {
+		if tmpl, ok := catalogs[tag][code]; ok {
+			return tmpl
+		}
+	}
+
+	return nil
+}
+
+// MatchLanguage returns the best supported language for an Accept-Language
+// header value such as "zh-CN,zh;q=0.9,en;q=0.8", or DefaultLanguage when
+// none of the accepted languages has a catalog.
+func MatchLanguage(accept string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var accepted []weighted
+	for _, part := range strings.Split(accept, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if tag = normalizeLanguage(tag); tag != "" && q > 0 {
+			accepted = append(accepted, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].q > accepted[j].q })
+
+	catalogMux.RLock()
+	defer catalogMux.RUnlock()
+
+	for _, a := range accepted {
+		for tag := a.tag; tag != ""; tag = parentLanguage(tag) {
+			if tag == DefaultLanguage || tag == "*" {
+				return DefaultLanguage
+			}
+			if _, ok := catalogs[tag]; ok {
+				return tag
+			}
+		}
+	}
+
+	return DefaultLanguage
+}
+
+// WithLanguage returns a copy of ctx carrying the preferred language.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	//nolint:staticcheck // string keys are shared with gin.Context
+	return context.WithValue(ctx, LanguageKey, lang)
+}
+
+// LanguageFrom returns the preferred language carried by ctx.
+func LanguageFrom(ctx context.Context) string {
+	lang, _ := ctx.Value(LanguageKey).(string)
+
+	return lang
+}
+
+// normalizeLanguage lowercases a language tag and uses "-" as separator.
+func normalizeLanguage(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
+
+// parentLanguage strips the last subtag: "zh-hans-cn" -> "zh-hans" -> "zh" -> "".
+func parentLanguage(tag string) string {
+	if i := strings.LastIndexByte(tag, '-'); i > 0 {
+		return tag[:i]
+	}
+
+	return ""
+}
+
+func parseMessage(msg string) (*template.Template, error) {
+	return template.New("").Option("missingkey=error").Parse(msg)
+}
+
+// render executes msg as a template with params. Messages without actions,
+// messages that fail to parse and messages referring to missing params are
+// returned as is.
+func render(msg string, params map[string]interface{}) string {
+	if !strings.Contains(msg, "{{") {
+		return msg
+	}
+	cached, ok := extTemplates.Load(msg)
+	if !ok {
+		tmpl, err := parseMessage(msg)
+		if err != nil {
+			extTemplates.Store(msg, err)
+
+			return msg
+		}
+		extTemplates.Store(msg, tmpl)
+		cached = tmpl
+	}
+
+	tmpl, ok := cached.(*template.Template)
+	if !ok {
+		return msg
+	}
+	rendered, err := execute(tmpl, params)
+	if err != nil {
+		return msg
+	}
+
+	return rendered
+}
+
+// execute renders tmpl with params. On error nothing of the partial output
+// is returned, so callers fall back to a message of their own.
+func execute(tmpl *template.Template, params map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func init() {
+	if err := LoadMessages(builtinLocales, "locales/*"); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,133 @@
+package errors
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"testing/fstest"
+)
+
+const codeFileTooLarge = 200001
+
+func init() {
+	Register(defaultCoder{codeFileTooLarge, http.StatusBadRequest, "File exceeds {{.limit}} MB", ""})
+}
+
+func TestLocalize(t *testing.T) {
+	if err := RegisterMessages("zh", map[int]string{codeFileTooLarge: "文件不能超过 {{.limit}} MB"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := WithCodeParams(codeFileTooLarge, map[string]interface{}{"limit": 10})
+	if got, want := err.Error(), "File exceeds 10 MB"; got != want {
+		t.Errorf("Error(): got %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		err  error
+		lang string
+		want string
+	}{
+		{err, "zh", "文件不能超过 10 MB"},
+		{Wrap(err, "upload failed"), "zh_CN", "文件不能超过 10 MB"},
+		{err, "fr", "File exceeds 10 MB"},
+		{err, "", "File exceeds 10 MB"},
+		// a missing param falls back to the raw default message
+		{WithCode(codeFileTooLarge, "too large"), "zh", "File exceeds {{.limit}} MB"},
+		{WithCodeParams(codeFileTooLarge, map[string]interface{}{"size": 10}), "zh", "File exceeds {{.limit}} MB"},
+		{New("boom"), "zh-Hans-CN", "服务器内部错误"},
+		{New("boom"), "en", "An internal server error occurred"},
+	}
+
+	for i, tt := range tests {
+		if got := Localize(ParseCoder(tt.err), tt.lang); got != tt.want {
+			t.Errorf("test %d: got %q, want %q", i+1, got, tt.want)
+		}
+	}
+
+	if got, want := ParseCoder(err).String(), "File exceeds 10 MB"; got != want {
+		t.Errorf("String(): got %q, want %q", got, want)
+	}
+	missing := WithCodeParams(codeFileTooLarge, nil)
+	if got, want := missing.Error(), "File exceeds {{.limit}} MB"; got != want {
+		t.Errorf("missing params Error(): got %q, want %q", got, want)
+	}
+	if got, want := ParseCoder(missing).String(), "File exceeds {{.limit}} MB"; got != want {
+		t.Errorf("missing params String(): got %q, want %q", got, want)
+	}
+	if got := Localize(nil, "zh"); got != "" {
+		t.Errorf("nil coder: got %q", got)
+	}
+}
+
+func TestLoadMessages(t *testing.T) {
+	fsys := fstest.MapFS{
+		"i18n/app.ja.yaml": {Data: []byte("200001: ファイルは {{.limit}} MB 以下にしてください\n")},
+		"i18n/de.json":     {Data: []byte(`{"200001": "Datei größer als {{.limit}} MB"}`)},
+		"bad/fr.json":      {Data: []byte(`{"x": "y"}`)},
+		"bad/fr.txt":       {Data: []byte("200001: y")},
+		"bad/it.yaml":      {Data: []byte("200001: '{{.limit'")},
+	}
+	if err := LoadMessages(fsys, "i18n/*"); err != nil {
+		t.Fatal(err)
+	}
+	for _, pattern := range []string{"bad/fr.json", "bad/fr.txt", "bad/it.yaml"} {
+		if err := LoadMessages(fsys, pattern); err == nil {
+			t.Errorf("%s: expected error", pattern)
+		}
+	}
+
+	coder := ParseCoder(WithCodeParams(codeFileTooLarge, map[string]interface{}{"limit": 5}))
+	if got, want := Localize(coder, "ja-JP"), "ファイルは 5 MB 以下にしてください"; got != want {
+		t.Errorf("ja: got %q, want %q", got, want)
+	}
+	if got, want := Localize(coder, "de"), "Datei größer als 5 MB"; got != want {
+		t.Errorf("de: got %q, want %q", got, want)
+	}
+
+	langs := Languages()
+	if langs[0] != DefaultLanguage {
+		t.Errorf("Languages: got %v", langs)
+	}
+	for _, lang := range []string{"de", "ja", "zh"} {
+		if !contains(langs, lang) {
+			t.Errorf("Languages: %v missing %s", langs, lang)
+		}
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", DefaultLanguage},
+		{"zh-CN,zh;q=0.9,en;q=0.8", "zh"},
+		{"en-US,en;q=0.9,zh;q=0.8", "en"},
+		{"fr, zh;q=0.5", "zh"},
+		{"fr;q=0.2, ZH-tw;q=0.8", "zh"},
+		{"zh;q=0, fr", DefaultLanguage},
+		{"fr, *;q=0.1", DefaultLanguage},
+	}
+
+	for _, tt := range tests {
+		if got := MatchLanguage(tt.accept); got != tt.want {
+			t.Errorf("MatchLanguage(%q): got %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+
+	ctx := WithLanguage(context.Background(), "zh")
+	if got := LanguageFrom(ctx); got != "zh" {
+		t.Errorf("LanguageFrom: got %q", got)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,461 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AsyncScheme is the zap sink scheme of asynchronous outputs, e.g.
+// "async:?target=stdout&buffer_size=8192&overflow=drop-oldest".
+const AsyncScheme = "async"
+
+const (
+	defaultAsyncBufferSize    = 8192
+	defaultAsyncFlushInterval = time.Second
+)
+
+func init() {
+	if err := zap.RegisterSink(AsyncScheme, newAsyncSink); err != nil {
+		panic(err)
+	}
+}
+
+// OverflowPolicy decides what an AsyncWriter does with an entry when its buffer is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits until the background writer makes room.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest discards the oldest buffered entry.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDropNewest discards the entry being written.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+)
+
+// AsyncOptions configures asynchronous writing of the local output paths. Entries are
+// buffered in memory and written in batches every FlushInterval, once half of
+// the buffer is used, or on Flush.
+type AsyncOptions struct {
+	Enabled       bool           `json:"enabled"        mapstructure:"enabled"`
+	BufferSize    int            `json:"buffer-size"    mapstructure:"buffer-size"`    // entries, 8192 by default
+	Overflow      OverflowPolicy `json:"overflow"       mapstructure:"overflow"`       // OverflowBlock by default
+	FlushInterval time.Duration  `json:"flush-interval" mapstructure:"flush-interval"` // 1s by default
+}
+
+// validate reports invalid async options.
+func (o AsyncOptions) validate() error {
+	if o.BufferSize < 0 || o.FlushInterval < 0 {
+		return fmt.Errorf("log async options must not be negative")
+	}
+	switch o.Overflow {
+	case "", OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+		return nil
+	default:
+		return fmt.Errorf("invalid log overflow policy %q, want %s, %s or %s",
+			o.Overflow, OverflowBlock, OverflowDropOldest, OverflowDropNewest)
+	}
+}
+
+func (o AsyncOptions) withDefaults() AsyncOptions {
+	if o.BufferSize <= 0 {
+		o.BufferSize = defaultAsyncBufferSize
+	}
+	if o.Overflow == "" {
+		o.Overflow = OverflowBlock
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = defaultAsyncFlushInterval
+	}
+
+	return o
+}
+
+// asyncPaths wraps the local paths in an async sink URL when async writing is
+// enabled. Other URL sinks are left as is: remote sinks queue entries
+// themselves and expect one entry per write.
+func asyncPaths(paths []string, opts AsyncOptions) []string {
+	if !opts.Enabled {
+		return paths
+	}
+
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !localPath(path) {
+			result = append(result, path)
+
+			continue
+		}
+		values := url.Values{}
+		values.Set("target", path)
+		if opts.BufferSize > 0 {
+			values.Set("buffer_size", strconv.Itoa(opts.BufferSize))
+		}
+		if opts.Overflow != "" {
+			values.Set("overflow", string(opts.Overflow))
+		}
+		if opts.FlushInterval > 0 {
+			values.Set("flush_interval", opts.FlushInterval.String())
+		}
+		result = append(result, (&url.URL{Scheme: AsyncScheme, RawQuery: values.Encode()}).String())
+	}
+
+	return result
+}
+
+// localPath reports whether path is a standard stream, a file or a rotated file.
+func localPath(path string) bool {
+	scheme, _, found := strings.Cut(path, "://")
+
+	return !found || scheme == "file" || scheme == RotateScheme
+}
+
+// parseAsyncURL parses an async sink URL into the target path and options.
+func parseAsyncURL(u *url.URL) (string, AsyncOptions, error) {
+	opts := AsyncOptions{Enabled: true}
+	query := u.Query()
+	target := query.Get("target")
+	if target == "" {
+		return "", opts, errors.New("async sink URL requires a target")
+	}
+	if value := query.Get("buffer_size"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return "", opts, fmt.Errorf("invalid async sink parameter buffer_size=%q", value)
+		}
+		opts.BufferSize = n
+	}
+	if value := query.Get("flush_interval"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return "", opts, fmt.Errorf("invalid async sink parameter flush_interval=%q", value)
+		}
+		opts.FlushInterval = d
+	}
+	opts.Overflow = OverflowPolicy(query.Get("overflow"))
+	if err := opts.validate(); err != nil {
+		return "", opts, err
+	}
+
+	return target, opts.withDefaults(), nil
+}
+
+var (
+	asyncWritersMu sync.Mutex
+	asyncWriters   = map[string]*asyncSink{}
+)
+
+// asyncSink shares one AsyncWriter between every sink opened for the same
+// target, so that repeated Init calls do not start a writer each. The options
+// of the first sink apply until the writer is stopped by Shutdown.
+type asyncSink struct {
+	*AsyncWriter
+	closeTarget func()
+	refs        int
+}
+
+func newAsyncSink(u *url.URL) (zap.Sink, error) {
+	target, opts, err := parseAsyncURL(u)
+	if err != nil {
+		return nil, err
+	}
+
+	asyncWritersMu.Lock()
+	defer asyncWritersMu.Unlock()
+
+	if sink, ok := asyncWriters[target]; ok && !sink.stopped() {
+		sink.refs++
+
+		return &sharedAsyncSink{sink: sink, target: target}, nil
+	}
+	ws, closeTarget, err := zap.Open(target)
+	if err != nil {
+		return nil, err
+	}
+	sink := &asyncSink{AsyncWriter: NewAsyncWriter(ws, opts), closeTarget: closeTarget, refs: 1}
+	asyncWriters[target] = sink
+
+	return &sharedAsyncSink{sink: sink, target: target}, nil
+}
+
+// sharedAsyncSink is one reference to a shared asyncSink.
+type sharedAsyncSink struct {
+	sink   *asyncSink
+	target string
+	closed sync.Once
+}
+
+func (s *sharedAsyncSink) Write(p []byte) (int, error) { return s.sink.Write(p) }
+func (s *sharedAsyncSink) Sync() error                 { return s.sink.Sync() }
+
+func (s *sharedAsyncSink) Close() error {
+	var err error
+	s.closed.Do(func() {
+		asyncWritersMu.Lock()
+		defer asyncWritersMu.Unlock()
+
+		s.sink.refs--
+		if s.sink.refs == 0 {
+			if asyncWriters[s.target] == s.sink {
+				delete(asyncWriters, s.target)
+			}
+			err = s.sink.Stop()
+			s.sink.closeTarget()
+		}
+	})
+
+	return err
+}
+
+// AsyncWriter is a zapcore.WriteSyncer that buffers writes in a bounded ring
+// and writes them to the underlying WriteSyncer from a background goroutine.
+// Sync waits until every buffered entry has been written.
+type AsyncWriter struct {
+	out  zapcore.WriteSyncer
+	opts AsyncOptions
+
+	mu      sync.Mutex
+	notFull *sync.Cond
+	ring    [][]byte
+	head    int
+	n       int
+	closed  bool
+	batch   []byte // owned by the background goroutine
+
+	kick    chan struct{}
+	syncReq chan chan error
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewAsyncWriter starts a background goroutine writing to out. Call Stop to
+// drain the buffer and end it.
+func NewAsyncWriter(out zapcore.WriteSyncer, opts AsyncOptions) *AsyncWriter {
+	opts = opts.withDefaults()
+	w := &AsyncWriter{
+		out:     out,
+		opts:    opts,
+		ring:    make([][]byte, opts.BufferSize),
+		kick:    make(chan struct{}, 1),
+		syncReq: make(chan chan error),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	w.notFull = sync.NewCond(&w.mu)
+	go w.run()
+
+	return w
+}
+
+// Write buffers a copy of p. After Stop it writes p synchronously.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	for !w.closed && w.n == len(w.ring) {
+		switch w.opts.Overflow {
+		case OverflowDropNewest:
+			w.mu.Unlock()
+			drops.overflowed.Add(1)
+
+			return len(p), nil
+		case OverflowDropOldest:
+			w.ring[w.head] = nil
+			w.head = (w.head + 1) % len(w.ring)
+			w.n--
+			drops.overflowed.Add(1)
+		default:
+			w.wake()
+			w.notFull.Wait()
+		}
+	}
+	if w.closed {
+		w.mu.Unlock()
+		// keep the order of entries buffered before Stop
+		<-w.done
+
+		return w.out.Write(p)
+	}
+
+	w.ring[(w.head+w.n)%len(w.ring)] = append([]byte(nil), p...)
+	w.n++
+	if w.n >= len(w.ring)/2 {
+		w.wake()
+	}
+	w.mu.Unlock()
+
+	return len(p), nil
+}
+
+// Sync writes every buffered entry and syncs the underlying WriteSyncer.
+func (w *AsyncWriter) Sync() error {
+	reply := make(chan error, 1)
+	var err error
+	select {
+	case w.syncReq <- reply:
+		err = <-reply
+	case <-w.done:
+	}
+
+	return errors.Join(err, w.out.Sync())
+}
+
+// Stop writes every buffered entry and ends the background goroutine. Later
+// writes go to the underlying WriteSyncer synchronously.
+func (w *AsyncWriter) Stop() error {
+	w.once.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		w.notFull.Broadcast()
+		w.mu.Unlock()
+		close(w.stop)
+	})
+	<-w.done
+
+	return w.out.Sync()
+}
+
+func (w *AsyncWriter) stopped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.closed
+}
+
+// wake asks the background goroutine to write the buffer now.
+func (w *AsyncWriter) wake() {
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = w.drain()
+		case <-w.kick:
+			_ = w.drain()
+		case reply := <-w.syncReq:
+			reply <- w.drain()
+		case <-w.stop:
+			_ = w.drain()
+
+			return
+		}
+	}
+}
+
+// drain writes the buffered entries to out in a single write.
+func (w *AsyncWriter) drain() error {
+	w.mu.Lock()
+	batch := w.batch[:0]
+	for ; w.n > 0; w.n-- {
+		batch = append(batch, w.ring[w.head]...)
+		w.ring[w.head] = nil
+		w.head = (w.head + 1) % len(w.ring)
+	}
+	w.notFull.Broadcast()
+	w.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	_, err := w.out.Write(batch)
+	// do not keep a huge batch around after a burst
+	if cap(batch) <= 1024*1024 {
+		w.batch = batch
+	}
+
+	return err
+}
+
+// Shutdown flushes the global logger and stops every async writer, so that
+// entries logged afterwards are written synchronously.
+func Shutdown() error {
+	Flush()
+
+	asyncWritersMu.Lock()
+	sinks := make([]*asyncSink, 0, len(asyncWriters))
+	for _, sink := range asyncWriters {
+		sinks = append(sinks, sink)
+	}
+	asyncWritersMu.Unlock()
+
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ShutdownOnSignal calls Shutdown when one of signals, SIGINT and SIGTERM by
+// default, is received, and then delivers the signal again with this handler
+// removed so that the default behaviour or other handlers still apply.
+// Applications that handle these signals themselves should call Shutdown
+// from their handler instead. The returned function removes the hook.
+func ShutdownOnSignal(signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	ch := make(chan os.Signal, 1)
+	quit := make(chan struct{})
+	signal.Notify(ch, signals...)
+	go func() {
+		select {
+		case sig := <-ch:
+			_ = Shutdown()
+			signal.Stop(ch)
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				_ = p.Signal(sig)
+			}
+		case <-quit:
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(quit)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+// gatedWriter blocks its first write until the gate is opened.
+type gatedWriter struct {
+	started chan struct{}
+	gate    chan struct{}
+	once    sync.Once
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}), gate: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) Sync() error { return nil }
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.String()
+}
+
+func Test_AsyncWriterOverflow(t *testing.T) {
+	tests := []struct {
+		policy     log.OverflowPolicy
+		want       string
+		overflowed uint64
+	}{
+		{policy: log.OverflowDropNewest, want: "abc", overflowed: 1},
+		{policy: log.OverflowDropOldest, want: "acd", overflowed: 1},
+		{policy: log.OverflowBlock, want: "abcd"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			before := log.Dropped()
+			out := newGatedWriter()
+			w := log.NewAsyncWriter(out, log.AsyncOptions{BufferSize: 2, Overflow: tt.policy, FlushInterval: time.Hour})
+
+			// the background writer takes "a" and blocks, "b" and "c" fill the buffer
+			_, _ = w.Write([]byte("a"))
+			<-out.started
+			_, _ = w.Write([]byte("b"))
+			_, _ = w.Write([]byte("c"))
+
+			written := make(chan struct{})
+			go func() {
+				_, _ = w.Write([]byte("d"))
+				close(written)
+			}()
+			if tt.policy == log.OverflowBlock {
+				select {
+				case <-written:
+					t.Fatal("write did not block on a full buffer")
+				case <-time.After(50 * time.Millisecond):
+				}
+				close(out.gate)
+				<-written
+			} else {
+				<-written
+				close(out.gate)
+			}
+
+			assert.Nil(t, w.Sync())
+			assert.Equal(t, tt.want, out.String())
+			assert.Equal(t, tt.overflowed, log.Dropped().Overflowed-before.Overflowed)
+
+			// after Stop writes are synchronous
+			assert.Nil(t, w.Stop())
+			_, _ = w.Write([]byte("e"))
+			assert.Equal(t, tt.want+"e", out.String())
+		})
+	}
+}
+
+func Test_AsyncOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "async.log")
+	opts := log.NewOptions()
+	opts.OutputPaths = []string{path}
+	opts.Async = log.AsyncOptions{Enabled: true, BufferSize: 16, FlushInterval: time.Hour}
+	assert.Empty(t, opts.Validate())
+	log.Init(opts)
+	t.Cleanup(func() { log.Init(log.NewOptions()) })
+
+	log.Info("buffered")
+	data, _ := os.ReadFile(path)
+	assert.NotContains(t, string(data), "buffered")
+	log.Flush()
+	data, _ = os.ReadFile(path)
+	assert.Contains(t, string(data), "buffered")
+
+	assert.Nil(t, log.Shutdown())
+	log.Info("synchronous")
+	data, _ = os.ReadFile(path)
+	assert.True(t, strings.Contains(string(data), "synchronous"))
+
+	opts.Async.Overflow = "spill"
+	assert.Len(t, opts.Validate(), 1)
+}
@@ -20,6 +20,11 @@ package log
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
 type key int
@@ -28,6 +33,133 @@ const (
 	logContextKey key = iota
 )
 
+// ContextLoggerKey is the string key under which a request scoped logger is
+// stored in containers that only resolve string keys, such as gin.Context.
+const ContextLoggerKey = "log.logger"
+
+// ContextExtractor returns the fields to log for the values carried by ctx.
+type ContextExtractor func(ctx context.Context) []Field
+
+type namedExtractor struct {
+	name      string
+	extractor ContextExtractor
+}
+
+var (
+	extractorsMu sync.Mutex
+	extractors   atomic.Pointer[[]namedExtractor]
+)
+
+func init() {
+	RegisterContextKeys(KeyRequestID, KeyUsername, KeyWatcherName, KeyTenantID, KeyOfferingKey)
+	RegisterContextExtractor("otel", traceFields)
+}
+
+// RegisterContextExtractor registers extractor under name, replacing any
+// extractor already registered with that name. A nil extractor removes it.
+// Extractors run in registration order whenever L, the slog handler or the
+// request logger collect the fields of a context.
+func RegisterContextExtractor(name string, extractor ContextExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+
+	var current []namedExtractor
+	if p := extractors.Load(); p != nil {
+		current = *p
+	}
+
+	updated := make([]namedExtractor, 0, len(current)+1)
+	replaced := false
+	for _, e := range current {
+		if e.name != name {
+			updated = append(updated, e)
+
+			continue
+		}
+		if extractor != nil {
+			updated = append(updated, namedExtractor{name: name, extractor: extractor})
+		}
+		replaced = true
+	}
+	if !replaced && extractor != nil {
+		updated = append(updated, namedExtractor{name: name, extractor: extractor})
+	}
+	extractors.Store(&updated)
+}
+
+// RegisterContextKeys logs the values stored under keys with WithContextValue
+// (or gin.Context.Set) using the key as field name.
+func RegisterContextKeys(keys ...string) {
+	for _, k := range keys {
+		k := k
+		RegisterContextExtractor("key:"+k, func(ctx context.Context) []Field {
+			if value := ctx.Value(k); value != nil {
+				return []Field{zap.Any(k, value)}
+			}
+
+			return nil
+		})
+	}
+}
+
+// contextFields returns the request scoped fields of ctx collected by the
+// registered extractors.
+func contextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	p := extractors.Load()
+	if p == nil {
+		return nil
+	}
+
+	var fields []Field
+	for _, e := range *p {
+		fields = append(fields, e.extractor(ctx)...)
+	}
+
+	return fields
+}
+
+// traceFields returns the OpenTelemetry trace and span IDs of ctx.
+func traceFields(ctx context.Context) []Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return []Field{zap.String(KeyTraceID, sc.TraceID().String()), zap.String(KeySpanID, sc.SpanID().String())}
+}
+
+// WithContextValue returns a copy of ctx carrying value under key. Keys are
+// plain strings so that gin.Context.Set resolves them the same way.
+func WithContextValue(ctx context.Context, key string, value interface{}) context.Context {
+	//nolint:staticcheck // string keys are shared with gin.Context
+	return context.WithValue(ctx, key, value)
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return WithContextValue(ctx, KeyRequestID, requestID)
+}
+
+// RequestIDFrom returns the request ID carried by ctx.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(KeyRequestID).(string)
+
+	return id
+}
+
+// WithTenantID returns a copy of ctx carrying the tenant ID.
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return WithContextValue(ctx, KeyTenantID, tenantID)
+}
+
+// WithOfferingKey returns a copy of ctx carrying the offering key.
+func WithOfferingKey(ctx context.Context, offeringKey string) context.Context {
+	return WithContextValue(ctx, KeyOfferingKey, offeringKey)
+}
+
 // WithContext returns a copy of context in which the log value is set.
 func WithContext(ctx context.Context) context.Context {
 	return std.WithContext(ctx)
@@ -39,12 +171,25 @@ func (l *zapLogger) WithContext(ctx context.Context) context.Context {
 
 // FromContext returns the value of the log key on the ctx.
 func FromContext(ctx context.Context) Logger {
-	if ctx != nil {
-		logger := ctx.Value(logContextKey)
-		if logger != nil {
-			return logger.(Logger)
-		}
+	if logger := loggerFromContext(ctx); logger != nil {
+		return logger
 	}
 
 	return WithName("Unknown-Context")
 }
+
+// loggerFromContext returns the logger attached to ctx by WithContext or
+// stored under ContextLoggerKey, or nil.
+func loggerFromContext(ctx context.Context) Logger {
+	if ctx == nil {
+		return nil
+	}
+	if logger, ok := ctx.Value(logContextKey).(Logger); ok {
+		return logger
+	}
+	if logger, ok := ctx.Value(ContextLoggerKey).(Logger); ok {
+		return logger
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+type regionKey struct{}
+
+func Test_ContextExtractors(t *testing.T) {
+	path := initFileLogger(t, "")
+	log.RegisterContextExtractor("region", func(ctx context.Context) []log.Field {
+		if region, ok := ctx.Value(regionKey{}).(string); ok {
+			return []log.Field{log.String("region", region)}
+		}
+
+		return nil
+	})
+	t.Cleanup(func() { log.RegisterContextExtractor("region", nil) })
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	ctx = log.WithTenantID(log.WithRequestID(ctx, "req-1"), "tenant-1")
+	ctx = context.WithValue(ctx, regionKey{}, "eu")
+	assert.Equal(t, "req-1", log.RequestIDFrom(ctx))
+
+	// a logger attached to ctx is used by L, without repeating bound fields
+	ctx = log.L(ctx).WithName("api").WithValues("attempt", 1).WithContext(ctx)
+	log.L(ctx).Info("handled")
+
+	lines := strings.Split(strings.TrimSpace(readLog(t, path)), "\n")
+	assert.Len(t, lines, 1)
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "api", entry["logger"])
+	assert.Equal(t, "req-1", entry[log.KeyRequestID])
+	assert.Equal(t, "tenant-1", entry[log.KeyTenantID])
+	assert.Equal(t, "eu", entry["region"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry[log.KeyTraceID])
+	assert.Equal(t, "00f067aa0ba902b7", entry[log.KeySpanID])
+	assert.Equal(t, 1, strings.Count(lines[0], `"region"`))
+}
+
+func Test_ContextNestedSpan(t *testing.T) {
+	path := initFileLogger(t, "")
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	parentID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	childID, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithSpanContext(context.Background(),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: parentID}))
+	ctx = log.L(ctx).WithValues("attempt", 1).WithContext(ctx)
+
+	// a child span replaces the span ID bound by the parent logger
+	child := trace.ContextWithSpanContext(ctx,
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: childID}))
+	log.L(child).Info("child")
+	log.L(ctx).Info("parent")
+
+	lines := strings.Split(strings.TrimSpace(readLog(t, path)), "\n")
+	assert.Len(t, lines, 2)
+	for i, want := range []string{"b7ad6b7169203331", "00f067aa0ba902b7"} {
+		var entry map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(lines[i]), &entry))
+		assert.Equal(t, want, entry[log.KeySpanID])
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry[log.KeyTraceID])
+		assert.Equal(t, float64(1), entry["attempt"])
+		assert.Equal(t, 1, strings.Count(lines[i], `"`+log.KeySpanID+`"`))
+		assert.Equal(t, 1, strings.Count(lines[i], `"`+log.KeyTraceID+`"`))
+	}
+}
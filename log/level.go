@@ -0,0 +1,291 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelController changes the minimum log level at runtime, both globally and
+// for named loggers (see WithName). A module level applies to every logger
+// whose name matches its glob pattern, e.g. "storage.*"; when several patterns
+// match, the longest one wins.
+type LevelController struct {
+	global zap.AtomicLevel
+
+	mu      sync.RWMutex
+	modules []moduleLevel
+	min     atomic.Int32 // lowest level any logger may log at
+	cache   sync.Map     // logger name -> zapcore.Level
+}
+
+type moduleLevel struct {
+	pattern string
+	level   zapcore.Level
+}
+
+func newLevelController(level zapcore.Level) *LevelController {
+	c := &LevelController{global: zap.NewAtomicLevelAt(level)}
+	c.min.Store(int32(level))
+
+	return c
+}
+
+// Level returns the global minimum level.
+func (c *LevelController) Level() Level {
+	return c.global.Level()
+}
+
+// SetLevel changes the global minimum level. Module levels still take precedence
+// for the loggers they match.
+func (c *LevelController) SetLevel(level Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.global.SetLevel(level)
+	c.reset()
+}
+
+// ModuleLevels returns the module levels in the same format SetModuleLevels accepts.
+func (c *LevelController) ModuleLevels() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	specs := make([]string, 0, len(c.modules))
+	for _, module := range c.modules {
+		specs = append(specs, module.pattern+"="+module.level.String())
+	}
+
+	return strings.Join(specs, ",")
+}
+
+// SetModuleLevels replaces all module levels with spec, a comma separated list of
+// pattern=level pairs such as "runtime=debug,storage.*=warn". An empty spec
+// removes every module level.
+func (c *LevelController) SetModuleLevels(spec string) error {
+	modules, err := parseModuleLevels(spec)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.modules = modules
+	c.reset()
+
+	return nil
+}
+
+// LevelFor returns the effective minimum level of the logger with the given name.
+func (c *LevelController) LevelFor(name string) Level {
+	if level, ok := c.cache.Load(name); ok {
+		return level.(zapcore.Level)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	level, best := c.global.Level(), -1
+	for _, module := range c.modules {
+		if len(module.pattern) < best {
+			continue
+		}
+		if matched, _ := path.Match(module.pattern, name); matched {
+			level, best = module.level, len(module.pattern)
+		}
+	}
+	c.cache.Store(name, level)
+
+	return level
+}
+
+// Enabled reports whether the logger with the given name logs at level.
+func (c *LevelController) Enabled(name string, level Level) bool {
+	return level >= c.LevelFor(name)
+}
+
+// reset recomputes the lowest level and drops cached name lookups. Callers must
+// hold c.mu.
+func (c *LevelController) reset() {
+	min := c.global.Level()
+	for _, module := range c.modules {
+		if module.level < min {
+			min = module.level
+		}
+	}
+	c.min.Store(int32(min))
+	c.cache.Range(func(key, _ interface{}) bool {
+		c.cache.Delete(key)
+
+		return true
+	})
+}
+
+type levelPayload struct {
+	Level   *string `json:"level,omitempty"`
+	Modules *string `json:"modules,omitempty"`
+}
+
+// ServeHTTP reports the levels on GET and changes them on PUT. Both requests and
+// responses use JSON like {"level":"info","modules":"runtime=debug,storage.*=warn"};
+// fields omitted from a PUT body are left unchanged.
+func (c *LevelController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var payload levelPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeLevelError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+
+			return
+		}
+		if err := c.apply(payload); err != nil {
+			writeLevelError(w, http.StatusBadRequest, err)
+
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeLevelError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+
+		return
+	}
+
+	level, modules := c.Level().String(), c.ModuleLevels()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(levelPayload{Level: &level, Modules: &modules})
+}
+
+// apply validates the whole payload before changing anything.
+func (c *LevelController) apply(payload levelPayload) error {
+	var level zapcore.Level
+	if payload.Level != nil {
+		if err := level.UnmarshalText([]byte(*payload.Level)); err != nil {
+			return err
+		}
+	}
+	if payload.Modules != nil {
+		if err := c.SetModuleLevels(*payload.Modules); err != nil {
+			return err
+		}
+	}
+	if payload.Level != nil {
+		c.SetLevel(level)
+	}
+
+	return nil
+}
+
+func writeLevelError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// parseModuleLevels parses a "pattern=level,pattern=level" spec.
+func parseModuleLevels(spec string) ([]moduleLevel, error) {
+	var modules []moduleLevel
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pattern, levelText, ok := strings.Cut(item, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid module level %q, want pattern=level", item)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid module pattern %q: %w", pattern, err)
+		}
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(levelText))); err != nil {
+			return nil, fmt.Errorf("invalid module level %q: %w", item, err)
+		}
+		modules = append(modules, moduleLevel{pattern: pattern, level: level})
+	}
+
+	return modules, nil
+}
+
+// levelCore filters entries by the LevelController using the entry's logger
+// name. The wrapped core is built to accept every level.
+type levelCore struct {
+	zapcore.Core
+	levels *LevelController
+}
+
+// allLevels is the level of the wrapped core, low enough to let every entry through.
+const allLevels = zapcore.Level(math.MinInt8)
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return int32(level) >= c.levels.min.Load()
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.Enabled(entry.LoggerName, entry.Level) {
+		return checked
+	}
+
+	return c.Core.Check(entry, checked)
+}
+
+// enabledFor reports whether a logger named name with the given core logs at level.
+func enabledFor(core zapcore.Core, name string, level zapcore.Level) bool {
+	if lc, ok := core.(*levelCore); ok {
+		return lc.levels.Enabled(name, level)
+	}
+
+	return core.Enabled(level)
+}
+
+// Levels returns the level controller of the global logger.
+func Levels() *LevelController {
+	return std.levels
+}
+
+// SetLevel changes the global minimum level of the global logger.
+func SetLevel(level Level) { std.levels.SetLevel(level) }
+
+// SetModuleLevels replaces the module levels of the global logger, see
+// LevelController.SetModuleLevels.
+func SetModuleLevels(spec string) error { return std.levels.SetModuleLevels(spec) }
+
+// LevelHandler returns an http.Handler that reads and changes the levels of the
+// global logger, following Init if the global logger is replaced later.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Levels().ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,118 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+func initFileLogger(t *testing.T, moduleLevels string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.log")
+	opts := log.NewOptions()
+	opts.Format = "json"
+	opts.OutputPaths = []string{path}
+	opts.ModuleLevels = moduleLevels
+	log.Init(opts)
+	t.Cleanup(func() { log.Init(log.NewOptions()) })
+
+	return path
+}
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	log.Flush()
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	return string(data)
+}
+
+func Test_ModuleLevels(t *testing.T) {
+	path := initFileLogger(t, "storage.*=debug,storage.s3.*=error")
+
+	log.WithName("storage").WithName("gcs").Debug("gcs debug")
+	log.WithName("storage").WithName("s3").WithName("client").Warn("s3 warn")
+	log.WithName("runtime").Debug("runtime debug")
+	log.Info("global info")
+
+	output := readLog(t, path)
+	assert.Contains(t, output, "gcs debug")
+	assert.NotContains(t, output, "s3 warn")
+	assert.NotContains(t, output, "runtime debug")
+	assert.Contains(t, output, "global info")
+
+	assert.True(t, log.WithName("storage").WithName("gcs").V(log.DebugLevel).Enabled())
+	assert.False(t, log.WithName("runtime").V(log.DebugLevel).Enabled())
+}
+
+func Test_SetLevelAtRuntime(t *testing.T) {
+	path := initFileLogger(t, "")
+	logger := log.WithName("runtime")
+
+	logger.Debug("before")
+	assert.Nil(t, log.SetModuleLevels("runtime=debug"))
+	logger.Debug("after")
+	log.SetLevel(log.ErrorLevel)
+	log.Warn("suppressed warn")
+
+	output := readLog(t, path)
+	assert.NotContains(t, output, "before")
+	assert.Contains(t, output, "after")
+	assert.NotContains(t, output, "suppressed warn")
+	assert.NotNil(t, log.SetModuleLevels("runtime=verbose"))
+}
+
+func Test_LevelHandler(t *testing.T) {
+	initFileLogger(t, "runtime=debug")
+	handler := log.LevelHandler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/log/level", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"level":"info","modules":"runtime=debug"}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	body := strings.NewReader(`{"level":"warn","modules":"storage.*=debug"}`)
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/log/level", body))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"level":"warn","modules":"storage.*=debug"}`, recorder.Body.String())
+	assert.Equal(t, log.DebugLevel, log.Levels().LevelFor("storage.s3"))
+	assert.Equal(t, log.WarnLevel, log.Levels().LevelFor("runtime"))
+
+	// an invalid payload changes nothing
+	recorder = httptest.NewRecorder()
+	body = strings.NewReader(`{"level":"error","modules":"storage=loud"}`)
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/log/level", body))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, log.WarnLevel, log.Levels().Level())
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/log/level", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
@@ -23,6 +23,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"sync"
 
 	"go.uber.org/zap"
@@ -146,6 +147,13 @@ type zapLogger struct {
 	// deals with our desire to have multiple verbosity levels.
 	zapLogger *zap.Logger
 	infoLogger
+	// name is the full logger name, used to look up module levels.
+	name   string
+	levels *LevelController
+	// unbound is zapLogger without the context fields added by L, which are
+	// kept in bound so that a later L can replace their values.
+	unbound *zap.Logger
+	bound   []Field
 }
 
 // handleFields converts a bunch of arbitrary key-value pairs into Zap fields.  It takes
@@ -209,6 +217,9 @@ func Init(opts *Options) {
 	mu.Lock()
 	defer mu.Unlock()
 	std = New(opts)
+	if opts != nil && opts.SlogDefault {
+		slog.SetDefault(NewSlog())
+	}
 }
 
 // New create logger by opts which can custmoized by command arguments.
@@ -241,23 +252,29 @@ func New(opts *Options) *zapLogger {
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
 
+	levels := newLevelController(zapLevel)
+	// an invalid spec is rejected by Validate; fall back to the global level like Level does
+	_ = levels.SetModuleLevels(opts.ModuleLevels)
+
 	loggerConfig := &zap.Config{
-		Level:             zap.NewAtomicLevelAt(zapLevel),
+		Level:             zap.NewAtomicLevelAt(allLevels),
 		Development:       opts.Development,
 		DisableCaller:     opts.DisableCaller,
 		DisableStacktrace: opts.DisableStacktrace,
-		Sampling: &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		},
-		Encoding:         opts.Format,
-		EncoderConfig:    encoderConfig,
-		OutputPaths:      opts.OutputPaths,
-		ErrorOutputPaths: opts.ErrorOutputPaths,
+		Encoding:          opts.Format,
+		EncoderConfig:     encoderConfig,
+		OutputPaths:       asyncPaths(rotatePaths(opts.OutputPaths, opts.Rotate), opts.Async),
+		ErrorOutputPaths:  rotatePaths(opts.ErrorOutputPaths, opts.Rotate),
 	}
 
 	var err error
-	l, err := loggerConfig.Build(zap.AddStacktrace(zapcore.PanicLevel), zap.AddCallerSkip(1))
+	l, err := loggerConfig.Build(
+		zap.AddStacktrace(zapcore.PanicLevel),
+		zap.AddCallerSkip(1),
+		zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &levelCore{Core: wrapSampling(wrapMask(core, opts.Mask), opts.Sampling), levels: levels}
+		}),
+	)
 	if err != nil {
 		panic(err)
 	}
@@ -267,6 +284,8 @@ func New(opts *Options) *zapLogger {
 			log:   l,
 			level: zap.InfoLevel,
 		},
+		name:   opts.Name,
+		levels: levels,
 	}
 	klog.InitLogger(l)
 	zap.RedirectStdLog(l)
@@ -309,7 +328,7 @@ func StdInfoLogger() *log.Logger {
 func V(level Level) InfoLogger { return std.V(level) }
 
 func (l *zapLogger) V(level Level) InfoLogger {
-	if l.zapLogger.Core().Enabled(level) {
+	if enabledFor(l.zapLogger.Core(), l.name, level) {
 		return &infoLogger{
 			level: level,
 			log:   l.zapLogger,
@@ -329,9 +348,9 @@ func (l *zapLogger) Write(p []byte) (n int, err error) {
 func WithValues(keysAndValues ...interface{}) Logger { return std.WithValues(keysAndValues...) }
 
 func (l *zapLogger) WithValues(keysAndValues ...interface{}) Logger {
-	newLogger := l.zapLogger.With(handleFields(l.zapLogger, keysAndValues)...)
+	fields := handleFields(l.zapLogger, keysAndValues)
 
-	return NewLogger(newLogger)
+	return l.derive(func(zl *zap.Logger) *zap.Logger { return zl.With(fields...) }, l.name)
 }
 
 // WithName adds a new path segment to the logger's name. Segments are joined by
@@ -339,9 +358,26 @@ func (l *zapLogger) WithValues(keysAndValues ...interface{}) Logger {
 func WithName(s string) Logger { return std.WithName(s) }
 
 func (l *zapLogger) WithName(name string) Logger {
-	newLogger := l.zapLogger.Named(name)
+	// keep in step with zap.Logger.Named, which does not expose the name
+	fullName := name
+	if l.name != "" {
+		fullName = l.name + "." + name
+	}
+
+	return l.derive(func(zl *zap.Logger) *zap.Logger { return zl.Named(name) }, fullName)
+}
 
-	return NewLogger(newLogger)
+// derive creates a child logger sharing the level controller. apply is
+// applied to both the logger and its unbound counterpart.
+func (l *zapLogger) derive(apply func(*zap.Logger) *zap.Logger, name string) *zapLogger {
+	logger := NewLogger(apply(l.zapLogger)).(*zapLogger)
+	logger.name = name
+	logger.levels = l.levels
+	if l.unbound != nil {
+		logger.unbound, logger.bound = apply(l.unbound), l.bound
+	}
+
+	return logger
 }
 
 // Flush calls the underlying Core's Sync method, flushing any buffered
@@ -545,26 +581,73 @@ func (l *zapLogger) Fatalw(msg string, keysAndValues ...interface{}) {
 }
 
 // L method output with specified context value.
+// L returns the logger attached to ctx, or the standard logger, with the
+// fields of the registered context extractors added.
 func L(ctx context.Context) *zapLogger {
+	if logger, ok := loggerFromContext(ctx).(*zapLogger); ok {
+		return logger.L(ctx)
+	}
+
 	return std.L(ctx)
 }
 
 func (l *zapLogger) L(ctx context.Context) *zapLogger {
 	lg := l.clone()
 
-	if requestID := ctx.Value(KeyRequestID); requestID != nil {
-		lg.zapLogger = lg.zapLogger.With(zap.Any(KeyRequestID, requestID))
+	fields := contextFields(ctx)
+	if len(fields) == 0 {
+		return lg
 	}
-	if username := ctx.Value(KeyUsername); username != nil {
-		lg.zapLogger = lg.zapLogger.With(zap.Any(KeyUsername, username))
+	bound := mergeFields(lg.bound, fields)
+	if fieldsEqual(bound, lg.bound) {
+		return lg
 	}
-	if watcherName := ctx.Value(KeyWatcherName); watcherName != nil {
-		lg.zapLogger = lg.zapLogger.With(zap.Any(KeyWatcherName, watcherName))
+	// rebuild from the unbound logger so that a nested span or a new request
+	// ID replaces the previous value instead of being logged twice
+	if lg.unbound == nil {
+		lg.unbound = lg.zapLogger
 	}
+	lg.zapLogger = lg.unbound.With(bound...)
+	lg.bound = bound
 
 	return lg
 }
 
+// mergeFields returns bound with the values of fields, replacing fields of
+// the same key and appending new ones.
+func mergeFields(bound, fields []Field) []Field {
+	merged := make([]Field, len(bound), len(bound)+len(fields))
+	copy(merged, bound)
+	for _, field := range fields {
+		replaced := false
+		for i := range merged {
+			if merged[i].Key == field.Key {
+				merged[i], replaced = field, true
+
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, field)
+		}
+	}
+
+	return merged
+}
+
+func fieldsEqual(a, b []Field) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equals(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
 //nolint:predeclared
 func (l *zapLogger) clone() *zapLogger {
 	copy := *l
@@ -0,0 +1,573 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/QingsiLiu/baseComponents/utils"
+)
+
+// Built-in detectors of MaskOptions.Validators. E-mails and ID card numbers
+// are checked with utils.IsEmail and utils.IsIDCard; phone numbers may carry
+// an 86 country code and space or dash separators.
+const (
+	MaskEmail  = "email"
+	MaskPhone  = "phone"
+	MaskIDCard = "idcard"
+)
+
+// redactedLength is the number of mask characters replacing denylisted values
+// that are not strings.
+const redactedLength = 6
+
+var (
+	emailCandidate = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	digitsRun      = regexp.MustCompile(`[0-9]+[Xx]?`)
+	// phoneCandidate matches mobile numbers with an optional 86 country code
+	// and space or dash separators, e.g. +86 138-1234-5678.
+	phoneCandidate = regexp.MustCompile(`(?:(?:\+|00)?86[ -]?)?1[3-9][0-9][ -]?[0-9]{4}[ -]?[0-9]{4}`)
+)
+
+// MaskOptions masks sensitive data in messages and fields before entries are
+// encoded. Values of denylisted fields are masked completely; elsewhere
+// e-mails keep the first character and the domain, phone numbers and ID card
+// numbers keep the first 3 and last 4 digits, and pattern matches keep a
+// quarter at each end.
+type MaskOptions struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Fields lists field names, matched case-insensitively, whose values are always masked.
+	Fields []string `json:"fields" mapstructure:"fields"`
+	// Patterns are regular expressions whose matches are masked.
+	Patterns []string `json:"patterns" mapstructure:"patterns"`
+	// Validators selects the built-in detectors: email, phone and idcard.
+	Validators []string `json:"validators" mapstructure:"validators"`
+	// Char is the mask character, "*" by default.
+	Char string `json:"char" mapstructure:"char"`
+}
+
+// validate reports invalid mask options.
+func (o MaskOptions) validate() error {
+	_, err := newMasker(o)
+
+	return err
+}
+
+// wrapMask wraps core with the masker configured by opts. Disabled or invalid
+// options, which Validate rejects, leave core untouched.
+func wrapMask(core zapcore.Core, opts MaskOptions) zapcore.Core {
+	if !opts.Enabled {
+		return core
+	}
+	m, err := newMasker(opts)
+	if err != nil {
+		return core
+	}
+
+	return &maskCore{Core: core, masker: m}
+}
+
+// masker applies the mask rules to strings and fields.
+type masker struct {
+	fields   map[string]struct{}
+	patterns []*regexp.Regexp
+	email    bool
+	phone    bool
+	idcard   bool
+	char     rune
+}
+
+func newMasker(opts MaskOptions) (*masker, error) {
+	m := &masker{fields: make(map[string]struct{}, len(opts.Fields)), char: '*'}
+	for _, field := range opts.Fields {
+		m.fields[strings.ToLower(field)] = struct{}{}
+	}
+	for _, pattern := range opts.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mask pattern %q: %w", pattern, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+	for _, validator := range opts.Validators {
+		switch strings.ToLower(validator) {
+		case MaskEmail:
+			m.email = true
+		case MaskPhone:
+			m.phone = true
+		case MaskIDCard:
+			m.idcard = true
+		default:
+			return nil, fmt.Errorf("unknown mask validator %q, want %s, %s or %s", validator, MaskEmail, MaskPhone, MaskIDCard)
+		}
+	}
+	if opts.Char != "" {
+		if utf8.RuneCountInString(opts.Char) != 1 {
+			return nil, fmt.Errorf("mask char must be a single character, got %q", opts.Char)
+		}
+		m.char, _ = utf8.DecodeRuneInString(opts.Char)
+	}
+
+	return m, nil
+}
+
+func (m *masker) denied(key string) bool {
+	if len(m.fields) == 0 || key == "" {
+		return false
+	}
+	_, ok := m.fields[strings.ToLower(key)]
+
+	return ok
+}
+
+// redact masks s completely.
+func (m *masker) redact(s string) string {
+	return utils.Mask(s, 0, utf8.RuneCountInString(s), m.char)
+}
+
+// redacted replaces values that are not strings.
+func (m *masker) redacted() string {
+	return strings.Repeat(string(m.char), redactedLength)
+}
+
+// value masks the string value of the field key.
+func (m *masker) value(key, s string) string {
+	if m.denied(key) {
+		return m.redact(s)
+	}
+
+	return m.text(s)
+}
+
+// text masks the sensitive data found in free text.
+func (m *masker) text(s string) string {
+	if m.email && strings.IndexByte(s, '@') >= 0 {
+		s = emailCandidate.ReplaceAllStringFunc(s, func(match string) string {
+			if !utils.IsEmail(match) {
+				return match
+			}
+			at := strings.IndexByte(match, '@')
+
+			return utils.Mask(match, min(1, at-1), at, m.char)
+		})
+	}
+	if m.idcard && strings.ContainsAny(s, "0123456789") {
+		s = digitsRun.ReplaceAllStringFunc(s, func(match string) string {
+			if len(match) == 18 && utils.IsIDCard(match) {
+				return utils.Mask(match, 3, 14, m.char)
+			}
+
+			return match
+		})
+	}
+	if m.phone && strings.ContainsAny(s, "0123456789") {
+		s = m.phones(s)
+	}
+	for _, re := range m.patterns {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			n := utf8.RuneCountInString(match)
+			keep := n / 4
+
+			return utils.Mask(match, keep, n-keep, m.char)
+		})
+	}
+
+	return s
+}
+
+// phones masks the middle 4 digits of the mobile numbers in s, keeping the
+// country code and separators. Matches that are part of a longer digit run
+// are left alone.
+func (m *masker) phones(s string) string {
+	matches := phoneCandidate.FindAllStringIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if (start > 0 && isDigit(s[start-1])) || (end < len(s) && isDigit(s[end])) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		phone := []byte(s[start:end])
+		// The national number is the last 11 digits; mask its 4th to 7th.
+		for i, digits := len(phone)-1, 0; i >= 0 && digits < 8; i-- {
+			if !isDigit(phone[i]) {
+				continue
+			}
+			if digits++; digits > 4 {
+				phone[i] = 0
+			}
+		}
+		for _, c := range phone {
+			if c == 0 {
+				b.WriteRune(m.char)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// maskFields masks fields, copying the slice only if a field changes.
+func (m *masker) maskFields(fields []zapcore.Field) []zapcore.Field {
+	var masked []zapcore.Field
+	for i, f := range fields {
+		mf, changed := m.field(f)
+		if !changed {
+			if masked != nil {
+				masked = append(masked, f)
+			}
+
+			continue
+		}
+		if masked == nil {
+			masked = make([]zapcore.Field, i, len(fields))
+			copy(masked, fields[:i])
+		}
+		masked = append(masked, mf)
+	}
+	if masked == nil {
+		return fields
+	}
+
+	return masked
+}
+
+func (m *masker) field(f zapcore.Field) (zapcore.Field, bool) {
+	switch f.Type {
+	case zapcore.StringType:
+		if s := m.value(f.Key, f.String); s != f.String {
+			return zap.String(f.Key, s), true
+		}
+	case zapcore.ByteStringType:
+		b := string(f.Interface.([]byte))
+		if s := m.value(f.Key, b); s != b {
+			return zap.String(f.Key, s), true
+		}
+	case zapcore.StringerType:
+		original, ok := stringOf(f)
+		if !ok {
+			return f, false
+		}
+		if s := m.value(f.Key, original); s != original {
+			return zap.String(f.Key, s), true
+		}
+	case zapcore.ErrorType:
+		if err, changed := m.error(f.Key, f.Interface); changed {
+			return zap.NamedError(f.Key, err), true
+		}
+	case zapcore.Int64Type, zapcore.Uint64Type:
+		original := strconv.FormatInt(f.Integer, 10)
+		if f.Type == zapcore.Uint64Type {
+			original = strconv.FormatUint(uint64(f.Integer), 10)
+		}
+		if s := m.value(f.Key, original); s != original {
+			return zap.String(f.Key, s), true
+		}
+	case zapcore.ObjectMarshalerType:
+		if m.denied(f.Key) {
+			return zap.String(f.Key, m.redacted()), true
+		}
+
+		return zap.Object(f.Key, maskedObject{ObjectMarshaler: f.Interface.(zapcore.ObjectMarshaler), masker: m}), true
+	case zapcore.ArrayMarshalerType:
+		if m.denied(f.Key) {
+			return zap.String(f.Key, m.redacted()), true
+		}
+
+		return zap.Array(f.Key, maskedArray{ArrayMarshaler: f.Interface.(zapcore.ArrayMarshaler), masker: m}), true
+	case zapcore.ReflectType:
+		if v, changed := m.reflected(f.Key, f.Interface); changed {
+			return zap.Any(f.Key, v), true
+		}
+	case zapcore.NamespaceType, zapcore.SkipType:
+	default:
+		if m.denied(f.Key) {
+			return zap.String(f.Key, m.redacted()), true
+		}
+	}
+
+	return f, false
+}
+
+// stringOf returns the text of Stringer and error fields, like zap does.
+func stringOf(f zapcore.Field) (s string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	switch v := f.Interface.(type) {
+	case fmt.Stringer:
+		return v.String(), true
+	case error:
+		return v.Error(), true
+	default:
+		return "", false
+	}
+}
+
+// error masks the text of an error field. Errors implementing fmt.Formatter
+// keep their verbose form, masked too, so zap still encodes it as errorVerbose.
+func (m *masker) error(key string, v interface{}) (err error, changed bool) {
+	defer func() {
+		if recover() != nil {
+			err, changed = nil, false
+		}
+	}()
+	original, ok := v.(error)
+	if !ok {
+		return nil, false
+	}
+	masked := maskedError{message: m.value(key, original.Error())}
+	changed = masked.message != original.Error()
+	if _, ok := original.(fmt.Formatter); ok {
+		verbose := fmt.Sprintf("%+v", original)
+		masked.verbose = m.value(key, verbose)
+		changed = changed || masked.verbose != verbose
+	}
+	if !changed {
+		return nil, false
+	}
+
+	return masked, true
+}
+
+// maskedError carries the masked text of an error and of its verbose form.
+type maskedError struct {
+	message string
+	verbose string
+}
+
+func (e maskedError) Error() string {
+	return e.message
+}
+
+func (e maskedError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') && e.verbose != "" {
+		_, _ = fmt.Fprint(s, e.verbose)
+
+		return
+	}
+	_, _ = fmt.Fprint(s, e.message)
+}
+
+// reflected masks a value logged with zap.Any through its JSON form.
+func (m *masker) reflected(key string, v interface{}) (interface{}, bool) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v, false
+	}
+	var generic interface{}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return v, false
+	}
+
+	return m.walk(key, generic)
+}
+
+func (m *masker) walk(key string, v interface{}) (interface{}, bool) {
+	if m.denied(key) {
+		if s, ok := v.(string); ok {
+			return m.redact(s), true
+		}
+
+		return m.redacted(), true
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		changed := false
+		for k, item := range v {
+			if masked, ok := m.walk(k, item); ok {
+				v[k] = masked
+				changed = true
+			}
+		}
+
+		return v, changed
+	case []interface{}:
+		changed := false
+		for i, item := range v {
+			if masked, ok := m.walk("", item); ok {
+				v[i] = masked
+				changed = true
+			}
+		}
+
+		return v, changed
+	case string:
+		s := m.text(v)
+
+		return s, s != v
+	case json.Number:
+		s := m.text(v.String())
+		if s != v.String() {
+			return s, true
+		}
+	}
+
+	return v, false
+}
+
+// maskCore masks entries before the wrapped core encodes them. It must wrap
+// the core that owns the encoder, since its Check bypasses the wrapped Check.
+type maskCore struct {
+	zapcore.Core
+	masker *masker
+}
+
+func (c *maskCore) With(fields []zapcore.Field) zapcore.Core {
+	return &maskCore{Core: c.Core.With(c.masker.maskFields(fields)), masker: c.masker}
+}
+
+func (c *maskCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c *maskCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.masker.text(entry.Message)
+
+	return c.Core.Write(entry, c.masker.maskFields(fields))
+}
+
+// maskedObject masks the values an ObjectMarshaler adds.
+type maskedObject struct {
+	zapcore.ObjectMarshaler
+	masker *masker
+}
+
+func (o maskedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.ObjectMarshaler.MarshalLogObject(&maskObjectEncoder{ObjectEncoder: enc, masker: o.masker})
+}
+
+// maskedArray masks the values an ArrayMarshaler appends.
+type maskedArray struct {
+	zapcore.ArrayMarshaler
+	masker *masker
+}
+
+func (a maskedArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return a.ArrayMarshaler.MarshalLogArray(&maskArrayEncoder{ArrayEncoder: enc, masker: a.masker})
+}
+
+type maskObjectEncoder struct {
+	zapcore.ObjectEncoder
+	masker *masker
+}
+
+func (e *maskObjectEncoder) AddString(key, value string) {
+	e.ObjectEncoder.AddString(key, e.masker.value(key, value))
+}
+
+func (e *maskObjectEncoder) AddByteString(key string, value []byte) {
+	e.ObjectEncoder.AddString(key, e.masker.value(key, string(value)))
+}
+
+func (e *maskObjectEncoder) AddInt64(key string, value int64) {
+	original := strconv.FormatInt(value, 10)
+	if s := e.masker.value(key, original); s != original {
+		e.ObjectEncoder.AddString(key, s)
+
+		return
+	}
+	e.ObjectEncoder.AddInt64(key, value)
+}
+
+func (e *maskObjectEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	if e.masker.denied(key) {
+		e.ObjectEncoder.AddString(key, e.masker.redacted())
+
+		return nil
+	}
+
+	return e.ObjectEncoder.AddObject(key, maskedObject{ObjectMarshaler: marshaler, masker: e.masker})
+}
+
+func (e *maskObjectEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	if e.masker.denied(key) {
+		e.ObjectEncoder.AddString(key, e.masker.redacted())
+
+		return nil
+	}
+
+	return e.ObjectEncoder.AddArray(key, maskedArray{ArrayMarshaler: marshaler, masker: e.masker})
+}
+
+func (e *maskObjectEncoder) AddReflected(key string, value interface{}) error {
+	masked, _ := e.masker.reflected(key, value)
+
+	return e.ObjectEncoder.AddReflected(key, masked)
+}
+
+type maskArrayEncoder struct {
+	zapcore.ArrayEncoder
+	masker *masker
+}
+
+func (e *maskArrayEncoder) AppendString(value string) {
+	e.ArrayEncoder.AppendString(e.masker.text(value))
+}
+
+func (e *maskArrayEncoder) AppendByteString(value []byte) {
+	e.ArrayEncoder.AppendString(e.masker.text(string(value)))
+}
+
+func (e *maskArrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(maskedObject{ObjectMarshaler: marshaler, masker: e.masker})
+}
+
+func (e *maskArrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(maskedArray{ArrayMarshaler: marshaler, masker: e.masker})
+}
+
+func (e *maskArrayEncoder) AppendReflected(value interface{}) error {
+	masked, _ := e.masker.reflected("", value)
+
+	return e.ArrayEncoder.AppendReflected(masked)
+}
@@ -0,0 +1,143 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/QingsiLiu/baseComponents/errors"
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+type account struct {
+	email, secret string
+}
+
+func (a account) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("email", a.email)
+	enc.AddString("secret", a.secret)
+
+	return nil
+}
+
+func initMaskedLogger(t *testing.T, enabled bool) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mask.log")
+	opts := log.NewOptions()
+	opts.Format = "json"
+	opts.OutputPaths = []string{path}
+	opts.Mask.Enabled = enabled
+	opts.Mask.Patterns = []string{`sk-[A-Za-z0-9]{8,}`}
+	assert.Empty(t, opts.Validate())
+	log.Init(opts)
+	t.Cleanup(func() { log.Init(log.NewOptions()) })
+
+	return path
+}
+
+func Test_Mask(t *testing.T) {
+	path := initMaskedLogger(t, true)
+
+	log.Infof("user %s phone %s id %s key %s", "alice@example.com", "13812345678", "11010519491231002X", "sk-abcdefgh12345678")
+	log.WithValues("authorization", "Bearer x").Infow("login",
+		"password", "hunter2",
+		"mobile", int64(13912345678),
+		"order", "20240102030405",
+		"profile", map[string]interface{}{
+			"token":  "abc",
+			"email":  "carol@example.com",
+			"nested": map[string]string{"phone": "13812345678"},
+		},
+	)
+	log.Info("account", log.Object("account", account{email: "dave@example.com", secret: "s3"}))
+
+	lines := strings.Split(strings.TrimSpace(readLog(t, path)), "\n")
+	assert.Len(t, lines, 3)
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "user a****@example.com phone 138****5678 id 110***********002X key sk-a***********5678", entry["message"])
+
+	entry = nil
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "********", entry["authorization"])
+	assert.Equal(t, "*******", entry["password"])
+	assert.Equal(t, "139****5678", entry["mobile"])
+	assert.Equal(t, "20240102030405", entry["order"])
+	assert.Equal(t, map[string]interface{}{
+		"token":  "***",
+		"email":  "c****@example.com",
+		"nested": map[string]interface{}{"phone": "138****5678"},
+	}, entry["profile"])
+
+	entry = nil
+	assert.Nil(t, json.Unmarshal([]byte(lines[2]), &entry))
+	assert.Equal(t, map[string]interface{}{"email": "d***@example.com", "secret": "**"}, entry["account"])
+}
+
+func Test_MaskPhoneFormats(t *testing.T) {
+	path := initMaskedLogger(t, true)
+
+	log.Infof("a %s b %s c %s d %s e %s f %s", "+8613812345678", "8613812345678", "138-1234-5678",
+		"+86 139 1234 5678", "013812345678", "order 2013812345678")
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(readLog(t, path)), &entry))
+	assert.Equal(t, "a +86138****5678 b 86138****5678 c 138-****-5678 d +86 139 **** 5678 e 013812345678 f order 2013812345678",
+		entry["message"])
+}
+
+func Test_MaskErrorKeepsVerbose(t *testing.T) {
+	path := initMaskedLogger(t, true)
+
+	log.Error("failed", log.Err(errors.New("no user 13812345678")), log.Any("plain", errors.New("no user")))
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(readLog(t, path)), &entry))
+	assert.Equal(t, "no user 138****5678", entry["error"])
+	verbose, _ := entry["errorVerbose"].(string)
+	assert.Contains(t, verbose, "no user 138****5678")
+	assert.Contains(t, verbose, "Test_MaskErrorKeepsVerbose")
+	assert.NotContains(t, verbose, "13812345678")
+	assert.Equal(t, "no user", entry["plain"])
+	assert.Contains(t, entry["plainVerbose"], "Test_MaskErrorKeepsVerbose")
+}
+
+func Test_MaskDisabled(t *testing.T) {
+	path := initMaskedLogger(t, false)
+	log.Infow("contact alice@example.com", "password", "hunter2")
+
+	output := readLog(t, path)
+	assert.Contains(t, output, "alice@example.com")
+	assert.Contains(t, output, "hunter2")
+}
+
+func Test_MaskValidate(t *testing.T) {
+	opts := log.NewOptions()
+	opts.Mask.Validators = []string{"passport"}
+	assert.Len(t, opts.Validate(), 1)
+	opts.Mask.Validators = nil
+	opts.Mask.Patterns = []string{"("}
+	assert.Len(t, opts.Validate(), 1)
+}
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"go.uber.org/zap"
@@ -29,15 +30,39 @@ import (
 )
 
 const (
-	flagLevel             = "log.level"
-	flagDisableCaller     = "log.disable-caller"
-	flagDisableStacktrace = "log.disable-stacktrace"
-	flagFormat            = "log.format"
-	flagEnableColor       = "log.enable-color"
-	flagOutputPaths       = "log.output-paths"
-	flagErrorOutputPaths  = "log.error-output-paths"
-	flagDevelopment       = "log.development"
-	flagName              = "log.name"
+	flagLevel              = "log.level"
+	flagDisableCaller      = "log.disable-caller"
+	flagDisableStacktrace  = "log.disable-stacktrace"
+	flagFormat             = "log.format"
+	flagEnableColor        = "log.enable-color"
+	flagOutputPaths        = "log.output-paths"
+	flagErrorOutputPaths   = "log.error-output-paths"
+	flagDevelopment        = "log.development"
+	flagName               = "log.name"
+	flagModuleLevels       = "log.module-levels"
+	flagSlogDefault        = "log.slog-default"
+	flagRotateMaxSize      = "log.rotate.max-size"
+	flagRotateMaxAge       = "log.rotate.max-age"
+	flagRotateMaxBackups   = "log.rotate.max-backups"
+	flagRotateCompress     = "log.rotate.compress"
+	flagRotateDaily        = "log.rotate.daily"
+	flagSamplingDisable    = "log.sampling.disable"
+	flagSamplingInitial    = "log.sampling.initial"
+	flagSamplingThereafter = "log.sampling.thereafter"
+	flagSamplingTick       = "log.sampling.tick"
+	flagSamplingLevels     = "log.sampling.levels"
+	flagSamplingDedup      = "log.sampling.dedup-window"
+	flagSamplingRateLimit  = "log.sampling.rate-limit"
+	flagSamplingRateBurst  = "log.sampling.rate-burst"
+	flagAsync              = "log.async"
+	flagAsyncBufferSize    = "log.async.buffer-size"
+	flagAsyncOverflow      = "log.async.overflow"
+	flagAsyncFlushInterval = "log.async.flush-interval"
+	flagMask               = "log.mask"
+	flagMaskFields         = "log.mask.fields"
+	flagMaskPatterns       = "log.mask.patterns"
+	flagMaskValidators     = "log.mask.validators"
+	flagMaskChar           = "log.mask.char"
 
 	consoleFormat = "console"
 	jsonFormat    = "json"
@@ -54,6 +79,19 @@ type Options struct {
 	EnableColor       bool     `json:"enable-color"       mapstructure:"enable-color"`
 	Development       bool     `json:"development"        mapstructure:"development"`
 	Name              string   `json:"name"               mapstructure:"name"`
+	ModuleLevels      string   `json:"module-levels"      mapstructure:"module-levels"`
+	// SlogDefault makes Init install a handler writing through the new logger as
+	// the log/slog default, so slog.Info and friends follow this configuration.
+	SlogDefault bool `json:"slog-default" mapstructure:"slog-default"`
+	// Rotate rotates the file paths in OutputPaths and ErrorOutputPaths when
+	// MaxSize or Daily is set. Paths given as rotate:// URLs use their own parameters.
+	Rotate RotateOptions `json:"rotate" mapstructure:"rotate"`
+	// Sampling configures sampling, deduplication and rate limiting of entries.
+	Sampling SamplingOptions `json:"sampling" mapstructure:"sampling"`
+	// Async writes OutputPaths through in-memory buffers in the background.
+	Async AsyncOptions `json:"async" mapstructure:"async"`
+	// Mask masks sensitive data in messages and fields when enabled.
+	Mask MaskOptions `json:"mask" mapstructure:"mask"`
 }
 
 // NewOptions creates an Options object with default parameters.
@@ -67,6 +105,15 @@ func NewOptions() *Options {
 		Development:       false,
 		OutputPaths:       []string{"stdout"},
 		ErrorOutputPaths:  []string{"stderr"},
+		Sampling: SamplingOptions{
+			Initial:    100,
+			Thereafter: 100,
+			Tick:       time.Second,
+		},
+		Mask: MaskOptions{
+			Fields:     []string{"password", "passwd", "secret", "token", "authorization"},
+			Validators: []string{MaskEmail, MaskPhone, MaskIDCard},
+		},
 	}
 }
 
@@ -79,6 +126,26 @@ func (o *Options) Validate() []error {
 		errs = append(errs, err)
 	}
 
+	if _, err := parseModuleLevels(o.ModuleLevels); err != nil {
+		errs = append(errs, err)
+	}
+
+	if o.Rotate.MaxSize < 0 || o.Rotate.MaxAge < 0 || o.Rotate.MaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("log rotation limits must not be negative"))
+	}
+
+	if err := o.Sampling.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := o.Async.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := o.Mask.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
 	format := strings.ToLower(o.Format)
 	if format != consoleFormat && format != jsonFormat {
 		errs = append(errs, fmt.Errorf("not a valid log format: %q", o.Format))
@@ -105,6 +172,50 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 			"the behavior of DPanicLevel and takes stacktraces more liberally.",
 	)
 	fs.StringVar(&o.Name, flagName, o.Name, "The name of the logger.")
+	fs.StringVar(&o.ModuleLevels, flagModuleLevels, o.ModuleLevels,
+		"Minimum log levels of named loggers as comma separated `PATTERN=LEVEL` pairs, "+
+			"e.g. runtime=debug,storage.*=warn. The longest matching pattern wins.")
+	fs.BoolVar(&o.SlogDefault, flagSlogDefault, o.SlogDefault,
+		"Install a log/slog handler writing through this logger as the slog default.")
+	fs.IntVar(&o.Rotate.MaxSize, flagRotateMaxSize, o.Rotate.MaxSize,
+		"Rotate output files once they reach this size in megabytes, 0 disables size based rotation.")
+	fs.IntVar(&o.Rotate.MaxAge, flagRotateMaxAge, o.Rotate.MaxAge,
+		"Days to keep rotated log files, 0 keeps them forever.")
+	fs.IntVar(&o.Rotate.MaxBackups, flagRotateMaxBackups, o.Rotate.MaxBackups,
+		"Number of rotated log files to keep, 0 keeps all of them.")
+	fs.BoolVar(&o.Rotate.Compress, flagRotateCompress, o.Rotate.Compress, "Compress rotated log files with gzip.")
+	fs.BoolVar(&o.Rotate.Daily, flagRotateDaily, o.Rotate.Daily, "Rotate output files when the local date changes.")
+	fs.BoolVar(&o.Sampling.Disable, flagSamplingDisable, o.Sampling.Disable,
+		"Disable sampling, per level overrides still apply.")
+	fs.IntVar(&o.Sampling.Initial, flagSamplingInitial, o.Sampling.Initial,
+		"Entries with the same level and message logged per tick before sampling starts, 0 uses the default of 100.")
+	fs.IntVar(&o.Sampling.Thereafter, flagSamplingThereafter, o.Sampling.Thereafter,
+		"Log every Nth entry with the same level and message after the initial ones, 0 drops them all.")
+	fs.DurationVar(&o.Sampling.Tick, flagSamplingTick, o.Sampling.Tick, "Interval at which sampling counters reset.")
+	fs.StringVar(&o.Sampling.Levels, flagSamplingLevels, o.Sampling.Levels,
+		"Per level sampling as comma separated `LEVEL=INITIAL:THEREAFTER` pairs, e.g. error=1000:10.")
+	fs.DurationVar(&o.Sampling.DedupWindow, flagSamplingDedup, o.Sampling.DedupWindow,
+		"Collapse identical entries within this window into one line with a repeat count, 0 disables it.")
+	fs.Float64Var(&o.Sampling.RateLimit, flagSamplingRateLimit, o.Sampling.RateLimit,
+		"Maximum entries per second of every named logger, 0 disables rate limiting.")
+	fs.IntVar(&o.Sampling.RateBurst, flagSamplingRateBurst, o.Sampling.RateBurst,
+		"Burst size of the per logger rate limit, defaults to the rate limit.")
+	fs.BoolVar(&o.Async.Enabled, flagAsync, o.Async.Enabled,
+		"Write output paths asynchronously through in-memory buffers.")
+	fs.IntVar(&o.Async.BufferSize, flagAsyncBufferSize, o.Async.BufferSize,
+		"Number of entries buffered per output path, 8192 if unset.")
+	fs.StringVar((*string)(&o.Async.Overflow), flagAsyncOverflow, string(o.Async.Overflow),
+		"What to do when the buffer is full: block, drop-oldest or drop-newest.")
+	fs.DurationVar(&o.Async.FlushInterval, flagAsyncFlushInterval, o.Async.FlushInterval,
+		"Interval at which buffered entries are written, 1s if unset.")
+	fs.BoolVar(&o.Mask.Enabled, flagMask, o.Mask.Enabled, "Mask sensitive data in log messages and fields.")
+	fs.StringSliceVar(&o.Mask.Fields, flagMaskFields, o.Mask.Fields,
+		"Names of fields whose values are always masked, matched case-insensitively.")
+	fs.StringArrayVar(&o.Mask.Patterns, flagMaskPatterns, o.Mask.Patterns,
+		"Regular expression whose matches are masked, may be repeated.")
+	fs.StringSliceVar(&o.Mask.Validators, flagMaskValidators, o.Mask.Validators,
+		"Built-in detectors of sensitive data: email, phone and idcard.")
+	fs.StringVar(&o.Mask.Char, flagMaskChar, o.Mask.Char, "Mask character, * if unset.")
 }
 
 func (o *Options) String() string {
@@ -129,11 +240,7 @@ func (o *Options) Build() error {
 		Development:       o.Development,
 		DisableCaller:     o.DisableCaller,
 		DisableStacktrace: o.DisableStacktrace,
-		Sampling: &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		},
-		Encoding: o.Format,
+		Encoding:          o.Format,
 		EncoderConfig: zapcore.EncoderConfig{
 			MessageKey:     "message",
 			LevelKey:       "level",
@@ -148,10 +255,15 @@ func (o *Options) Build() error {
 			EncodeCaller:   zapcore.ShortCallerEncoder,
 			EncodeName:     zapcore.FullNameEncoder,
 		},
-		OutputPaths:      o.OutputPaths,
-		ErrorOutputPaths: o.ErrorOutputPaths,
+		OutputPaths:      asyncPaths(rotatePaths(o.OutputPaths, o.Rotate), o.Async),
+		ErrorOutputPaths: rotatePaths(o.ErrorOutputPaths, o.Rotate),
 	}
-	logger, err := zc.Build(zap.AddStacktrace(zapcore.PanicLevel))
+	logger, err := zc.Build(
+		zap.AddStacktrace(zapcore.PanicLevel),
+		zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return wrapSampling(wrapMask(core, o.Mask), o.Sampling)
+		}),
+	)
 	if err != nil {
 		return err
 	}
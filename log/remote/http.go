@@ -0,0 +1,252 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Schemes of the HTTP sinks.
+const (
+	SchemeHTTP      = "batch+http"
+	SchemeHTTPS     = "batch+https"
+	SchemeLokiHTTP  = "loki+http"
+	SchemeLokiHTTPS = "loki+https"
+)
+
+const defaultLokiPath = "/loki/api/v1/push"
+
+func init() {
+	for scheme, factory := range map[string]func(*url.URL) (zap.Sink, error){
+		SchemeHTTP:      newHTTPSink,
+		SchemeHTTPS:     newHTTPSink,
+		SchemeLokiHTTP:  newLokiSink,
+		SchemeLokiHTTPS: newLokiSink,
+	} {
+		if err := zap.RegisterSink(scheme, factory); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// httpSender POSTs batches encoded by encode to url.
+type httpSender struct {
+	client *http.Client
+	url    string
+	header http.Header
+	encode func(entries []entry) ([]byte, error)
+	// check inspects successful responses, e.g. for an error code in the body.
+	check func(body []byte) error
+}
+
+// newHTTPSender takes the target from u, turning scheme "x+http" into "http".
+// Query parameters named header.NAME become request headers and the user info
+// becomes basic auth; other parameters stay on the target URL.
+func newHTTPSender(u *url.URL, query url.Values) *httpSender {
+	target := *u
+	if _, transport, ok := strings.Cut(u.Scheme, "+"); ok {
+		target.Scheme = transport
+	} else {
+		target.Scheme = "https"
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	for name, values := range query {
+		if key, ok := strings.CutPrefix(name, "header."); ok {
+			for _, value := range values {
+				header.Add(key, value)
+			}
+			query.Del(name)
+		}
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+		header.Set("Authorization", "Basic "+credentials)
+		target.User = nil
+	}
+	target.RawQuery = query.Encode()
+
+	return &httpSender{client: &http.Client{}, url: target.String(), header: header}
+}
+
+func (s *httpSender) send(ctx context.Context, entries []entry) error {
+	body, err := s.encode(entries)
+	if err != nil {
+		return permanentError{err}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header = s.header.Clone()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode/100 != 2 {
+		err = fmt.Errorf("remote log push to %s: %s: %s", req.URL.Redacted(), resp.Status, bytes.TrimSpace(respBody))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+			return err
+		}
+
+		return permanentError{err}
+	}
+	if s.check != nil {
+		return s.check(respBody)
+	}
+
+	return nil
+}
+
+func (s *httpSender) close() error {
+	s.client.CloseIdleConnections()
+
+	return nil
+}
+
+// newHTTPSink creates a sink posting each batch as a JSON array. JSON entries
+// are embedded as objects, console entries as strings.
+func newHTTPSink(u *url.URL) (zap.Sink, error) {
+	query := u.Query()
+	cfg, err := parseConfig(query)
+	if err != nil {
+		return nil, err
+	}
+	s := newHTTPSender(u, query)
+	s.encode = encodeJSONArray
+
+	return newBatchSink(cfg, s).start(), nil
+}
+
+func encodeJSONArray(entries []entry) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, e := range entries {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeJSONValue(&buf, e.line); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte(']')
+
+	return buf.Bytes(), nil
+}
+
+// writeJSONValue writes line as is when it is a JSON object and as a string otherwise.
+func writeJSONValue(buf *bytes.Buffer, line []byte) error {
+	if len(line) > 0 && line[0] == '{' && json.Valid(line) {
+		buf.Write(line)
+
+		return nil
+	}
+	quoted, err := json.Marshal(string(line))
+	if err != nil {
+		return err
+	}
+	buf.Write(quoted)
+
+	return nil
+}
+
+// newLokiSink creates a sink for the Loki push API. Parameters named
+// label.NAME set stream labels ({job="log"} if there are none), tenant sets
+// the X-Scope-OrgID header, and entries with a level get a level label.
+func newLokiSink(u *url.URL) (zap.Sink, error) {
+	query := u.Query()
+	cfg, err := parseConfig(query)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{}
+	for name := range query {
+		if key, ok := strings.CutPrefix(name, "label."); ok {
+			labels[key] = query.Get(name)
+			query.Del(name)
+		}
+	}
+	if len(labels) == 0 {
+		labels["job"] = "log"
+	}
+	tenant := query.Get("tenant")
+	query.Del("tenant")
+
+	target := *u
+	if target.Path == "" || target.Path == "/" {
+		target.Path = defaultLokiPath
+	}
+	s := newHTTPSender(&target, query)
+	if tenant != "" {
+		s.header.Set("X-Scope-OrgID", tenant)
+	}
+	s.encode = func(entries []entry) ([]byte, error) { return encodeLoki(labels, entries) }
+
+	return newBatchSink(cfg, s).start(), nil
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// encodeLoki groups entries into one stream per level.
+func encodeLoki(labels map[string]string, entries []entry) ([]byte, error) {
+	var streams []*lokiStream
+	byLevel := map[string]*lokiStream{}
+	for _, e := range entries {
+		level := ""
+		if l, ok := entryLevel(e.line); ok {
+			level = l.String()
+		}
+		stream, ok := byLevel[level]
+		if !ok {
+			stream = &lokiStream{Stream: make(map[string]string, len(labels)+1)}
+			for k, v := range labels {
+				stream.Stream[k] = v
+			}
+			if level != "" {
+				stream.Stream["level"] = level
+			}
+			byLevel[level] = stream
+			streams = append(streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), string(e.line)})
+	}
+
+	return json.Marshal(map[string]interface{}{"streams": streams})
+}
@@ -0,0 +1,203 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package remote
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	larkmsg "github.com/QingsiLiu/baseComponents/lark/msg"
+)
+
+// Schemes of the Lark bot sink. SchemeLark posts over HTTPS.
+const (
+	SchemeLark     = "lark"
+	SchemeLarkHTTP = "lark+http"
+)
+
+const (
+	defaultLarkThrottle   = time.Minute
+	defaultLarkMaxEntries = 10
+	larkMaxEntryLength    = 2000
+)
+
+func init() {
+	for _, scheme := range []string{SchemeLark, SchemeLarkHTTP} {
+		if err := zap.RegisterSink(scheme, newLarkSink); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// larkCard renders entries as an interactive card.
+type larkCard struct {
+	title      string
+	maxEntries int
+	hostname   string
+}
+
+// newLarkSink creates a sink posting entries at level (error by default) and
+// above to a Lark custom bot webhook. At most one card is sent per throttle
+// (1m by default); it shows up to max_entries entries and counts the rest.
+// With secret the requests are signed, and title sets the card title.
+func newLarkSink(u *url.URL) (zap.Sink, error) {
+	query := u.Query()
+	throttle := defaultLarkThrottle
+	if value := query.Get("throttle"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid lark sink parameter throttle=%q", value)
+		}
+		throttle = d
+	}
+	minLevel := zapcore.ErrorLevel
+	if value := query.Get("level"); value != "" {
+		if err := minLevel.UnmarshalText([]byte(value)); err != nil {
+			return nil, fmt.Errorf("invalid lark sink parameter level=%q", value)
+		}
+	}
+	card := larkCard{title: query.Get("title"), maxEntries: defaultLarkMaxEntries}
+	if value := query.Get("max_entries"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid lark sink parameter max_entries=%q", value)
+		}
+		card.maxEntries = n
+	}
+	card.hostname, _ = os.Hostname()
+	secret := query.Get("secret")
+	for _, name := range []string{"throttle", "level", "title", "max_entries", "secret"} {
+		query.Del(name)
+	}
+
+	cfg, err := parseConfig(query)
+	if err != nil {
+		return nil, err
+	}
+	// after a card, everything queued within the throttle goes into the next one
+	cfg.BatchSize = cfg.QueueSize
+
+	s := newHTTPSender(u, query)
+	s.encode = func(entries []entry) ([]byte, error) { return card.encode(entries, secret, time.Now()) }
+	s.check = checkLarkResponse
+
+	b := newBatchSink(cfg, s)
+	b.minGap = throttle
+	b.filter = func(line []byte) bool {
+		level, ok := entryLevel(line)
+
+		return ok && level >= minLevel
+	}
+
+	return b.start(), nil
+}
+
+func (c larkCard) encode(entries []entry, secret string, now time.Time) ([]byte, error) {
+	title := c.title
+	if title == "" {
+		title = fmt.Sprintf("%s 日志告警", c.hostname)
+	}
+
+	elements := make([]larkcard.MessageCardElement, 0, 2*c.maxEntries+1)
+	for i, e := range entries {
+		if i == c.maxEntries {
+			break
+		}
+		if i > 0 {
+			elements = append(elements, larkmsg.WithSplitLine())
+		}
+		if text := larkmsg.WithMainText(truncate(string(e.line), larkMaxEntryLength)); text != nil {
+			elements = append(elements, text)
+		}
+	}
+	note := fmt.Sprintf("%s 至 %s 共 %d 条", entries[0].time.Format(time.DateTime), entries[len(entries)-1].time.Format(time.DateTime), len(entries))
+	if len(entries) > c.maxEntries {
+		note += fmt.Sprintf("，仅显示前 %d 条", c.maxEntries)
+	}
+	elements = append(elements, larkmsg.WithNote(note))
+
+	content, err := larkmsg.NewSendCard(larkmsg.WithHeader(title, "red"), elements...)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"msg_type": "interactive",
+		"card":     json.RawMessage(content),
+	}
+	if secret != "" {
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		body["timestamp"] = timestamp
+		body["sign"] = larkSign(timestamp, secret)
+	}
+
+	return json.Marshal(body)
+}
+
+// larkSign signs a webhook request: the HMAC-SHA256 of an empty message keyed
+// by "timestamp\nsecret", base64 encoded.
+func larkSign(timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// checkLarkResponse reports the error code of a webhook response.
+func checkLarkResponse(body []byte) error {
+	var resp struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return permanentError{fmt.Errorf("invalid lark webhook response: %w", err)}
+	}
+	if resp.Code != 0 {
+		return permanentError{fmt.Errorf("lark webhook error %d: %s", resp.Code, resp.Msg)}
+	}
+
+	return nil
+}
+
+// truncate shortens s to at most n bytes without splitting a rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n] + "…"
+}
@@ -0,0 +1,392 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package remote ships log entries to remote services. Importing it registers
+// zap sinks for the following Options.OutputPaths URL schemes:
+//
+//	batch+http://host/path, batch+https://host/path  JSON array of entries per POST
+//	loki+http://host:3100, loki+https://host         Loki push API
+//	syslog+udp://host:514, syslog+tcp://host:514     RFC 5424 syslog
+//	lark://open.feishu.cn/open-apis/bot/v2/hook/ID   Lark bot cards for ERROR and above
+//
+// Every sink buffers entries in a bounded queue and sends them in batches from a
+// background goroutine, retrying failed batches with exponential backoff. The
+// query parameters batch_size, flush_interval, queue_size, overflow,
+// max_retries, retry_backoff and timeout tune this behaviour, see Config.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+const maxRetryBackoff = 30 * time.Second
+
+// Config holds the batching settings shared by every sink. Each field is read
+// from the URL query parameter named in its comment.
+type Config struct {
+	BatchSize     int                // batch_size: entries per request, 100 by default
+	FlushInterval time.Duration      // flush_interval: maximum delay of an entry, 1s by default
+	QueueSize     int                // queue_size: buffered entries, 10000 by default
+	Overflow      log.OverflowPolicy // overflow: policy when the queue is full, drop-newest by default
+	MaxRetries    int                // max_retries: retries of a failed batch, 3 by default
+	RetryBackoff  time.Duration      // retry_backoff: delay before the first retry, doubled after each, 500ms by default
+	Timeout       time.Duration      // timeout: per request, 10s by default
+}
+
+// DefaultConfig returns the default batching settings.
+func DefaultConfig() Config {
+	return Config{
+		BatchSize:     100,
+		FlushInterval: time.Second,
+		QueueSize:     10000,
+		Overflow:      log.OverflowDropNewest,
+		MaxRetries:    3,
+		RetryBackoff:  500 * time.Millisecond,
+		Timeout:       10 * time.Second,
+	}
+}
+
+// parseConfig reads the batching settings from query and removes them, leaving
+// the sink specific parameters.
+func parseConfig(query url.Values) (Config, error) {
+	cfg := DefaultConfig()
+	ints := map[string]*int{"batch_size": &cfg.BatchSize, "queue_size": &cfg.QueueSize, "max_retries": &cfg.MaxRetries}
+	for name, target := range ints {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || (n == 0 && name != "max_retries") {
+				return cfg, fmt.Errorf("invalid remote sink parameter %s=%q", name, value)
+			}
+			*target = n
+		}
+		query.Del(name)
+	}
+	durations := map[string]*time.Duration{
+		"flush_interval": &cfg.FlushInterval,
+		"retry_backoff":  &cfg.RetryBackoff,
+		"timeout":        &cfg.Timeout,
+	}
+	for name, target := range durations {
+		if value := query.Get(name); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return cfg, fmt.Errorf("invalid remote sink parameter %s=%q", name, value)
+			}
+			*target = d
+		}
+		query.Del(name)
+	}
+	if value := query.Get("overflow"); value != "" {
+		switch policy := log.OverflowPolicy(value); policy {
+		case log.OverflowBlock, log.OverflowDropOldest, log.OverflowDropNewest:
+			cfg.Overflow = policy
+		default:
+			return cfg, fmt.Errorf("invalid remote sink parameter overflow=%q", value)
+		}
+	}
+	query.Del("overflow")
+
+	return cfg, nil
+}
+
+// Counters reports what the remote sinks of this process did with their entries.
+type Counters struct {
+	Sent       uint64 `json:"sent"`       // delivered entries
+	Failed     uint64 `json:"failed"`     // entries of batches that failed after all retries
+	Overflowed uint64 `json:"overflowed"` // entries dropped because a queue was full
+}
+
+var counters struct {
+	sent, failed, overflowed atomic.Uint64
+}
+
+// Stats returns the counters of every remote sink.
+func Stats() Counters {
+	return Counters{
+		Sent:       counters.sent.Load(),
+		Failed:     counters.failed.Load(),
+		Overflowed: counters.overflowed.Load(),
+	}
+}
+
+// entry is one encoded log entry and the time it was written.
+type entry struct {
+	time time.Time
+	line []byte // without the trailing line ending
+}
+
+// sender delivers batches of entries.
+type sender interface {
+	send(ctx context.Context, entries []entry) error
+	close() error
+}
+
+// permanentError marks failures that a retry cannot fix.
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// batchSink is the zap.Sink shared by every scheme. It queues entries and
+// sends them in batches from a background goroutine.
+type batchSink struct {
+	cfg    Config
+	sender sender
+	// filter drops entries the sink is not interested in.
+	filter func(line []byte) bool
+	// minGap is the minimum time between two sends, except when closing.
+	minGap time.Duration
+
+	mu      sync.Mutex
+	notFull *sync.Cond
+	queue   []entry
+	closed  bool
+
+	lastSend time.Time // owned by the background goroutine
+
+	kick    chan struct{}
+	syncReq chan chan error
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newBatchSink(cfg Config, s sender) *batchSink {
+	b := &batchSink{
+		cfg:     cfg,
+		sender:  s,
+		kick:    make(chan struct{}, 1),
+		syncReq: make(chan chan error),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	b.notFull = sync.NewCond(&b.mu)
+
+	return b
+}
+
+func (b *batchSink) start() zap.Sink {
+	go b.run()
+
+	return b
+}
+
+// Write queues a copy of p. Entries written after Close are dropped.
+func (b *batchSink) Write(p []byte) (int, error) {
+	line := bytes.TrimRight(p, "\r\n")
+	if len(line) == 0 || (b.filter != nil && !b.filter(line)) {
+		return len(p), nil
+	}
+	e := entry{time: time.Now(), line: append([]byte(nil), line...)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for !b.closed && len(b.queue) >= b.cfg.QueueSize {
+		switch b.cfg.Overflow {
+		case log.OverflowBlock:
+			b.wake()
+			b.notFull.Wait()
+		case log.OverflowDropOldest:
+			b.queue[0] = entry{}
+			b.queue = b.queue[1:]
+			counters.overflowed.Add(1)
+		default:
+			counters.overflowed.Add(1)
+
+			return len(p), nil
+		}
+	}
+	if b.closed {
+		counters.overflowed.Add(1)
+
+		return len(p), nil
+	}
+	b.queue = append(b.queue, e)
+	if len(b.queue) >= b.cfg.BatchSize {
+		b.wake()
+	}
+
+	return len(p), nil
+}
+
+// Sync sends the queued entries, unless the sink is throttled.
+func (b *batchSink) Sync() error {
+	reply := make(chan error, 1)
+	select {
+	case b.syncReq <- reply:
+		return <-reply
+	case <-b.done:
+		return nil
+	}
+}
+
+// Close sends the queued entries and stops the background goroutine.
+func (b *batchSink) Close() error {
+	b.once.Do(func() {
+		b.mu.Lock()
+		b.closed = true
+		b.notFull.Broadcast()
+		b.mu.Unlock()
+		close(b.stop)
+	})
+	<-b.done
+
+	return b.sender.close()
+}
+
+func (b *batchSink) wake() {
+	select {
+	case b.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (b *batchSink) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = b.flush(false)
+		case <-b.kick:
+			_ = b.flush(false)
+		case reply := <-b.syncReq:
+			reply <- b.flush(false)
+		case <-b.stop:
+			_ = b.flush(true)
+
+			return
+		}
+	}
+}
+
+// flush sends the queue in batches of BatchSize. Unless force is set, it
+// sends nothing within minGap of the previous send.
+func (b *batchSink) flush(force bool) error {
+	var errs []error
+	for {
+		if !force && b.minGap > 0 && time.Since(b.lastSend) < b.minGap {
+			break
+		}
+
+		b.mu.Lock()
+		n := min(len(b.queue), b.cfg.BatchSize)
+		batch := make([]entry, n)
+		copy(batch, b.queue)
+		clear(b.queue[:n])
+		b.queue = b.queue[n:]
+		b.notFull.Broadcast()
+		b.mu.Unlock()
+
+		if n == 0 {
+			break
+		}
+		b.lastSend = time.Now()
+		if err := b.send(batch); err != nil {
+			errs = append(errs, err)
+		}
+		if !force && b.minGap > 0 {
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// send delivers batch, retrying with exponential backoff.
+func (b *batchSink) send(batch []entry) error {
+	backoff := b.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), b.cfg.Timeout)
+		err := b.sender.send(ctx, batch)
+		cancel()
+		if err == nil {
+			counters.sent.Add(uint64(len(batch)))
+
+			return nil
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) || attempt >= b.cfg.MaxRetries {
+			counters.failed.Add(uint64(len(batch)))
+
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// entryLevel returns the level of an entry encoded by the log package, reading
+// the "level" key of JSON entries or the level column of console entries.
+func entryLevel(line []byte) (zapcore.Level, bool) {
+	var level zapcore.Level
+	if len(line) > 0 && line[0] == '{' {
+		var fields struct {
+			Level string `json:"level"`
+		}
+		// an empty level would parse as info
+		if json.Unmarshal(line, &fields) != nil || fields.Level == "" || level.UnmarshalText([]byte(fields.Level)) != nil {
+			return level, false
+		}
+
+		return level, true
+	}
+
+	columns := strings.SplitN(string(line), "\t", 4)
+	for _, column := range columns[:min(len(columns), 3)] {
+		if column = stripANSI(column); column != "" && level.UnmarshalText([]byte(column)) == nil {
+			return level, true
+		}
+	}
+
+	return level, false
+}
+
+// stripANSI removes the color codes of CapitalColorLevelEncoder.
+func stripANSI(s string) string {
+	for {
+		start := strings.Index(s, "\x1b[")
+		if start < 0 {
+			return s
+		}
+		end := strings.IndexByte(s[start:], 'm')
+		if end < 0 {
+			return s
+		}
+		s = s[:start] + s[start+end+1:]
+	}
+}
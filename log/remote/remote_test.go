@@ -0,0 +1,314 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package remote
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+// recorder is a stand-in server that records request bodies and answers with
+// the queued status codes, then 200.
+type recorder struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	statuses []int
+	response string
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+	if len(r.statuses) > 0 {
+		w.WriteHeader(r.statuses[0])
+		r.statuses = r.statuses[1:]
+
+		return
+	}
+	_, _ = io.WriteString(w, r.response)
+}
+
+func (r *recorder) received() ([]*http.Request, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*http.Request(nil), r.requests...), append([]string(nil), r.bodies...)
+}
+
+func openSink(t *testing.T, rawURL string) zapcore.WriteSyncer {
+	t.Helper()
+	ws, closeSink, err := zap.Open(rawURL)
+	assert.Nil(t, err)
+	t.Cleanup(closeSink)
+
+	return ws
+}
+
+func TestHTTPSink(t *testing.T) {
+	rec := &recorder{statuses: []int{http.StatusServiceUnavailable}}
+	server := httptest.NewServer(rec)
+	t.Cleanup(server.Close)
+	before := Stats()
+
+	target := strings.Replace(server.URL, "http://", "batch+http://user:pass@", 1)
+	sink := openSink(t, target+"/ingest?batch_size=2&flush_interval=1h&retry_backoff=1ms&header.X-Token=abc&source=api")
+	_, _ = sink.Write([]byte(`{"level":"info","message":"one"}` + "\n"))
+	_, _ = sink.Write([]byte("2024-01-02 03:04:05.000\tWARN\tconsole line\n"))
+	_, _ = sink.Write([]byte(`{"level":"error","message":"three"}` + "\n"))
+	assert.Nil(t, sink.Sync())
+
+	requests, bodies := rec.received()
+	// the first request failed with 503 and was retried
+	assert.Len(t, requests, 3)
+	assert.Equal(t, "/ingest", requests[0].URL.Path)
+	assert.Equal(t, "api", requests[0].URL.Query().Get("source"))
+	assert.Equal(t, "abc", requests[0].Header.Get("X-Token"))
+	user, pass, ok := requests[0].BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user:pass", user+":"+pass)
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.JSONEq(t, `[{"level":"info","message":"one"},"2024-01-02 03:04:05.000\tWARN\tconsole line"]`, bodies[1])
+	assert.JSONEq(t, `[{"level":"error","message":"three"}]`, bodies[2])
+	assert.Equal(t, uint64(3), Stats().Sent-before.Sent)
+}
+
+func TestHTTPSinkPermanentError(t *testing.T) {
+	rec := &recorder{statuses: []int{http.StatusBadRequest, http.StatusBadRequest}}
+	server := httptest.NewServer(rec)
+	t.Cleanup(server.Close)
+	before := Stats()
+
+	sink := openSink(t, strings.Replace(server.URL, "http://", "batch+http://", 1)+"?retry_backoff=1ms")
+	_, _ = sink.Write([]byte("lost\n"))
+	assert.NotNil(t, sink.Sync())
+
+	requests, _ := rec.received()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, uint64(1), Stats().Failed-before.Failed)
+}
+
+func TestLokiSink(t *testing.T) {
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	t.Cleanup(server.Close)
+
+	sink := openSink(t, strings.Replace(server.URL, "http://", "loki+http://", 1)+"?label.app=api&tenant=team-a")
+	_, _ = sink.Write([]byte(`{"level":"info","message":"one"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"level":"error","message":"two"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"level":"info","message":"three"}` + "\n"))
+	assert.Nil(t, sink.Sync())
+
+	requests, bodies := rec.received()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, defaultLokiPath, requests[0].URL.Path)
+	assert.Equal(t, "team-a", requests[0].Header.Get("X-Scope-OrgID"))
+
+	var push struct {
+		Streams []lokiStream `json:"streams"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(bodies[0]), &push))
+	assert.Len(t, push.Streams, 2)
+	assert.Equal(t, map[string]string{"app": "api", "level": "info"}, push.Streams[0].Stream)
+	assert.Len(t, push.Streams[0].Values, 2)
+	assert.Equal(t, `{"level":"info","message":"three"}`, push.Streams[0].Values[1][1])
+	_, err := strconv.ParseInt(push.Streams[0].Values[0][0], 10, 64)
+	assert.Nil(t, err)
+	assert.Equal(t, "error", push.Streams[1].Stream["level"])
+}
+
+func TestSyslogUDPSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	sink := openSink(t, "syslog+udp://"+conn.LocalAddr().String()+"?facility=local0&app=api&hostname=web-1")
+	_, _ = sink.Write([]byte(`{"level":"error","message":"boom"}` + "\n"))
+	assert.Nil(t, sink.Sync())
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	msg := string(buf[:n])
+	// local0 (16) * 8 + error (3)
+	assert.True(t, strings.HasPrefix(msg, "<131>1 "), msg)
+	assert.Contains(t, msg, " web-1 api ")
+	assert.True(t, strings.HasSuffix(msg, ` - - {"level":"error","message":"boom"}`), msg)
+}
+
+func TestSyslogTCPSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	sink := openSink(t, "syslog+tcp://"+listener.Addr().String()+"?app=api")
+	_, _ = sink.Write([]byte("2024-01-02 03:04:05.000\tDEBUG\tfirst\n"))
+	_, _ = sink.Write([]byte("2024-01-02 03:04:05.000\tWARN\tsecond\n"))
+	assert.Nil(t, sink.Sync())
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	for _, want := range []string{"<15>1 ", "<12>1 "} {
+		size, err := reader.ReadString(' ')
+		assert.Nil(t, err)
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		assert.Nil(t, err)
+		msg := make([]byte, n)
+		_, err = io.ReadFull(reader, msg)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(string(msg), want), string(msg))
+	}
+}
+
+func TestLarkSink(t *testing.T) {
+	rec := &recorder{response: `{"code":0,"msg":"success"}`}
+	server := httptest.NewServer(rec)
+	t.Cleanup(server.Close)
+
+	sink := openSink(t, strings.Replace(server.URL, "http://", "lark+http://", 1)+
+		"/open-apis/bot/v2/hook/token?secret=s3cr3t&title=api&max_entries=1&throttle=1h")
+	_, _ = sink.Write([]byte(`{"level":"info","message":"ignored"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"level":"error","message":"first"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"level":"fatal","message":"second"}` + "\n"))
+	assert.Nil(t, sink.Sync())
+	// throttled until the hour is over
+	_, _ = sink.Write([]byte(`{"level":"error","message":"third"}` + "\n"))
+	assert.Nil(t, sink.Sync())
+
+	requests, bodies := rec.received()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "/open-apis/bot/v2/hook/token", requests[0].URL.Path)
+	var body struct {
+		MsgType   string          `json:"msg_type"`
+		Card      json.RawMessage `json:"card"`
+		Timestamp string          `json:"timestamp"`
+		Sign      string          `json:"sign"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(bodies[0]), &body))
+	assert.Equal(t, "interactive", body.MsgType)
+	assert.Equal(t, larkSign(body.Timestamp, "s3cr3t"), body.Sign)
+	card := string(body.Card)
+	assert.Contains(t, card, "first")
+	assert.NotContains(t, card, "second")
+	assert.NotContains(t, card, "ignored")
+	assert.Contains(t, card, "共 2 条")
+}
+
+func TestOverflow(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(started) })
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	before := Stats()
+
+	sink := openSink(t, strings.Replace(server.URL, "http://", "batch+http://", 1)+"?batch_size=1&queue_size=1")
+	// the first entry is in flight, the second fills the queue and the third is dropped
+	_, _ = sink.Write([]byte("a\n"))
+	<-started
+	_, _ = sink.Write([]byte("b\n"))
+	_, _ = sink.Write([]byte("c\n"))
+	close(release)
+	assert.Nil(t, sink.Sync())
+
+	after := Stats()
+	assert.Equal(t, uint64(1), after.Overflowed-before.Overflowed)
+	assert.Equal(t, uint64(2), after.Sent-before.Sent)
+}
+
+func TestOutputPaths(t *testing.T) {
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	t.Cleanup(server.Close)
+
+	opts := log.NewOptions()
+	opts.Format = "json"
+	opts.OutputPaths = []string{filepath.Join(t.TempDir(), "app.log"), strings.Replace(server.URL, "http://", "batch+http://", 1)}
+	log.Init(opts)
+	t.Cleanup(func() { log.Init(log.NewOptions()) })
+
+	log.Infow("shipped", "id", 7)
+	log.Flush()
+
+	_, bodies := rec.received()
+	assert.Len(t, bodies, 1)
+	var entries []map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(bodies[0]), &entries))
+	assert.Equal(t, "shipped", entries[0]["message"])
+	assert.Equal(t, float64(7), entries[0]["id"])
+}
+
+func TestOutputPathsWithAsync(t *testing.T) {
+	batch, lark := &recorder{}, &recorder{response: `{"code":0,"msg":"success"}`}
+	batchServer, larkServer := httptest.NewServer(batch), httptest.NewServer(lark)
+	t.Cleanup(batchServer.Close)
+	t.Cleanup(larkServer.Close)
+
+	opts := log.NewOptions()
+	opts.Format = "json"
+	opts.Async.Enabled = true
+	opts.OutputPaths = []string{
+		filepath.Join(t.TempDir(), "app.log"),
+		strings.Replace(batchServer.URL, "http://", "batch+http://", 1),
+		strings.Replace(larkServer.URL, "http://", "lark+http://", 1) + "/open-apis/bot/v2/hook/token",
+	}
+	log.Init(opts)
+	t.Cleanup(func() { log.Init(log.NewOptions()) })
+
+	log.Info("first")
+	log.Error("second")
+	log.Info("third")
+	log.Flush()
+
+	// every entry reaches the remote sinks on its own
+	_, bodies := batch.received()
+	assert.Len(t, bodies, 1)
+	var entries []map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(bodies[0]), &entries))
+	assert.Len(t, entries, 3)
+	_, bodies = lark.received()
+	assert.Len(t, bodies, 1)
+	assert.Contains(t, bodies[0], "second")
+	assert.NotContains(t, bodies[0], "third")
+}
@@ -0,0 +1,192 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Schemes of the syslog sinks.
+const (
+	SchemeSyslogUDP = "syslog+udp"
+	SchemeSyslogTCP = "syslog+tcp"
+)
+
+// rfc5424Time is the TIMESTAMP format of RFC 5424, limited to microseconds.
+const rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+
+func init() {
+	for _, scheme := range []string{SchemeSyslogUDP, SchemeSyslogTCP} {
+		if err := zap.RegisterSink(scheme, newSyslogSink); err != nil {
+			panic(err)
+		}
+	}
+}
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// severities maps levels to RFC 5424 severities.
+var severities = map[zapcore.Level]int{
+	zapcore.DebugLevel:  7,
+	zapcore.InfoLevel:   6,
+	zapcore.WarnLevel:   4,
+	zapcore.ErrorLevel:  3,
+	zapcore.DPanicLevel: 2,
+	zapcore.PanicLevel:  1,
+	zapcore.FatalLevel:  0,
+}
+
+// syslogSender writes RFC 5424 messages, one datagram each over UDP and with
+// octet counting framing (RFC 6587) over TCP. It reconnects after errors.
+type syslogSender struct {
+	network  string
+	addr     string
+	facility int
+	hostname string
+	app      string
+	pid      int
+
+	conn net.Conn
+}
+
+// newSyslogSink creates a syslog sink. The facility (user by default), app
+// (the program name by default) and hostname parameters fill in the header.
+func newSyslogSink(u *url.URL) (zap.Sink, error) {
+	query := u.Query()
+	cfg, err := parseConfig(query)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("syslog sink URL requires a host")
+	}
+
+	s := &syslogSender{
+		network:  "udp",
+		addr:     u.Host,
+		facility: facilities["user"],
+		hostname: query.Get("hostname"),
+		app:      query.Get("app"),
+		pid:      os.Getpid(),
+	}
+	if u.Scheme == SchemeSyslogTCP {
+		s.network = "tcp"
+	}
+	if name := query.Get("facility"); name != "" {
+		facility, ok := facilities[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid syslog facility %q", name)
+		}
+		s.facility = facility
+	}
+	if s.hostname == "" {
+		s.hostname, _ = os.Hostname()
+	}
+	if s.app == "" {
+		s.app = filepath.Base(os.Args[0])
+	}
+
+	return newBatchSink(cfg, s).start(), nil
+}
+
+// format renders e as an RFC 5424 message.
+func (s *syslogSender) format(e entry) []byte {
+	severity := severities[zapcore.InfoLevel]
+	if level, ok := entryLevel(e.line); ok {
+		severity = severities[level]
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %d - - ", s.facility*8+severity, e.time.Format(rfc5424Time),
+		headerField(s.hostname), headerField(s.app), s.pid)
+	buf.Write(e.line)
+
+	return buf.Bytes()
+}
+
+// headerField returns the NILVALUE for empty header fields.
+func headerField(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+func (s *syslogSender) send(ctx context.Context, entries []entry) error {
+	if s.conn == nil {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, s.network, s.addr)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(deadline)
+	}
+
+	var err error
+	if s.network == "udp" {
+		for _, e := range entries {
+			if _, err = s.conn.Write(s.format(e)); err != nil {
+				break
+			}
+		}
+	} else {
+		var buf bytes.Buffer
+		for _, e := range entries {
+			msg := s.format(e)
+			buf.WriteString(strconv.Itoa(len(msg)))
+			buf.WriteByte(' ')
+			buf.Write(msg)
+		}
+		_, err = s.conn.Write(buf.Bytes())
+	}
+	if err != nil {
+		_ = s.close()
+	}
+
+	return err
+}
+
+func (s *syslogSender) close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
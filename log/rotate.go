@@ -0,0 +1,493 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RotateScheme is the zap sink scheme of rotating files, e.g.
+// "rotate:///var/log/app.log?max_size=100&max_backups=10&compress=true".
+const RotateScheme = "rotate"
+
+const (
+	megabyte        = 1024 * 1024
+	backupTimestamp = "2006-01-02T15-04-05.000"
+	compressSuffix  = ".gz"
+)
+
+func init() {
+	if err := zap.RegisterSink(RotateScheme, newRotateSink); err != nil {
+		panic(err)
+	}
+}
+
+// RotateOptions configures rotation of log files. A file rotates when it would
+// exceed MaxSize or, with Daily, on the first write of a new day. Rotated files
+// are renamed to name-<timestamp>.ext next to the log file.
+type RotateOptions struct {
+	MaxSize    int  `json:"max-size"    mapstructure:"max-size"`    // megabytes, 0 disables size based rotation
+	MaxAge     int  `json:"max-age"     mapstructure:"max-age"`     // days to keep rotated files, 0 keeps them forever
+	MaxBackups int  `json:"max-backups" mapstructure:"max-backups"` // rotated files to keep, 0 keeps all of them
+	Compress   bool `json:"compress"    mapstructure:"compress"`    // gzip rotated files
+	Daily      bool `json:"daily"       mapstructure:"daily"`       // rotate when the local date changes
+
+	// Now returns the current time, time.Now by default.
+	Now func() time.Time `json:"-" mapstructure:"-"`
+}
+
+// enabled reports whether plain file outputs should be rotated.
+func (o RotateOptions) enabled() bool {
+	return o.MaxSize > 0 || o.Daily
+}
+
+// query encodes the options as sink URL parameters.
+func (o RotateOptions) query() string {
+	values := url.Values{}
+	if o.MaxSize > 0 {
+		values.Set("max_size", strconv.Itoa(o.MaxSize))
+	}
+	if o.MaxAge > 0 {
+		values.Set("max_age", strconv.Itoa(o.MaxAge))
+	}
+	if o.MaxBackups > 0 {
+		values.Set("max_backups", strconv.Itoa(o.MaxBackups))
+	}
+	if o.Compress {
+		values.Set("compress", "true")
+	}
+	if o.Daily {
+		values.Set("daily", "true")
+	}
+
+	return values.Encode()
+}
+
+// rotatePaths turns plain file paths into rotate sink URLs when rotation is
+// enabled. stdout, stderr and URLs with an explicit scheme are kept as is.
+func rotatePaths(paths []string, opts RotateOptions) []string {
+	if !opts.enabled() {
+		return paths
+	}
+
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path == "stdout" || path == "stderr" || strings.Contains(path, "://") {
+			result = append(result, path)
+
+			continue
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		result = append(result, (&url.URL{Scheme: RotateScheme, Path: filepath.ToSlash(path), RawQuery: opts.query()}).String())
+	}
+
+	return result
+}
+
+// parseRotateURL parses a rotate sink URL into the file path and options.
+func parseRotateURL(u *url.URL) (string, RotateOptions, error) {
+	var opts RotateOptions
+	if u.Host != "" && u.Host != "localhost" {
+		return "", opts, fmt.Errorf("rotate sink URL must not have a host, got %q", u.Host)
+	}
+	if u.Path == "" {
+		return "", opts, errors.New("rotate sink URL requires a file path")
+	}
+
+	query := u.Query()
+	for name, target := range map[string]*int{"max_size": &opts.MaxSize, "max_age": &opts.MaxAge, "max_backups": &opts.MaxBackups} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return "", opts, fmt.Errorf("invalid rotate sink parameter %s=%q", name, value)
+			}
+			*target = n
+		}
+	}
+	for name, target := range map[string]*bool{"compress": &opts.Compress, "daily": &opts.Daily} {
+		if value := query.Get(name); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return "", opts, fmt.Errorf("invalid rotate sink parameter %s=%q", name, value)
+			}
+			*target = b
+		}
+	}
+
+	return filepath.FromSlash(u.Path), opts, nil
+}
+
+var (
+	rotateFilesMu sync.Mutex
+	rotateFiles   = map[string]*rotateSink{}
+)
+
+// rotateSink shares one RotateFile between every sink opened for the same path,
+// so that loggers built by New, Init and Options.Build never rotate the same
+// file independently. The most recently opened sink's options apply.
+type rotateSink struct {
+	*RotateFile
+	refs int
+}
+
+func newRotateSink(u *url.URL) (zap.Sink, error) {
+	path, opts, err := parseRotateURL(u)
+	if err != nil {
+		return nil, err
+	}
+
+	rotateFilesMu.Lock()
+	defer rotateFilesMu.Unlock()
+
+	if sink, ok := rotateFiles[path]; ok {
+		sink.refs++
+		sink.setOptions(opts)
+
+		return &sharedRotateSink{sink: sink, path: path}, nil
+	}
+	file, err := NewRotateFile(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	sink := &rotateSink{RotateFile: file, refs: 1}
+	rotateFiles[path] = sink
+
+	return &sharedRotateSink{sink: sink, path: path}, nil
+}
+
+// sharedRotateSink is one reference to a shared rotateSink.
+type sharedRotateSink struct {
+	sink   *rotateSink
+	path   string
+	closed sync.Once
+}
+
+func (s *sharedRotateSink) Write(p []byte) (int, error) { return s.sink.Write(p) }
+func (s *sharedRotateSink) Sync() error                 { return s.sink.Sync() }
+
+func (s *sharedRotateSink) Close() error {
+	var err error
+	s.closed.Do(func() {
+		rotateFilesMu.Lock()
+		defer rotateFilesMu.Unlock()
+
+		s.sink.refs--
+		if s.sink.refs == 0 {
+			delete(rotateFiles, s.path)
+			err = s.sink.Close()
+		}
+	})
+
+	return err
+}
+
+// RotateFile is an io.WriteCloser that writes to a file and rotates it by size
+// and date. Removing and compressing rotated files happens in the background.
+type RotateFile struct {
+	path string
+
+	mu   sync.Mutex
+	opts RotateOptions
+	file *os.File
+	size int64
+	day  string
+
+	millMu sync.Mutex
+	millWg sync.WaitGroup
+}
+
+// NewRotateFile opens path for appending, creating it and its directory if needed.
+func NewRotateFile(path string, opts RotateOptions) (*RotateFile, error) {
+	f := &RotateFile{path: path, opts: opts}
+	if f.opts.Now == nil {
+		f.opts.Now = time.Now
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *RotateFile) setOptions(opts RotateOptions) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if opts.Now == nil {
+		opts.Now = f.opts.Now
+	}
+	f.opts = opts
+}
+
+// Write implements io.Writer, rotating the file first when needed.
+func (f *RotateFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	now := f.opts.Now()
+	maxSize := int64(f.opts.MaxSize) * megabyte
+	if (f.opts.Daily && now.Format("2006-01-02") != f.day) || (maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > maxSize) {
+		if err := f.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// Sync commits the current file to stable storage.
+func (f *RotateFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	return f.file.Sync()
+}
+
+// Rotate rotates the file immediately.
+func (f *RotateFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+
+	return f.rotate(f.opts.Now())
+}
+
+// Close closes the file and waits for background cleanup to finish.
+func (f *RotateFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+	f.millWg.Wait()
+
+	return err
+}
+
+// open opens the log file for appending. The modification time of an existing
+// file decides which day its content belongs to.
+func (f *RotateFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	f.day = f.opts.Now().Format("2006-01-02")
+	if info.Size() > 0 {
+		f.day = info.ModTime().Format("2006-01-02")
+	}
+
+	return nil
+}
+
+// rotate renames the current file to a timestamped backup and opens a new one.
+// Callers must hold f.mu.
+func (f *RotateFile) rotate(now time.Time) error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	if err := os.Rename(f.path, f.backupName(now)); err != nil && !os.IsNotExist(err) {
+		// keep appending to the current file rather than losing every later write
+		if openErr := f.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.day = now.Format("2006-01-02")
+
+	opts := f.opts
+	f.millWg.Add(1)
+	go func() {
+		defer f.millWg.Done()
+		f.mill(opts)
+	}()
+
+	return nil
+}
+
+// backupName returns a backup path that is not taken yet. Rotations within the
+// same millisecond get a "-1", "-2", ... suffix after the timestamp.
+func (f *RotateFile) backupName(now time.Time) string {
+	dir, base := filepath.Split(f.path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext) + "-" + now.Local().Format(backupTimestamp)
+
+	name := filepath.Join(dir, stem+ext)
+	for seq := 1; exists(name) || exists(name+compressSuffix); seq++ {
+		name = filepath.Join(dir, stem+"-"+strconv.Itoa(seq)+ext)
+	}
+
+	return name
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+
+	return err == nil
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+	seq  int
+}
+
+// backups lists rotated files, newest first.
+func (f *RotateFile) backups() ([]backupFile, error) {
+	dir, base := filepath.Split(f.path)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return nil, err
+	}
+	var backups []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), compressSuffix), ext)
+		seq := 0
+		if len(stamp) > len(backupTimestamp) && stamp[len(backupTimestamp)] == '-' {
+			if seq, err = strconv.Atoi(stamp[len(backupTimestamp)+1:]); err != nil {
+				continue
+			}
+			stamp = stamp[:len(backupTimestamp)]
+		}
+		t, err := time.ParseInLocation(backupTimestamp, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), time: t, seq: seq})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].time.Equal(backups[j].time) {
+			return backups[i].time.After(backups[j].time)
+		}
+
+		return backups[i].seq > backups[j].seq
+	})
+
+	return backups, nil
+}
+
+// mill removes backups beyond MaxBackups or older than MaxAge and compresses
+// the remaining ones.
+func (f *RotateFile) mill(opts RotateOptions) {
+	f.millMu.Lock()
+	defer f.millMu.Unlock()
+
+	backups, err := f.backups()
+	if err != nil {
+		return
+	}
+	cutoff := opts.Now().Add(-time.Duration(opts.MaxAge) * 24 * time.Hour)
+	for i, backup := range backups {
+		if (opts.MaxBackups > 0 && i >= opts.MaxBackups) || (opts.MaxAge > 0 && backup.time.Before(cutoff)) {
+			_ = os.Remove(backup.path)
+
+			continue
+		}
+		if opts.Compress && !strings.HasSuffix(backup.path, compressSuffix) {
+			_ = compressFile(backup.path)
+		}
+	}
+}
+
+// compressFile gzips path into path.gz and removes path.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + compressSuffix + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path+compressSuffix); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
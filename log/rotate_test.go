@@ -0,0 +1,153 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func Test_RotateFileBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	var mu sync.Mutex // Now is called by the background mill as well
+	file, err := log.NewRotateFile(path, log.RotateOptions{
+		MaxSize:    1,
+		MaxBackups: 1,
+		Compress:   true,
+		Now: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			now = now.Add(time.Second)
+
+			return now
+		},
+	})
+	assert.Nil(t, err)
+
+	chunk := bytes.Repeat([]byte("x"), 600*1024)
+	for i := 0; i < 3; i++ {
+		_, err = file.Write(chunk)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, file.Close())
+
+	// two rotations, only the newest backup is kept and it is compressed
+	names := listDir(t, dir)
+	assert.Len(t, names, 2)
+	assert.True(t, strings.HasPrefix(names[0], "app-2024-01-02T03-04-") && strings.HasSuffix(names[0], ".log.gz"), names[0])
+
+	gz, err := os.Open(filepath.Join(dir, names[0]))
+	assert.Nil(t, err)
+	defer gz.Close()
+	reader, err := gzip.NewReader(gz)
+	assert.Nil(t, err)
+	content, err := io.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk, content)
+}
+
+func Test_RotateFileDaily(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 1, 2, 23, 59, 0, 0, time.Local)
+	// a backup older than MaxAge is removed on the next rotation
+	stale := filepath.Join(dir, "app-2023-12-01T00-00-00.000.log")
+	assert.Nil(t, os.WriteFile(stale, []byte("old"), 0o644))
+
+	file, err := log.NewRotateFile(path, log.RotateOptions{Daily: true, MaxAge: 7, Now: func() time.Time { return now }})
+	assert.Nil(t, err)
+	_, _ = file.Write([]byte("day one\n"))
+	now = now.Add(2 * time.Minute)
+	_, _ = file.Write([]byte("day two\n"))
+	assert.Nil(t, file.Close())
+
+	assert.Equal(t, []string{"app-2024-01-03T00-01-00.000.log", "app.log"}, listDir(t, dir))
+	current, _ := os.ReadFile(path)
+	assert.Equal(t, "day two\n", string(current))
+}
+
+func Test_RotateFileBackupNameCollision(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	file, err := log.NewRotateFile(path, log.RotateOptions{MaxSize: 1, MaxBackups: 2, Now: func() time.Time { return now }})
+	assert.Nil(t, err)
+
+	// every rotation happens within the same millisecond
+	for i := 0; i < 4; i++ {
+		_, err = file.Write(bytes.Repeat([]byte{byte('a' + i)}, 600*1024))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, file.Close())
+
+	// three rotations, the oldest backup is removed by MaxBackups
+	assert.Equal(t, []string{
+		"app-2024-01-02T03-04-05.000-1.log",
+		"app-2024-01-02T03-04-05.000-2.log",
+		"app.log",
+	}, listDir(t, dir))
+	newest, _ := os.ReadFile(filepath.Join(dir, "app-2024-01-02T03-04-05.000-2.log"))
+	assert.Equal(t, byte('c'), newest[0])
+}
+
+func Test_RotateOptionsOutputPaths(t *testing.T) {
+	dir := t.TempDir()
+	opts := log.NewOptions()
+	opts.OutputPaths = []string{filepath.Join(dir, "app.log"), "rotate://" + filepath.ToSlash(filepath.Join(dir, "explicit.log")) + "?max_size=10"}
+	opts.Rotate = log.RotateOptions{MaxSize: 100, MaxBackups: 3}
+	assert.Empty(t, opts.Validate())
+	log.Init(opts)
+	t.Cleanup(func() { log.Init(log.NewOptions()) })
+
+	log.Info("rotated output")
+	log.Flush()
+
+	for _, name := range []string{"app.log", "explicit.log"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		assert.Nil(t, err)
+		assert.True(t, strings.Contains(string(data), "rotated output"), name)
+	}
+}
@@ -0,0 +1,425 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingOptions limits the volume of repeated and bursty entries.
+//
+// Sampling logs the first Initial entries with the same level and message in
+// every Tick and then every Thereafter-th one. Zero values keep the historical
+// 100 and 100 per second, Disable turns sampling off. Deduplication collapses
+// entries with the same level, logger name and message within DedupWindow into
+// the first one plus a summary line with a "repeated" count. RateLimit caps the entries per
+// second of every named logger with a token bucket of RateBurst entries.
+type SamplingOptions struct {
+	Disable    bool          `json:"disable"    mapstructure:"disable"`    // turns off sampling except for Levels
+	Initial    int           `json:"initial"    mapstructure:"initial"`    // 100 by default
+	Thereafter int           `json:"thereafter" mapstructure:"thereafter"` // 100 when Initial is 0, else 0 drops every entry after Initial
+	Tick       time.Duration `json:"tick"       mapstructure:"tick"`       // 1s by default
+	// Levels overrides Initial and Thereafter for single levels as comma separated
+	// LEVEL=INITIAL:THEREAFTER pairs, e.g. "error=1000:10,debug=0:0".
+	Levels      string        `json:"levels"       mapstructure:"levels"`
+	DedupWindow time.Duration `json:"dedup-window" mapstructure:"dedup-window"` // 0 disables deduplication
+	RateLimit   float64       `json:"rate-limit"   mapstructure:"rate-limit"`   // entries per second, 0 disables
+	RateBurst   int           `json:"rate-burst"   mapstructure:"rate-burst"`   // RateLimit rounded up by default
+
+	// Now returns the current time, time.Now by default.
+	Now func() time.Time `json:"-" mapstructure:"-"`
+}
+
+// defaultSampling is the sampling zap.NewProductionConfig uses and Options
+// applied before sampling became configurable.
+const defaultSampling = 100
+
+// validate reports invalid sampling options.
+func (o SamplingOptions) validate() error {
+	if o.Initial < 0 || o.Thereafter < 0 || o.Tick < 0 || o.DedupWindow < 0 || o.RateLimit < 0 || o.RateBurst < 0 {
+		return fmt.Errorf("log sampling options must not be negative")
+	}
+	_, err := parseSamplingLevels(o.Levels)
+
+	return err
+}
+
+type samplingRule struct {
+	initial, thereafter int
+}
+
+// parseSamplingLevels parses a "level=initial:thereafter,..." spec.
+func parseSamplingLevels(spec string) (map[zapcore.Level]samplingRule, error) {
+	rules := map[zapcore.Level]samplingRule{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		levelText, ruleText, ok := strings.Cut(item, "=")
+		initialText, thereafterText, ok2 := strings.Cut(ruleText, ":")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid sampling level %q, want level=initial:thereafter", item)
+		}
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(levelText))); err != nil {
+			return nil, fmt.Errorf("invalid sampling level %q: %w", item, err)
+		}
+		initial, err := strconv.Atoi(strings.TrimSpace(initialText))
+		if err != nil || initial < 0 {
+			return nil, fmt.Errorf("invalid sampling level %q: bad initial", item)
+		}
+		thereafter, err := strconv.Atoi(strings.TrimSpace(thereafterText))
+		if err != nil || thereafter < 0 {
+			return nil, fmt.Errorf("invalid sampling level %q: bad thereafter", item)
+		}
+		rules[level] = samplingRule{initial: initial, thereafter: thereafter}
+	}
+
+	return rules, nil
+}
+
+// DropStats counts the entries dropped by sampling, deduplication, rate
+// limiting and full async buffers since the process started.
+type DropStats struct {
+	Sampled      uint64 `json:"sampled"`
+	Deduplicated uint64 `json:"deduplicated"`
+	RateLimited  uint64 `json:"rate-limited"`
+	Overflowed   uint64 `json:"overflowed"`
+}
+
+var drops struct {
+	sampled, deduplicated, rateLimited, overflowed atomic.Uint64
+}
+
+// Dropped returns the number of entries dropped by every logger of this package.
+func Dropped() DropStats {
+	return DropStats{
+		Sampled:      drops.sampled.Load(),
+		Deduplicated: drops.deduplicated.Load(),
+		RateLimited:  drops.rateLimited.Load(),
+		Overflowed:   drops.overflowed.Load(),
+	}
+}
+
+// wrapSampling wraps core with the sampler, rate limiter and deduplicator
+// configured by opts. Invalid level overrides are rejected by Validate and
+// ignored here.
+func wrapSampling(core zapcore.Core, opts SamplingOptions) zapcore.Core {
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	core = newSampler(core, opts)
+	if opts.RateLimit > 0 {
+		burst := opts.RateBurst
+		if burst <= 0 {
+			burst = int(math.Ceil(opts.RateLimit))
+		}
+		core = &rateLimitCore{Core: core, limiter: &rateLimiter{
+			rate:    opts.RateLimit,
+			burst:   float64(burst),
+			now:     now,
+			buckets: map[string]*tokenBucket{},
+		}}
+	}
+	if opts.DedupWindow > 0 {
+		core = &dedupCore{Core: core, dedup: &deduper{
+			window: opts.DedupWindow,
+			now:    now,
+			seen:   map[dedupKey]*dedupEntry{},
+		}}
+	}
+
+	return core
+}
+
+func newSampler(core zapcore.Core, opts SamplingOptions) zapcore.Core {
+	tick := opts.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	hook := zapcore.SamplerHook(func(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+		if dec&zapcore.LogDropped > 0 {
+			drops.sampled.Add(1)
+		}
+	})
+	build := func(rule samplingRule) zapcore.Core {
+		if rule.initial <= 0 {
+			return core
+		}
+		thereafter := rule.thereafter
+		if thereafter <= 0 {
+			thereafter = math.MaxInt
+		}
+
+		return zapcore.NewSamplerWithOptions(core, tick, rule.initial, thereafter, hook)
+	}
+
+	rule := samplingRule{initial: opts.Initial, thereafter: opts.Thereafter}
+	switch {
+	case opts.Disable:
+		rule = samplingRule{}
+	case rule.initial == 0:
+		rule.initial = defaultSampling
+		if rule.thereafter == 0 {
+			rule.thereafter = defaultSampling
+		}
+	}
+	sampler := build(rule)
+	rules, _ := parseSamplingLevels(opts.Levels)
+	if len(rules) == 0 {
+		return sampler
+	}
+
+	lc := &levelSamplerCore{Core: sampler, levels: make(map[zapcore.Level]zapcore.Core, len(rules))}
+	for level, rule := range rules {
+		lc.levels[level] = build(rule)
+	}
+
+	return lc
+}
+
+// levelSamplerCore routes entries to the sampler of their level, falling back
+// to the embedded default sampler.
+type levelSamplerCore struct {
+	zapcore.Core
+	levels map[zapcore.Level]zapcore.Core
+}
+
+func (c *levelSamplerCore) With(fields []zapcore.Field) zapcore.Core {
+	levels := make(map[zapcore.Level]zapcore.Core, len(c.levels))
+	for level, core := range c.levels {
+		levels[level] = core.With(fields)
+	}
+
+	return &levelSamplerCore{Core: c.Core.With(fields), levels: levels}
+}
+
+func (c *levelSamplerCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core, ok := c.levels[entry.Level]; ok {
+		return core.Check(entry, checked)
+	}
+
+	return c.Core.Check(entry, checked)
+}
+
+// rateLimitCore drops the entries of a named logger that exceed its token bucket.
+type rateLimitCore struct {
+	zapcore.Core
+	limiter *rateLimiter
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{Core: c.Core.With(fields), limiter: c.limiter}
+}
+
+func (c *rateLimitCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return checked
+	}
+	if !c.limiter.allow(entry.LoggerName) {
+		drops.rateLimited.Add(1)
+
+		return checked
+	}
+
+	return c.Core.Check(entry, checked)
+}
+
+type rateLimiter struct {
+	rate, burst float64
+	now         func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket // logger name -> bucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token from the bucket of the named logger.
+func (l *rateLimiter) allow(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	bucket, ok := l.buckets[name]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[name] = bucket
+	}
+	if elapsed := now.Sub(bucket.last).Seconds(); elapsed > 0 {
+		bucket.tokens = math.Min(l.burst, bucket.tokens+elapsed*l.rate)
+		bucket.last = now
+	}
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+
+	return true
+}
+
+// dedupCore logs the first of identical entries within the window and a
+// summary with the number of dropped repeats once the window has passed.
+type dedupCore struct {
+	zapcore.Core
+	dedup *deduper
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{Core: c.Core.With(fields), dedup: c.dedup}
+}
+
+func (c *dedupCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return checked
+	}
+	logged, summaries := c.dedup.observe(entry, c.Core)
+	for _, summary := range summaries {
+		summary.write(c.dedup.now())
+	}
+	if !logged {
+		drops.deduplicated.Add(1)
+
+		return checked
+	}
+
+	return c.Core.Check(entry, checked)
+}
+
+// Sync writes the summaries of pending repeats before syncing.
+func (c *dedupCore) Sync() error {
+	now := c.dedup.now()
+	for _, summary := range c.dedup.pending() {
+		summary.write(now)
+	}
+
+	return c.Core.Sync()
+}
+
+type deduper struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	seen  map[dedupKey]*dedupEntry
+	sweep time.Time // when the oldest window expires
+}
+
+type dedupKey struct {
+	level   zapcore.Level
+	name    string
+	message string
+}
+
+type dedupEntry struct {
+	entry    zapcore.Entry // first entry of the window
+	core     zapcore.Core  // core that logged it, used for the summary
+	start    time.Time
+	repeated int
+}
+
+// observe reports whether entry should be logged and returns the summaries
+// of every window that has passed, whatever its message, so that a repeat
+// is reported once any entry is logged after its window. Idle windows are
+// reported on Sync.
+func (d *deduper) observe(entry zapcore.Entry, core zapcore.Core) (bool, []dedupEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	var summaries []dedupEntry
+	if !d.sweep.IsZero() && !now.Before(d.sweep) {
+		summaries = d.expire(now)
+	}
+
+	key := dedupKey{level: entry.Level, name: entry.LoggerName, message: entry.Message}
+	if seen, ok := d.seen[key]; ok {
+		seen.repeated++
+
+		return false, summaries
+	}
+	d.seen[key] = &dedupEntry{entry: entry, core: core, start: now}
+	if d.sweep.IsZero() {
+		d.sweep = now.Add(d.window)
+	}
+
+	return true, summaries
+}
+
+// expire removes the windows that have passed, returns the summaries of those
+// with repeats and schedules the next sweep. The caller must hold d.mu.
+func (d *deduper) expire(now time.Time) []dedupEntry {
+	var summaries []dedupEntry
+	d.sweep = time.Time{}
+	for k, seen := range d.seen {
+		end := seen.start.Add(d.window)
+		if now.Before(end) {
+			if d.sweep.IsZero() || end.Before(d.sweep) {
+				d.sweep = end
+			}
+
+			continue
+		}
+		if seen.repeated > 0 {
+			summaries = append(summaries, *seen)
+		}
+		delete(d.seen, k)
+	}
+
+	return summaries
+}
+
+// pending returns the summaries of every window with repeats and resets their
+// counts; the windows themselves keep running.
+func (d *deduper) pending() []dedupEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var summaries []dedupEntry
+	for _, seen := range d.seen {
+		if seen.repeated > 0 {
+			summaries = append(summaries, *seen)
+			seen.repeated = 0
+		}
+	}
+
+	return summaries
+}
+
+func (e dedupEntry) write(now time.Time) {
+	entry := e.entry
+	entry.Time = now
+	entry.Stack = ""
+	_ = e.core.Write(entry, []zapcore.Field{zap.Int("repeated", e.repeated)})
+}
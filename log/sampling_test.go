@@ -0,0 +1,162 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+func initSampledLogger(t *testing.T, sampling log.SamplingOptions) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sampling.log")
+	opts := log.NewOptions()
+	opts.Format = "json"
+	opts.OutputPaths = []string{path}
+	opts.Sampling = sampling
+	assert.Empty(t, opts.Validate())
+	log.Init(opts)
+	t.Cleanup(func() { log.Init(log.NewOptions()) })
+
+	return path
+}
+
+func logLines(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(readLog(t, path)), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func Test_SamplingLevels(t *testing.T) {
+	path := initSampledLogger(t, log.SamplingOptions{Initial: 2, Thereafter: 0, Levels: "error=0:0"})
+	before := log.Dropped()
+
+	for i := 0; i < 5; i++ {
+		log.Warn("provider down")
+		log.Error("request failed")
+	}
+
+	entries := logLines(t, path)
+	assert.Len(t, entries, 7)
+	assert.Equal(t, uint64(3), log.Dropped().Sampled-before.Sampled)
+}
+
+func Test_Dedup(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	path := initSampledLogger(t, log.SamplingOptions{DedupWindow: time.Minute, Now: func() time.Time { return now }})
+	before := log.Dropped()
+
+	for i := 0; i < 4; i++ {
+		log.Errorw("HTTP request error", "attempt", i)
+	}
+	log.Info("other")
+	now = now.Add(2 * time.Minute)
+	log.Errorw("HTTP request error", "attempt", 4)
+
+	entries := logLines(t, path)
+	assert.Len(t, entries, 4)
+	assert.Equal(t, float64(0), entries[0]["attempt"])
+	assert.Equal(t, "other", entries[1]["message"])
+	// the summary of the first window precedes the first entry of the next one
+	assert.Equal(t, "HTTP request error", entries[2]["message"])
+	assert.Equal(t, float64(3), entries[2]["repeated"])
+	assert.Equal(t, float64(4), entries[3]["attempt"])
+	assert.Equal(t, uint64(3), log.Dropped().Deduplicated-before.Deduplicated)
+
+	// pending repeats are reported on Flush
+	log.Error("HTTP request error")
+	entries = logLines(t, path)
+	assert.Len(t, entries, 5)
+	assert.Equal(t, float64(1), entries[4]["repeated"])
+}
+
+func Test_DedupFlushOnOtherMessage(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	path := initSampledLogger(t, log.SamplingOptions{DedupWindow: time.Minute, Now: func() time.Time { return now }})
+
+	for i := 0; i < 3; i++ {
+		log.Error("HTTP request error")
+	}
+	now = now.Add(2 * time.Minute)
+	// the expired window is reported by the next entry of any message
+	log.Info("other")
+
+	entries := logLines(t, path)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "HTTP request error", entries[1]["message"])
+	assert.Equal(t, float64(2), entries[1]["repeated"])
+	assert.Equal(t, "other", entries[2]["message"])
+}
+
+func Test_RateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	path := initSampledLogger(t, log.SamplingOptions{RateLimit: 1, RateBurst: 2, Now: func() time.Time { return now }})
+	before := log.Dropped()
+
+	for i := 0; i < 5; i++ {
+		log.WithName("noisy").Infow("tick", "i", i)
+		log.WithName("quiet").Infow("tick", "i", i)
+	}
+	now = now.Add(time.Second)
+	log.WithName("noisy").Info("recovered")
+
+	entries := logLines(t, path)
+	assert.Len(t, entries, 5)
+	assert.Equal(t, "recovered", entries[4]["message"])
+	assert.Equal(t, uint64(6), log.Dropped().RateLimited-before.RateLimited)
+}
+
+func Test_SamplingValidate(t *testing.T) {
+	opts := log.NewOptions()
+	opts.Sampling.Levels = "error=10"
+	assert.Len(t, opts.Validate(), 1)
+	opts.Sampling.Levels = "error=10:1,warn=0:0"
+	opts.Sampling.RateLimit = -1
+	assert.Len(t, opts.Validate(), 1)
+}
+
+func Test_SamplingDefaultsAndDisable(t *testing.T) {
+	path := initSampledLogger(t, log.SamplingOptions{})
+	for i := 0; i < 150; i++ {
+		log.Info("heartbeat")
+	}
+	assert.Len(t, logLines(t, path), 100)
+
+	path = initSampledLogger(t, log.SamplingOptions{Disable: true})
+	for i := 0; i < 150; i++ {
+		log.Info("heartbeat")
+	}
+	assert.Len(t, logLines(t, path), 150)
+}
@@ -0,0 +1,356 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slogHandler is a slog.Handler that writes through a zap logger, so slog
+// records follow the level, encoding and outputs of this package.
+type slogHandler struct {
+	logger *zap.Logger
+	name   string
+}
+
+// NewSlogHandler returns a slog.Handler that writes through logger. Groups are
+// written as nested objects, and request scoped values set on the context (see
+// KeyRequestID) are added to every record. A logger returned by FromSlog yields
+// its original handler; other Logger implementations fall back to the global logger.
+func NewSlogHandler(logger Logger) slog.Handler {
+	switch l := logger.(type) {
+	case *zapLogger:
+		return &slogHandler{logger: l.zapLogger, name: l.name}
+	case *slogLogger:
+		return l.logger.Handler()
+	default:
+		return &slogHandler{logger: std.zapLogger, name: std.name}
+	}
+}
+
+// NewSlog returns a slog.Logger that writes through the global logger.
+func NewSlog() *slog.Logger {
+	return slog.New(NewSlogHandler(std))
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return enabledFor(h.logger.Core(), h.name, slogToZapLevel(level))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	checked := h.logger.Check(slogToZapLevel(record.Level), record.Message)
+	if checked == nil {
+		return nil
+	}
+	if !record.Time.IsZero() {
+		checked.Time = record.Time
+	}
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		checked.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	fields := contextFields(ctx)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendSlogAttr(fields, attr)
+
+		return true
+	})
+	checked.Write(fields...)
+
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]zap.Field, 0, len(attrs))
+	for _, attr := range attrs {
+		fields = appendSlogAttr(fields, attr)
+	}
+
+	return &slogHandler{logger: h.logger.With(fields...), name: h.name}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &slogHandler{logger: h.logger.With(zap.Namespace(name)), name: h.name}
+}
+
+// appendSlogAttr converts attr to zap fields following the slog.Handler rules:
+// empty attributes are dropped and groups without a key are inlined.
+func appendSlogAttr(fields []zap.Field, attr slog.Attr) []zap.Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(attr.Key, attr.Value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(attr.Key, attr.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(attr.Key, attr.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(attr.Key, attr.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(attr.Key, attr.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(attr.Key, attr.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(attr.Key, attr.Value.Time()))
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		if len(group) == 0 {
+			return fields
+		}
+		if attr.Key == "" {
+			for _, member := range group {
+				fields = appendSlogAttr(fields, member)
+			}
+
+			return fields
+		}
+
+		return append(fields, zap.Object(attr.Key, zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			for _, field := range appendSlogAttr(nil, slog.Attr{Value: attr.Value}) {
+				field.AddTo(enc)
+			}
+
+			return nil
+		})))
+	default:
+		if err, ok := attr.Value.Any().(error); ok {
+			return append(fields, zap.NamedError(attr.Key, err))
+		}
+
+		return append(fields, zap.Any(attr.Key, attr.Value.Any()))
+	}
+}
+
+func slogToZapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case level < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case level < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+func zapToSlogLevel(level zapcore.Level) slog.Level {
+	switch {
+	case level < zapcore.InfoLevel:
+		return slog.LevelDebug
+	case level < zapcore.WarnLevel:
+		return slog.LevelInfo
+	case level < zapcore.ErrorLevel:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+var _ Logger = &slogLogger{}
+
+// slogLogger is a Logger that writes through a slog.Logger. slog has no logger
+// names, so WithName segments are written as the "logger" attribute.
+type slogLogger struct {
+	logger *slog.Logger
+	name   string
+}
+
+// FromSlog returns a Logger that writes through logger, for code that receives
+// a *slog.Logger but calls APIs expecting this package's Logger.
+func FromSlog(logger *slog.Logger) Logger {
+	return &slogLogger{logger: logger}
+}
+
+// log builds the record itself so that the source position is the caller of
+// the exported method.
+func (l *slogLogger) log(level zapcore.Level, msg string, args []interface{}) {
+	ctx := context.Background()
+	slogLevel := zapToSlogLevel(level)
+	if !l.logger.Enabled(ctx, slogLevel) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// skip runtime.Callers, log and the exported method
+	runtime.Callers(3, pcs[:])
+	record := slog.NewRecord(time.Now(), slogLevel, msg, pcs[0])
+	if l.name != "" {
+		record.AddAttrs(slog.String("logger", l.name))
+	}
+	record.Add(args...)
+	_ = l.logger.Handler().Handle(ctx, record)
+}
+
+// fieldArgs converts zap fields to slog key/value arguments.
+func fieldArgs(fields []Field) []interface{} {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+	keys := make([]string, 0, len(enc.Fields))
+	for key := range enc.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, slog.Any(key, enc.Fields[key]))
+	}
+
+	return args
+}
+
+func (l *slogLogger) Enabled() bool {
+	return l.logger.Enabled(context.Background(), slog.LevelInfo)
+}
+
+func (l *slogLogger) Debug(msg string, fields ...Field) { l.log(DebugLevel, msg, fieldArgs(fields)) }
+func (l *slogLogger) Debugf(format string, v ...interface{}) {
+	l.log(DebugLevel, fmt.Sprintf(format, v...), nil)
+}
+func (l *slogLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.log(DebugLevel, msg, keysAndValues)
+}
+func (l *slogLogger) Info(msg string, fields ...Field) { l.log(InfoLevel, msg, fieldArgs(fields)) }
+func (l *slogLogger) Infof(format string, v ...interface{}) {
+	l.log(InfoLevel, fmt.Sprintf(format, v...), nil)
+}
+func (l *slogLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.log(InfoLevel, msg, keysAndValues)
+}
+func (l *slogLogger) Warn(msg string, fields ...Field) { l.log(WarnLevel, msg, fieldArgs(fields)) }
+func (l *slogLogger) Warnf(format string, v ...interface{}) {
+	l.log(WarnLevel, fmt.Sprintf(format, v...), nil)
+}
+func (l *slogLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.log(WarnLevel, msg, keysAndValues)
+}
+func (l *slogLogger) Error(msg string, fields ...Field) { l.log(ErrorLevel, msg, fieldArgs(fields)) }
+func (l *slogLogger) Errorf(format string, v ...interface{}) {
+	l.log(ErrorLevel, fmt.Sprintf(format, v...), nil)
+}
+func (l *slogLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.log(ErrorLevel, msg, keysAndValues)
+}
+
+// Panic logs at error level, slog has no panic level, and then panics.
+func (l *slogLogger) Panic(msg string, fields ...Field) {
+	l.log(ErrorLevel, msg, fieldArgs(fields))
+	panic(msg)
+}
+
+func (l *slogLogger) Panicf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	l.log(ErrorLevel, msg, nil)
+	panic(msg)
+}
+
+func (l *slogLogger) Panicw(msg string, keysAndValues ...interface{}) {
+	l.log(ErrorLevel, msg, keysAndValues)
+	panic(msg)
+}
+
+// Fatal logs at error level, slog has no fatal level, and then calls os.Exit(1).
+func (l *slogLogger) Fatal(msg string, fields ...Field) {
+	l.log(ErrorLevel, msg, fieldArgs(fields))
+	os.Exit(1)
+}
+
+func (l *slogLogger) Fatalf(format string, v ...interface{}) {
+	l.log(ErrorLevel, fmt.Sprintf(format, v...), nil)
+	os.Exit(1)
+}
+
+func (l *slogLogger) Fatalw(msg string, keysAndValues ...interface{}) {
+	l.log(ErrorLevel, msg, keysAndValues)
+	os.Exit(1)
+}
+
+func (l *slogLogger) V(level Level) InfoLogger {
+	if l.logger.Enabled(context.Background(), zapToSlogLevel(level)) {
+		return &slogInfoLogger{logger: l, level: level}
+	}
+
+	return disabledInfoLogger
+}
+
+func (l *slogLogger) Write(p []byte) (n int, err error) {
+	l.log(InfoLevel, string(p), nil)
+
+	return len(p), nil
+}
+
+func (l *slogLogger) WithValues(keysAndValues ...interface{}) Logger {
+	return &slogLogger{logger: l.logger.With(keysAndValues...), name: l.name}
+}
+
+func (l *slogLogger) WithName(name string) Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+
+	return &slogLogger{logger: l.logger, name: name}
+}
+
+func (l *slogLogger) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, logContextKey, l)
+}
+
+func (l *slogLogger) Flush() {}
+
+// slogInfoLogger is the InfoLogger returned by slogLogger.V.
+type slogInfoLogger struct {
+	logger *slogLogger
+	level  zapcore.Level
+}
+
+func (l *slogInfoLogger) Enabled() bool { return true }
+func (l *slogInfoLogger) Info(msg string, fields ...Field) {
+	l.logger.log(l.level, msg, fieldArgs(fields))
+}
+func (l *slogInfoLogger) Infof(format string, v ...interface{}) {
+	l.logger.log(l.level, fmt.Sprintf(format, v...), nil)
+}
+func (l *slogInfoLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.logger.log(l.level, msg, keysAndValues)
+}
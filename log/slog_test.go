@@ -0,0 +1,102 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+func Test_SlogDefault(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	path := filepath.Join(t.TempDir(), "slog.log")
+	opts := log.NewOptions()
+	opts.Format = "json"
+	opts.OutputPaths = []string{path}
+	opts.SlogDefault = true
+	log.Init(opts)
+	t.Cleanup(func() { log.Init(log.NewOptions()) })
+
+	//nolint:staticcheck // the log package stores request scoped values under string keys
+	ctx := context.WithValue(context.Background(), log.KeyRequestID, "req-1")
+	slog.Debug("hidden")
+	slog.With("service", "api").WithGroup("http").InfoContext(ctx, "handled",
+		"status", 200, slog.Group("client", "ip", "203.0.113.7"), "err", errors.New("boom"))
+
+	lines := strings.Split(strings.TrimSpace(readLog(t, path)), "\n")
+	assert.Len(t, lines, 1)
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "handled", entry["message"])
+	assert.Equal(t, "api", entry["service"])
+	assert.Contains(t, entry["caller"], "log/slog_test.go")
+
+	group := entry["http"].(map[string]interface{})
+	assert.Equal(t, "req-1", group[log.KeyRequestID])
+	assert.Equal(t, float64(200), group["status"])
+	assert.Equal(t, "boom", group["err"])
+	assert.Equal(t, map[string]interface{}{"ip": "203.0.113.7"}, group["client"])
+}
+
+func Test_SlogHandlerFollowsModuleLevels(t *testing.T) {
+	initFileLogger(t, "storage.*=debug")
+
+	storage := log.NewSlogHandler(log.WithName("storage").WithName("s3"))
+	runtime := log.NewSlogHandler(log.WithName("runtime"))
+	assert.True(t, storage.Enabled(context.Background(), slog.LevelDebug))
+	assert.False(t, runtime.Enabled(context.Background(), slog.LevelDebug))
+}
+
+func Test_FromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true})
+	logger := log.FromSlog(slog.New(handler)).WithName("worker").WithValues("job", "sync")
+
+	logger.Infow("started", "attempt", 1)
+	logger.Info("typed", log.Int32("count", 3))
+	logger.Debug("hidden")
+	assert.False(t, logger.V(log.DebugLevel).Enabled())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "started", entry["msg"])
+	assert.Equal(t, "worker", entry["logger"])
+	assert.Equal(t, "sync", entry["job"])
+	assert.Equal(t, float64(1), entry["attempt"])
+	assert.Contains(t, entry["source"].(map[string]interface{})["file"], "log/slog_test.go")
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, float64(3), entry["count"])
+
+	// a logger created by FromSlog converts back to its own handler
+	assert.Equal(t, handler, log.NewSlogHandler(log.FromSlog(slog.New(handler))))
+}
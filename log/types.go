@@ -28,6 +28,10 @@ const (
 	KeyRequestID   string = "requestID"
 	KeyUsername    string = "username"
 	KeyWatcherName string = "watcher"
+	KeyTenantID    string = "tenantID"
+	KeyOfferingKey string = "offeringKey"
+	KeyTraceID     string = "traceID"
+	KeySpanID      string = "spanID"
 )
 
 // Field is an alias for the field structure in the underlying log frame.
@@ -0,0 +1,382 @@
+package rehost
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/service/v2/core"
+	imageedit "github.com/QingsiLiu/baseComponents/service/v2/image/edit"
+	imagegenerate "github.com/QingsiLiu/baseComponents/service/v2/image/generate"
+	videogenerate "github.com/QingsiLiu/baseComponents/service/v2/video/generate"
+	"github.com/QingsiLiu/baseComponents/storage"
+)
+
+const (
+	defaultConcurrency = 4
+	defaultMaxBytes    = 512 << 20
+	sniffLen           = 512
+)
+
+var (
+	ErrNotCompleted = errors.New("operation is not completed")
+	ErrTooLarge     = errors.New("object exceeds size limit")
+)
+
+// Config controls where and how provider results are copied.
+type Config struct {
+	Bucket string
+	// KeyPrefix is prepended to every generated object key.
+	KeyPrefix string
+	// Concurrency bounds the number of parallel downloads. Defaults to 4.
+	Concurrency int
+	// MaxBytes rejects results larger than this. Defaults to 512 MiB.
+	MaxBytes int64
+	// Presign returns PreSignGetObject URLs instead of GenerateDownloadURL.
+	Presign    bool
+	HTTPClient *http.Client
+}
+
+// Asset describes one result URL copied into our bucket.
+type Asset struct {
+	SourceURL   string `json:"source_url"`
+	Key         string `json:"key"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+// Rehoster downloads temporary provider URLs and stores them under
+// deterministic keys so repeated calls for the same operation overwrite
+// instead of duplicating.
+type Rehoster struct {
+	store  storage.StorageService
+	cfg    Config
+	client *http.Client
+}
+
+func New(store storage.StorageService, cfg Config) (*Rehoster, error) {
+	if store == nil {
+		return nil, errors.New("rehost: storage is required")
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("rehost: bucket is required")
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultConcurrency
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultMaxBytes
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Minute}
+	}
+	return &Rehoster{store: store, cfg: cfg, client: client}, nil
+}
+
+// URLs copies every URL into the bucket under KeyPrefix/namespace/<index><ext>.
+// Assets are returned in input order. Empty URLs are skipped and yield a zero Asset.
+func (r *Rehoster) URLs(ctx context.Context, namespace string, urls []string) ([]Asset, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	assets := make([]Asset, len(urls))
+	errs := make([]error, len(urls))
+	sem := make(chan struct{}, r.cfg.Concurrency)
+	var wg sync.WaitGroup
+
+	for i, sourceURL := range urls {
+		if sourceURL == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, sourceURL string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+
+			asset, err := r.copy(ctx, r.objectKey(namespace, i), sourceURL)
+			if err != nil {
+				errs[i] = fmt.Errorf("rehost %s: %w", sourceURL, err)
+				cancel()
+				return
+			}
+			assets[i] = *asset
+		}(i, sourceURL)
+	}
+	wg.Wait()
+
+	var firstErr error
+	for _, err := range errs {
+		// Prefer the failure that triggered cancellation over the cancellations it caused.
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return assets, nil
+}
+
+// Operation rehosts the URLs selected by refs and rewrites them in place once
+// every copy has succeeded. The namespace is derived from the offering key and
+// external ID.
+func Operation[T any](ctx context.Context, r *Rehoster, op *core.Operation[T], refs func(*T) []*string) ([]Asset, error) {
+	if op == nil {
+		return nil, errors.New("rehost: operation is nil")
+	}
+	if op.Status != core.OperationStatusCompleted {
+		return nil, ErrNotCompleted
+	}
+
+	targets := refs(&op.Result)
+	urls := make([]string, len(targets))
+	for i, target := range targets {
+		urls[i] = *target
+	}
+
+	assets, err := r.URLs(ctx, operationNamespace(op.OfferingKey, op.ExternalID), urls)
+	if err != nil {
+		return nil, err
+	}
+	for i, target := range targets {
+		if assets[i].URL != "" {
+			*target = assets[i].URL
+		}
+	}
+	return assets, nil
+}
+
+func ImageGenerate(ctx context.Context, r *Rehoster, op *core.Operation[imagegenerate.Result]) ([]Asset, error) {
+	return Operation(ctx, r, op, func(result *imagegenerate.Result) []*string {
+		refs := make([]*string, 0, len(result.Images))
+		for i := range result.Images {
+			refs = append(refs, &result.Images[i].URL)
+		}
+		return refs
+	})
+}
+
+func ImageEdit(ctx context.Context, r *Rehoster, op *core.Operation[imageedit.Result]) ([]Asset, error) {
+	return Operation(ctx, r, op, func(result *imageedit.Result) []*string {
+		refs := make([]*string, 0, len(result.Images))
+		for i := range result.Images {
+			refs = append(refs, &result.Images[i].URL)
+		}
+		return refs
+	})
+}
+
+func VideoGenerate(ctx context.Context, r *Rehoster, op *core.Operation[videogenerate.Result]) ([]Asset, error) {
+	return Operation(ctx, r, op, func(result *videogenerate.Result) []*string {
+		refs := make([]*string, 0, len(result.Videos)+len(result.Images))
+		for i := range result.Videos {
+			refs = append(refs, &result.Videos[i].URL)
+		}
+		for i := range result.Images {
+			refs = append(refs, &result.Images[i].URL)
+		}
+		return refs
+	})
+}
+
+// TaskResult rehosts a v1 TaskInfo.Result slice and rewrites it in place.
+func TaskResult(ctx context.Context, r *Rehoster, source, taskID string, result []string) ([]Asset, error) {
+	assets, err := r.URLs(ctx, path.Join(sanitize(source), sanitize(taskID)), result)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result {
+		if assets[i].URL != "" {
+			result[i] = assets[i].URL
+		}
+	}
+	return assets, nil
+}
+
+func (r *Rehoster) copy(ctx context.Context, keyBase, sourceURL string) (*Asset, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > r.cfg.MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	body := bufio.NewReaderSize(&limitedReader{r: resp.Body, remaining: r.cfg.MaxBytes}, sniffLen)
+	head, err := body.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	contentType := detectContentType(head, resp.Header.Get("Content-Type"), sourceURL)
+	key := keyBase + extension(contentType, sourceURL)
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	if err := r.upload(key, contentType, io.TeeReader(body, io.MultiWriter(hash, counter))); err != nil {
+		return nil, err
+	}
+
+	objectURL, err := r.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Asset{
+		SourceURL:   sourceURL,
+		Key:         key,
+		URL:         objectURL,
+		ContentType: contentType,
+		Size:        counter.n,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// upload stores the detected content type when the backend supports upload options.
+func (r *Rehoster) upload(key, contentType string, body io.Reader) error {
+	if uploader, ok := r.store.(storage.OptionsUploader); ok {
+		return uploader.UploadObjectStreamWithOptions(r.cfg.Bucket, key, body, storage.UploadObjectOptions{ContentType: contentType})
+	}
+	return r.store.UploadObjectStream(r.cfg.Bucket, key, body)
+}
+
+func (r *Rehoster) objectKey(namespace string, index int) string {
+	return path.Join(r.cfg.KeyPrefix, namespace, fmt.Sprintf("%d", index))
+}
+
+func (r *Rehoster) objectURL(key string) (string, error) {
+	if r.cfg.Presign {
+		return r.store.PreSignGetObject(r.cfg.Bucket, key)
+	}
+	objectURL := r.store.GenerateDownloadURL(r.cfg.Bucket, key)
+	if objectURL == "" {
+		return "", errors.New("empty download url")
+	}
+	return objectURL, nil
+}
+
+func operationNamespace(offeringKey, externalID string) string {
+	parts := strings.Split(offeringKey, ":")
+	for i := range parts {
+		parts[i] = sanitize(parts[i])
+	}
+	return path.Join(append(parts, sanitize(externalID))...)
+}
+
+func sanitize(segment string) string {
+	segment = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, segment)
+	if segment == "" || segment == "." || segment == ".." {
+		return "_"
+	}
+	return segment
+}
+
+func detectContentType(head []byte, header, sourceURL string) string {
+	if sniffed := storage.DefaultContentTypes.Sniff(head); sniffed != "" {
+		return sniffed
+	}
+	if mediaType, _, err := mime.ParseMediaType(header); err == nil && mediaType != "" && mediaType != storage.DefaultContentType {
+		return mediaType
+	}
+	var name string
+	if parsed, err := url.Parse(sourceURL); err == nil {
+		name = parsed.Path
+	}
+	return storage.DetectContentType(name, head)
+}
+
+var extensionsByType = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/svg+xml":   ".svg",
+	"image/avif":      ".avif",
+	"image/heic":      ".heic",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"video/quicktime": ".mov",
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".m4a",
+	"audio/wav":       ".wav",
+	"audio/wave":      ".wav",
+	"audio/flac":      ".flac",
+	"application/pdf": ".pdf",
+}
+
+func extension(contentType, sourceURL string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if ext, ok := extensionsByType[mediaType]; ok {
+		return ext
+	}
+	if parsed, err := url.Parse(sourceURL); err == nil {
+		if ext := path.Ext(parsed.Path); ext != "" && len(ext) <= 6 {
+			return strings.ToLower(ext)
+		}
+	}
+	return ""
+}
+
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,144 @@
+package rehost
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QingsiLiu/baseComponents/service/v2/core"
+	imagegenerate "github.com/QingsiLiu/baseComponents/service/v2/image/generate"
+	videogenerate "github.com/QingsiLiu/baseComponents/service/v2/video/generate"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newProviderServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/output.png":
+			_, _ = w.Write(pngHeader)
+		case "/video":
+			w.Header().Set("Content-Type", "video/mp4")
+			_, _ = w.Write(append([]byte("\x00\x00\x00\x18ftypmp42"), bytes.Repeat([]byte{0}, 64)...))
+		case "/huge":
+			_, _ = w.Write(bytes.Repeat([]byte("a"), 4096))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestImageGenerateRewritesURLs(t *testing.T) {
+	server := newProviderServer(t)
+	store := memory.NewMemoryService("https://cdn.example.com")
+	rehoster, err := New(store, Config{Bucket: "media", KeyPrefix: "results"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	op := &core.Operation[imagegenerate.Result]{
+		OfferingKey: "image.generate:gpt-image-2:kie",
+		ExternalID:  "task/1",
+		Status:      core.OperationStatusCompleted,
+		Result: imagegenerate.Result{Images: []imagegenerate.Image{
+			{URL: server.URL + "/output.png"},
+			{B64JSON: "inline"},
+		}},
+	}
+
+	assets, err := ImageGenerate(context.Background(), rehoster, op)
+	if err != nil {
+		t.Fatalf("ImageGenerate() error = %v", err)
+	}
+
+	wantKey := "results/image.generate/gpt-image-2/kie/task_1/0.png"
+	if assets[0].Key != wantKey {
+		t.Fatalf("key = %q, want %q", assets[0].Key, wantKey)
+	}
+	if op.Result.Images[0].URL != "https://cdn.example.com/media/"+wantKey {
+		t.Fatalf("url = %q, want rewritten cdn url", op.Result.Images[0].URL)
+	}
+	if op.Result.Images[1].URL != "" {
+		t.Fatalf("empty url should stay empty, got %q", op.Result.Images[1].URL)
+	}
+	sum := sha256.Sum256(pngHeader)
+	if assets[0].SHA256 != hex.EncodeToString(sum[:]) || assets[0].Size != int64(len(pngHeader)) {
+		t.Fatalf("asset = %+v, want checksum and size of source", assets[0])
+	}
+	if assets[0].ContentType != "image/png" {
+		t.Fatalf("content type = %q, want image/png", assets[0].ContentType)
+	}
+	if data, err := store.GetObject("media", wantKey); err != nil || !bytes.Equal(data, pngHeader) {
+		t.Fatalf("stored object = %q, %v", data, err)
+	}
+}
+
+func TestVideoGenerateUsesDetectedExtension(t *testing.T) {
+	server := newProviderServer(t)
+	store := memory.NewMemoryService("")
+	rehoster, _ := New(store, Config{Bucket: "media"})
+
+	op := &core.Operation[videogenerate.Result]{
+		OfferingKey: "video.generate:seedance-2:kie",
+		ExternalID:  "v1",
+		Status:      core.OperationStatusCompleted,
+		Result:      videogenerate.Result{Videos: []videogenerate.Video{{URL: server.URL + "/video"}}},
+	}
+	assets, err := VideoGenerate(context.Background(), rehoster, op)
+	if err != nil {
+		t.Fatalf("VideoGenerate() error = %v", err)
+	}
+	if assets[0].Key != "video.generate/seedance-2/kie/v1/0.mp4" || assets[0].ContentType != "video/mp4" {
+		t.Fatalf("asset = %+v, want mp4 key and content type", assets[0])
+	}
+	if meta, err := store.GetObjectMetadata("media", assets[0].Key); err != nil || meta.ContentType != "video/mp4" {
+		t.Fatalf("stored metadata = %+v, %v, want video/mp4", meta, err)
+	}
+}
+
+func TestRehostFailuresLeaveOperationUntouched(t *testing.T) {
+	server := newProviderServer(t)
+	rehoster, _ := New(memory.NewMemoryService(""), Config{Bucket: "media", MaxBytes: 1024, Concurrency: 1})
+
+	op := &core.Operation[imagegenerate.Result]{
+		ExternalID: "t",
+		Status:     core.OperationStatusCompleted,
+		Result: imagegenerate.Result{Images: []imagegenerate.Image{
+			{URL: server.URL + "/output.png"},
+			{URL: server.URL + "/huge"},
+		}},
+	}
+	if _, err := ImageGenerate(context.Background(), rehoster, op); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("error = %v, want ErrTooLarge", err)
+	}
+	if op.Result.Images[0].URL != server.URL+"/output.png" {
+		t.Fatalf("url rewritten on failure: %q", op.Result.Images[0].URL)
+	}
+
+	op.Status = core.OperationStatusRunning
+	if _, err := ImageGenerate(context.Background(), rehoster, op); !errors.Is(err, ErrNotCompleted) {
+		t.Fatalf("error = %v, want ErrNotCompleted", err)
+	}
+}
+
+func TestTaskResultRewritesV1Slice(t *testing.T) {
+	server := newProviderServer(t)
+	rehoster, _ := New(memory.NewMemoryService("https://cdn.example.com"), Config{Bucket: "media", Presign: true})
+
+	result := []string{server.URL + "/output.png"}
+	if _, err := TaskResult(context.Background(), rehoster, "replicate_flux_schnell", "abc", result); err != nil {
+		t.Fatalf("TaskResult() error = %v", err)
+	}
+	if result[0] != "https://cdn.example.com/media/replicate_flux_schnell/abc/0.png?method=GET" {
+		t.Fatalf("result = %q, want presigned url", result[0])
+	}
+}
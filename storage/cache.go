@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheOptions 读缓存选项
+type CacheOptions struct {
+	MaxEntries int              // 最大缓存条目数，默认 1024
+	MaxBytes   int64            // 对象内容缓存的总字节数上限，默认 64MB，超过上限的单个对象不缓存
+	TTL        time.Duration    // 缓存有效期，0 表示不过期（仅通过本中间件的写操作失效）
+	Now        func() time.Time // 当前时间，默认 time.Now
+}
+
+// Cache 为 GetObject 和 GetObjectMetadata 提供 LRU 读缓存。
+// 经过本中间件的写操作会使对应缓存失效，绕过中间件直接写入后端的修改只能等待 TTL 过期。
+func Cache(opts CacheOptions) Middleware {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1024
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 64 << 20
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	c := &readCache{opts: opts, ll: list.New(), items: make(map[cacheKey]*list.Element)}
+	return Intercept(c.intercept)
+}
+
+type cacheKey struct {
+	op, bucket, key string
+}
+
+type cacheEntry struct {
+	key      cacheKey
+	data     []byte
+	metadata *ObjectMetadata
+	expires  time.Time
+}
+
+type readCache struct {
+	opts  CacheOptions
+	mu    sync.Mutex
+	ll    *list.List
+	items map[cacheKey]*list.Element
+	bytes int64
+	gen   uint64 // 每次失效递增，避免读取期间发生的写入被旧结果覆盖
+}
+
+func (c *readCache) intercept(call *Call, next func() error) error {
+	switch call.Op {
+	case OpGetObject, OpGetObjectMetadata:
+		return c.read(call, next)
+	case OpDeleteObjects:
+		err := next()
+		c.invalidate(call.Bucket, call.Keys...)
+		return err
+	case OpMoveObject:
+		err := next()
+		c.invalidate(call.SourceBucket, call.SourceKey)
+		c.invalidate(call.Bucket, call.Key)
+		return err
+	case OpDeleteFolder:
+		err := next()
+		c.invalidatePrefix(call.Bucket, call.Key)
+		return err
+	case OpUploadObject, OpUploadObjectStream, OpUploadObjectWithOptions, OpUploadObjectStreamWithOptions,
+		OpDeleteObject, OpCopyObject, OpSetObjectMetadata, OpRestoreObject:
+		err := next()
+		c.invalidate(call.Bucket, call.Key)
+		return err
+	default:
+		return next()
+	}
+}
+
+// read 命中时直接写入结果，未命中时执行下游操作并缓存成功的结果
+func (c *readCache) read(call *Call, next func() error) error {
+	key := cacheKey{op: call.Op, bucket: call.Bucket, key: call.Key}
+
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if entry.expires.IsZero() || c.opts.Now().Before(entry.expires) {
+			c.ll.MoveToFront(elem)
+			setCacheResult(call, entry)
+			c.mu.Unlock()
+			return nil
+		}
+		c.removeElement(elem)
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	if err := next(); err != nil {
+		return err
+	}
+
+	entry := &cacheEntry{key: key}
+	switch result := call.Result.(type) {
+	case *[]byte:
+		if int64(len(*result)) > c.opts.MaxBytes {
+			return nil
+		}
+		entry.data = append([]byte(nil), *result...)
+	case **ObjectMetadata:
+		if *result == nil {
+			return nil
+		}
+		entry.metadata = copyObjectMetadata(*result)
+	}
+	if c.opts.TTL > 0 {
+		entry.expires = c.opts.Now().Add(c.opts.TTL)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		return nil
+	}
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+	c.items[key] = c.ll.PushFront(entry)
+	c.bytes += int64(len(entry.data))
+	for c.ll.Len() > c.opts.MaxEntries || c.bytes > c.opts.MaxBytes {
+		c.removeElement(c.ll.Back())
+	}
+	return nil
+}
+
+func (c *readCache) invalidate(bucketName string, fileKeys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, fileKey := range fileKeys {
+		for _, op := range []string{OpGetObject, OpGetObjectMetadata} {
+			if elem, ok := c.items[cacheKey{op: op, bucket: bucketName, key: fileKey}]; ok {
+				c.removeElement(elem)
+			}
+		}
+	}
+}
+
+func (c *readCache) invalidatePrefix(bucketName, prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for key, elem := range c.items {
+		if key.bucket == bucketName && strings.HasPrefix(key.key, prefix) {
+			c.removeElement(elem)
+		}
+	}
+}
+
+func (c *readCache) removeElement(elem *list.Element) {
+	entry := c.ll.Remove(elem).(*cacheEntry)
+	delete(c.items, entry.key)
+	c.bytes -= int64(len(entry.data))
+}
+
+// setCacheResult 写入缓存结果的副本，调用方修改返回值不会影响缓存
+func setCacheResult(call *Call, entry *cacheEntry) {
+	switch result := call.Result.(type) {
+	case *[]byte:
+		*result = append([]byte(nil), entry.data...)
+	case **ObjectMetadata:
+		*result = copyObjectMetadata(entry.metadata)
+	}
+}
+
+func copyObjectMetadata(metadata *ObjectMetadata) *ObjectMetadata {
+	copied := *metadata
+	if metadata.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(metadata.Metadata))
+		for k, v := range metadata.Metadata {
+			copied.Metadata[k] = v
+		}
+	}
+	return &copied
+}
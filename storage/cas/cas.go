@@ -0,0 +1,476 @@
+// Package cas 内容寻址去重存储：对象内容按哈希保存为 blob，逻辑名称通过引用指向 blob，
+// 重复上传时直接复用已有 blob，不再被任何名称引用的 blob 由 GC 回收。
+//
+// 存储布局（均位于 Options.Prefix 之下）：
+//
+//	blobs/<hash[:2]>/<hash>     内容
+//	names/<name>                名称引用（JSON），记录 hash、大小、内容类型和用户元数据
+//	refs/<hash>/<escaped name>  反向引用（空对象），其数量即 blob 的引用计数
+//
+// 引用计数由独立的反向引用对象表示，增减引用只需写入或删除一个对象，
+// 多个进程共用同一存储时不存在读-改-写竞争。
+package cas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/utils"
+	"github.com/QingsiLiu/baseComponents/utils/murmur3"
+)
+
+// HashAlgorithm 内容哈希算法
+type HashAlgorithm string
+
+const (
+	// SHA256 SHA-256，十六进制 64 位
+	SHA256 HashAlgorithm = "sha256"
+	// Murmur3 murmur3 128 位，十六进制 32 位。计算更快但不抗碰撞，复用 blob 前会校验大小，
+	// 对内容来源不可信的场景建议同时开启 Options.Verify
+	Murmur3 HashAlgorithm = "murmur3"
+)
+
+const (
+	defaultPrefix      = "cas/"
+	defaultGracePeriod = time.Hour
+
+	blobsDir = "blobs/"
+	namesDir = "names/"
+	refsDir  = "refs/"
+)
+
+var (
+	// ErrInvalidHash 哈希值格式与算法不匹配
+	ErrInvalidHash = errors.New("cas: invalid hash")
+	// ErrHashCollision 哈希相同但内容不同
+	ErrHashCollision = errors.New("cas: hash collision")
+)
+
+// Options 去重存储选项
+type Options struct {
+	Bucket string        // 存储桶
+	Prefix string        // 全部对象的键前缀，默认 "cas/"
+	Hash   HashAlgorithm // 哈希算法，默认 SHA256
+	// Verify 复用已有 blob 前逐字节比对内容，需要额外读取一次 blob
+	Verify bool
+	// GracePeriod 最后修改时间在该时长内的 blob 和反向引用不会被 GC 回收，
+	// 避免与正在进行的上传竞争，默认 1 小时
+	GracePeriod time.Duration
+	Now         func() time.Time // 当前时间，默认 time.Now
+}
+
+// Object 逻辑名称对应的对象
+type Object struct {
+	Name        string            `json:"name"`
+	Hash        string            `json:"hash"`
+	Size        int64             `json:"size"`
+	ContentType string            `json:"contentType"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Created     time.Time         `json:"created"`
+	// Deduplicated 本次写入复用了已有 blob，未上传内容
+	Deduplicated bool `json:"-"`
+}
+
+// GCReport 单次回收结果
+type GCReport struct {
+	Scanned      int   `json:"scanned"`      // 扫描的 blob 数量
+	Deleted      int   `json:"deleted"`      // 删除的 blob 数量
+	Bytes        int64 `json:"bytes"`        // 释放的字节数
+	StaleRefs    int   `json:"staleRefs"`    // 清理的失效反向引用数量
+	Referenced   int   `json:"referenced"`   // 仍被引用的 blob 数量
+	Unreferenced int   `json:"unreferenced"` // 无引用但仍在宽限期内的 blob 数量
+}
+
+// Store 内容寻址去重存储
+type Store struct {
+	svc  storage.StorageService
+	opts Options
+}
+
+// New 创建去重存储
+func New(svc storage.StorageService, opts Options) (*Store, error) {
+	if svc == nil {
+		return nil, errors.New("cas: requires a storage service")
+	}
+	if opts.Bucket == "" {
+		return nil, errors.New("cas: requires a bucket")
+	}
+	switch opts.Hash {
+	case "":
+		opts.Hash = SHA256
+	case SHA256, Murmur3:
+	default:
+		return nil, fmt.Errorf("cas: unsupported hash algorithm %q", opts.Hash)
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = defaultPrefix
+	}
+	if !strings.HasSuffix(opts.Prefix, "/") {
+		opts.Prefix += "/"
+	}
+	if opts.GracePeriod <= 0 {
+		opts.GracePeriod = defaultGracePeriod
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Store{svc: svc, opts: opts}, nil
+}
+
+// Sum 按配置的算法计算内容哈希，客户端可先计算哈希再调用 Exists 判断是否需要上传
+func (s *Store) Sum(data []byte) string {
+	if s.opts.Hash == Murmur3 {
+		h1, h2 := murmur3.Sum128(data)
+		return fmt.Sprintf("%016x%016x", h1, h2)
+	}
+	return utils.SHA256(data)
+}
+
+// Exists 判断内容为 hash 的 blob 是否已存在，存在时可直接调用 Link 而无需上传
+func (s *Store) Exists(hash string) (bool, error) {
+	if err := s.validHash(hash); err != nil {
+		return false, err
+	}
+	return storage.ObjectExists(context.Background(), s.svc, s.opts.Bucket, s.BlobKey(hash))
+}
+
+// BlobKey 返回 blob 在存储桶中的对象键，可用于生成预签名下载地址
+func (s *Store) BlobKey(hash string) string {
+	return s.opts.Prefix + blobsDir + hash[:2] + "/" + hash
+}
+
+// Put 将内容保存为 name。内容已存在时复用已有 blob，返回的 Object.Deduplicated 为 true。
+// options 中的 ContentType 和 Metadata 记录在名称上；ACL 和 CacheControl 只在首次上传 blob 时生效
+func (s *Store) Put(name string, data []byte, options storage.UploadObjectOptions) (*Object, error) {
+	if name == "" {
+		return nil, errors.New("cas: name is empty")
+	}
+	contentType := options.ContentType
+	if contentType == "" {
+		contentType = storage.DetectContentType(name, data)
+	}
+	if len(options.AllowedContentTypes) > 0 && !storage.ContentTypeAllowed(contentType, options.AllowedContentTypes) {
+		return nil, fmt.Errorf("%w: %s", storage.ErrContentTypeNotAllowed, contentType)
+	}
+
+	hash := s.Sum(data)
+	// 先写反向引用再确认 blob，GC 看到引用后不会回收该 blob
+	created, err := s.addRef(hash, name)
+	if err != nil {
+		return nil, err
+	}
+	deduplicated, err := s.ensureBlob(hash, data, contentType, options)
+	if err != nil {
+		s.rollbackRef(hash, name, created)
+		return nil, err
+	}
+
+	object := &Object{
+		Name:         name,
+		Hash:         hash,
+		Size:         int64(len(data)),
+		ContentType:  contentType,
+		Metadata:     options.Metadata,
+		Deduplicated: deduplicated,
+	}
+	if err := s.writeName(object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// Link 将 name 指向已存在的 blob，不上传内容。blob 不存在时返回 storage.ErrNotFound
+func (s *Store) Link(name, hash string, metadata map[string]string) (*Object, error) {
+	if name == "" {
+		return nil, errors.New("cas: name is empty")
+	}
+	if err := s.validHash(hash); err != nil {
+		return nil, err
+	}
+	created, err := s.addRef(hash, name)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := s.svc.GetObjectMetadata(s.opts.Bucket, s.BlobKey(hash))
+	if err != nil {
+		s.rollbackRef(hash, name, created)
+		return nil, err
+	}
+
+	object := &Object{
+		Name:         name,
+		Hash:         hash,
+		Size:         blob.ContentLength,
+		ContentType:  blob.ContentType,
+		Metadata:     metadata,
+		Deduplicated: true,
+	}
+	if err := s.writeName(object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// Stat 返回 name 对应的对象信息，不存在时返回 storage.ErrNotFound
+func (s *Store) Stat(name string) (*Object, error) {
+	data, err := s.svc.GetObject(s.opts.Bucket, s.nameKey(name))
+	if err != nil {
+		return nil, err
+	}
+	var object Object
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("cas: corrupt name %s: %w", name, err)
+	}
+	return &object, nil
+}
+
+// Get 读取 name 对应的内容
+func (s *Store) Get(name string) ([]byte, *Object, error) {
+	object, err := s.Stat(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := s.svc.GetObject(s.opts.Bucket, s.BlobKey(object.Hash))
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, object, nil
+}
+
+// Delete 删除名称及其引用，blob 由 GC 在引用归零后回收。名称不存在时返回 storage.ErrNotFound
+func (s *Store) Delete(name string) error {
+	object, err := s.Stat(name)
+	if err != nil {
+		return err
+	}
+	// 先删除名称再删除反向引用：中途失败只会留下多余的引用，由 GC 清理，而不会留下指向已回收 blob 的名称
+	if err := s.svc.DeleteObject(s.opts.Bucket, s.nameKey(name)); err != nil {
+		return err
+	}
+	return s.svc.DeleteObject(s.opts.Bucket, s.refKey(object.Hash, name))
+}
+
+// RefCount 返回引用 hash 的名称数量
+func (s *Store) RefCount(hash string) (int, error) {
+	if err := s.validHash(hash); err != nil {
+		return 0, err
+	}
+	count := 0
+	err := storage.Walk(context.Background(), s.svc, &storage.ListObjectsInput{
+		Bucket: s.opts.Bucket,
+		Prefix: s.refPrefix(hash),
+	}, func(storage.ObjectInfo) error {
+		count++
+		return nil
+	}, storage.WithRecursive(true))
+	return count, err
+}
+
+// GC 回收无引用的 blob。先清理失效的反向引用（名称已删除或已指向其他 blob），
+// 再删除超过宽限期且无引用的 blob，删除前再次确认引用计数
+func (s *Store) GC(ctx context.Context) (*GCReport, error) {
+	report := &GCReport{}
+	now := s.opts.Now()
+
+	referenced, err := s.collectRefs(ctx, now, report)
+	if err != nil {
+		return report, err
+	}
+
+	err = storage.Walk(ctx, s.svc, &storage.ListObjectsInput{
+		Bucket: s.opts.Bucket,
+		Prefix: s.opts.Prefix + blobsDir,
+	}, func(object storage.ObjectInfo) error {
+		if object.IsDir {
+			return nil
+		}
+		report.Scanned++
+		hash := object.Key[strings.LastIndex(object.Key, "/")+1:]
+		if referenced[hash] {
+			report.Referenced++
+			return nil
+		}
+		if now.Sub(object.LastModified) < s.opts.GracePeriod {
+			report.Unreferenced++
+			return nil
+		}
+		// 扫描引用之后可能有新的上传复用了该 blob
+		count, err := s.RefCount(hash)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			report.Referenced++
+			return nil
+		}
+		if err := s.svc.DeleteObject(s.opts.Bucket, object.Key); err != nil {
+			return err
+		}
+		report.Deleted++
+		report.Bytes += object.Size
+		return nil
+	}, storage.WithRecursive(true))
+	return report, err
+}
+
+// collectRefs 返回仍被引用的 hash 集合，同时删除超过宽限期的失效反向引用
+func (s *Store) collectRefs(ctx context.Context, now time.Time, report *GCReport) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	prefix := s.opts.Prefix + refsDir
+	err := storage.Walk(ctx, s.svc, &storage.ListObjectsInput{
+		Bucket: s.opts.Bucket,
+		Prefix: prefix,
+	}, func(object storage.ObjectInfo) error {
+		if object.IsDir {
+			return nil
+		}
+		hash, escaped, ok := strings.Cut(strings.TrimPrefix(object.Key, prefix), "/")
+		if !ok {
+			return nil
+		}
+		// 宽限期内的引用可能属于尚未写入名称的上传
+		if now.Sub(object.LastModified) < s.opts.GracePeriod {
+			referenced[hash] = true
+			return nil
+		}
+		stale, err := s.staleRef(hash, escaped)
+		if err != nil {
+			return err
+		}
+		if !stale {
+			referenced[hash] = true
+			return nil
+		}
+		if err := s.svc.DeleteObject(s.opts.Bucket, object.Key); err != nil {
+			return err
+		}
+		report.StaleRefs++
+		return nil
+	}, storage.WithRecursive(true))
+	return referenced, err
+}
+
+// staleRef 判断反向引用对应的名称是否已删除或已指向其他 blob
+func (s *Store) staleRef(hash, escaped string) (bool, error) {
+	name, err := url.PathUnescape(escaped)
+	if err != nil {
+		return true, nil
+	}
+	object, err := s.Stat(name)
+	if storage.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return object.Hash != hash, nil
+}
+
+// ensureBlob 确保 blob 存在，已存在时返回 true
+func (s *Store) ensureBlob(hash string, data []byte, contentType string, options storage.UploadObjectOptions) (bool, error) {
+	key := s.BlobKey(hash)
+	blob, err := s.svc.GetObjectMetadata(s.opts.Bucket, key)
+	switch {
+	case err == nil:
+		if blob.ContentLength != int64(len(data)) {
+			return false, fmt.Errorf("%w: %s", ErrHashCollision, hash)
+		}
+		if s.opts.Verify {
+			existing, err := s.svc.GetObject(s.opts.Bucket, key)
+			if err != nil {
+				return false, err
+			}
+			if !bytes.Equal(existing, data) {
+				return false, fmt.Errorf("%w: %s", ErrHashCollision, hash)
+			}
+		}
+		return true, nil
+	case !storage.IsNotFound(err):
+		return false, err
+	}
+
+	blobOptions := storage.UploadObjectOptions{
+		ContentType:  contentType,
+		CacheControl: options.CacheControl,
+		ACL:          options.ACL,
+	}
+	if uploader, ok := s.svc.(storage.OptionsUploader); ok {
+		return false, uploader.UploadObjectWithOptions(s.opts.Bucket, key, data, blobOptions)
+	}
+	return false, s.svc.UploadObject(s.opts.Bucket, key, data)
+}
+
+// writeName 写入名称引用，名称原先指向其他 blob 时移除旧的反向引用
+func (s *Store) writeName(object *Object) error {
+	previous, err := s.Stat(object.Name)
+	if err != nil && !storage.IsNotFound(err) {
+		return err
+	}
+
+	object.Created = s.opts.Now()
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	if err := s.svc.UploadObject(s.opts.Bucket, s.nameKey(object.Name), data); err != nil {
+		return err
+	}
+	if previous != nil && previous.Hash != object.Hash {
+		return s.svc.DeleteObject(s.opts.Bucket, s.refKey(previous.Hash, object.Name))
+	}
+	return nil
+}
+
+// addRef 写入反向引用，返回本次调用是否新建了引用。
+// 名称已指向同一 blob 时引用已存在，写入失败回滚时不能删除它，否则 GC 会回收仍被名称使用的 blob
+func (s *Store) addRef(hash, name string) (bool, error) {
+	key := s.refKey(hash, name)
+	exists, err := storage.ObjectExists(context.Background(), s.svc, s.opts.Bucket, key)
+	if err != nil || exists {
+		return false, err
+	}
+	return true, s.svc.UploadObject(s.opts.Bucket, key, nil)
+}
+
+// rollbackRef 撤销 addRef，只删除本次调用新建的引用
+func (s *Store) rollbackRef(hash, name string, created bool) {
+	if created {
+		_ = s.svc.DeleteObject(s.opts.Bucket, s.refKey(hash, name))
+	}
+}
+
+func (s *Store) validHash(hash string) error {
+	size := 64
+	if s.opts.Hash == Murmur3 {
+		size = 32
+	}
+	if len(hash) != size {
+		return fmt.Errorf("%w: %q", ErrInvalidHash, hash)
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return fmt.Errorf("%w: %q", ErrInvalidHash, hash)
+		}
+	}
+	return nil
+}
+
+func (s *Store) nameKey(name string) string {
+	return s.opts.Prefix + namesDir + name
+}
+
+func (s *Store) refPrefix(hash string) string {
+	return s.opts.Prefix + refsDir + hash + "/"
+}
+
+// refKey 名称转义为单个路径段，避免名称中的 "/" 产生额外层级
+func (s *Store) refKey(hash, name string) string {
+	return s.refPrefix(hash) + url.PathEscape(name)
+}
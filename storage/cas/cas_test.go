@@ -0,0 +1,255 @@
+package cas
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+const testBucket = "bucket"
+
+func newStore(t *testing.T, opts Options) (*Store, *memory.MemoryService) {
+	t.Helper()
+	svc := memory.NewMemoryService("http://localhost")
+	opts.Bucket = testBucket
+	store, err := New(svc, opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return store, svc
+}
+
+func countObjects(t *testing.T, svc storage.StorageService, prefix string) int {
+	t.Helper()
+	count := 0
+	err := storage.Walk(context.Background(), svc, &storage.ListObjectsInput{Bucket: testBucket, Prefix: prefix}, func(storage.ObjectInfo) error {
+		count++
+		return nil
+	}, storage.WithRecursive(true))
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	return count
+}
+
+func TestPutDeduplicatesIdenticalContent(t *testing.T) {
+	store, svc := newStore(t, Options{})
+	data := []byte("reference image")
+
+	first, err := store.Put("users/1/ref.png", data, storage.UploadObjectOptions{})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if first.Deduplicated {
+		t.Fatal("first Put() Deduplicated = true")
+	}
+	second, err := store.Put("users/2/ref.png", data, storage.UploadObjectOptions{Metadata: map[string]string{"owner": "2"}})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if !second.Deduplicated || second.Hash != first.Hash {
+		t.Fatalf("second Put() = %+v, want deduplicated with hash %s", second, first.Hash)
+	}
+
+	if got := countObjects(t, svc, "cas/blobs/"); got != 1 {
+		t.Fatalf("blob count = %d, want 1", got)
+	}
+	if count, _ := store.RefCount(first.Hash); count != 2 {
+		t.Fatalf("RefCount() = %d, want 2", count)
+	}
+
+	got, object, err := store.Get("users/2/ref.png")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !bytes.Equal(got, data) || object.Metadata["owner"] != "2" {
+		t.Fatalf("Get() = %q, %+v", got, object)
+	}
+}
+
+func TestExistsAndLinkSkipUpload(t *testing.T) {
+	store, _ := newStore(t, Options{Hash: Murmur3})
+	data := []byte("shared content")
+	hash := store.Sum(data)
+	if len(hash) != 32 {
+		t.Fatalf("Sum() = %q, want 32 hex chars", hash)
+	}
+
+	if exists, err := store.Exists(hash); err != nil || exists {
+		t.Fatalf("Exists() = %v, %v; want false", exists, err)
+	}
+	if _, err := store.Link("b", hash, nil); !storage.IsNotFound(err) {
+		t.Fatalf("Link() to missing blob error = %v, want not found", err)
+	}
+	if count, _ := store.RefCount(hash); count != 0 {
+		t.Fatalf("RefCount() after failed Link = %d, want 0", count)
+	}
+
+	if _, err := store.Put("a", data, storage.UploadObjectOptions{}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if exists, err := store.Exists(hash); err != nil || !exists {
+		t.Fatalf("Exists() = %v, %v; want true", exists, err)
+	}
+	object, err := store.Link("b", hash, nil)
+	if err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+	if object.Size != int64(len(data)) || !object.Deduplicated {
+		t.Fatalf("Link() = %+v", object)
+	}
+
+	if _, err := store.Exists("not-a-hash"); !errors.Is(err, ErrInvalidHash) {
+		t.Fatalf("Exists() error = %v, want ErrInvalidHash", err)
+	}
+}
+
+func TestPutDetectsCollision(t *testing.T) {
+	store, svc := newStore(t, Options{Verify: true})
+	data := []byte("original")
+	hash := store.Sum(data)
+	// 模拟哈希相同但内容不同的 blob
+	if err := svc.UploadObject(testBucket, store.BlobKey(hash), []byte("tampered")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Put("a", data, storage.UploadObjectOptions{}); !errors.Is(err, ErrHashCollision) {
+		t.Fatalf("Put() error = %v, want ErrHashCollision", err)
+	}
+	if count, _ := store.RefCount(hash); count != 0 {
+		t.Fatalf("RefCount() after collision = %d, want 0", count)
+	}
+}
+
+// failingBlobs 读取 blob 元数据时返回错误，模拟后端暂时不可用
+type failingBlobs struct {
+	*memory.MemoryService
+	store *Store
+	fail  bool
+}
+
+func (f *failingBlobs) GetObjectMetadata(bucketName, fileKey string) (*storage.ObjectMetadata, error) {
+	if f.fail && strings.HasPrefix(fileKey, f.store.opts.Prefix+blobsDir) {
+		return nil, errors.New("backend unavailable")
+	}
+	return f.MemoryService.GetObjectMetadata(bucketName, fileKey)
+}
+
+func TestFailedWriteKeepsExistingReference(t *testing.T) {
+	svc := &failingBlobs{MemoryService: memory.NewMemoryService("http://localhost")}
+	store, err := New(svc, Options{Bucket: testBucket})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	svc.store = store
+
+	data := []byte("content")
+	object, err := store.Put("a", data, storage.UploadObjectOptions{})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	svc.fail = true
+	if _, err := store.Put("a", data, storage.UploadObjectOptions{}); err == nil {
+		t.Fatal("Put() with failing backend should fail")
+	}
+	if _, err := store.Link("a", object.Hash, nil); err == nil {
+		t.Fatal("Link() with failing backend should fail")
+	}
+	if _, err := store.Link("b", object.Hash, nil); err == nil {
+		t.Fatal("Link() with failing backend should fail")
+	}
+	svc.fail = false
+
+	// a 的引用是之前写入的，失败的写入不能删除它；b 的引用是失败的 Link 新建的，需要回滚
+	if count, _ := store.RefCount(object.Hash); count != 1 {
+		t.Fatalf("RefCount() after failed writes = %d, want 1", count)
+	}
+	if data, _, err := store.Get("a"); err != nil || string(data) != "content" {
+		t.Fatalf("Get() = %q, %v", data, err)
+	}
+}
+
+func TestOverwriteMovesReference(t *testing.T) {
+	store, _ := newStore(t, Options{})
+	first, _ := store.Put("a", []byte("v1"), storage.UploadObjectOptions{})
+	second, err := store.Put("a", []byte("v2"), storage.UploadObjectOptions{})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if count, _ := store.RefCount(first.Hash); count != 0 {
+		t.Fatalf("RefCount(old) = %d, want 0", count)
+	}
+	if count, _ := store.RefCount(second.Hash); count != 1 {
+		t.Fatalf("RefCount(new) = %d, want 1", count)
+	}
+}
+
+func TestGCRemovesUnreferencedBlobs(t *testing.T) {
+	now := time.Now()
+	store, svc := newStore(t, Options{Now: func() time.Time { return now }})
+	kept, _ := store.Put("kept", []byte("kept"), storage.UploadObjectOptions{})
+	dropped, _ := store.Put("dropped", []byte("dropped content"), storage.UploadObjectOptions{})
+	if err := store.Delete("dropped"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Stat("dropped"); !storage.IsNotFound(err) {
+		t.Fatalf("Stat() after Delete error = %v, want not found", err)
+	}
+
+	// 宽限期内不回收
+	report, err := store.GC(context.Background())
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if report.Deleted != 0 || report.Unreferenced != 1 {
+		t.Fatalf("GC() within grace period = %+v", report)
+	}
+
+	now = now.Add(2 * time.Hour)
+	report, err = store.GC(context.Background())
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if report.Deleted != 1 || report.Referenced != 1 || report.Bytes != dropped.Size {
+		t.Fatalf("GC() = %+v", report)
+	}
+	if exists, _ := store.Exists(dropped.Hash); exists {
+		t.Fatal("unreferenced blob survived GC")
+	}
+	if exists, _ := store.Exists(kept.Hash); !exists {
+		t.Fatal("referenced blob was collected")
+	}
+	if got := countObjects(t, svc, "cas/blobs/"); got != 1 {
+		t.Fatalf("blob count = %d, want 1", got)
+	}
+}
+
+func TestGCCleansStaleReferences(t *testing.T) {
+	now := time.Now()
+	store, svc := newStore(t, Options{Now: func() time.Time { return now }})
+	object, _ := store.Put("a", []byte("content"), storage.UploadObjectOptions{})
+	// 模拟 Delete 在删除名称后中断，留下失效的反向引用
+	if err := svc.DeleteObject(testBucket, store.nameKey("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	report, err := store.GC(context.Background())
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if report.StaleRefs != 1 || report.Deleted != 1 {
+		t.Fatalf("GC() = %+v", report)
+	}
+	if count, _ := store.RefCount(object.Hash); count != 0 {
+		t.Fatalf("RefCount() = %d, want 0", count)
+	}
+}
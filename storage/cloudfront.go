@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CloudFrontSigner 生成 CloudFront 签名链接和签名 Cookie。
+// 未设置 NotBefore、ClientIP、Resource 时使用预设策略（canned policy），否则使用自定义策略（custom policy）
+type CloudFrontSigner struct {
+	BaseURL    string          // 分配域名或备用域名，如 "https://d111111abcdef8.cloudfront.net"
+	KeyPairID  string          // 公有密钥 ID（可信密钥组）
+	PrivateKey *rsa.PrivateKey // 与公有密钥对应的私钥
+	Now        func() time.Time
+}
+
+// NewCloudFrontSigner 使用 PEM 编码的私钥（PKCS#1 或 PKCS#8）创建签名器
+func NewCloudFrontSigner(baseURL, keyPairID string, privateKeyPEM []byte) (*CloudFrontSigner, error) {
+	key, err := ParseRSAPrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &CloudFrontSigner{BaseURL: baseURL, KeyPairID: keyPairID, PrivateKey: key}, nil
+}
+
+// ParseRSAPrivateKey 解析 PEM 编码的 RSA 私钥，支持 PKCS#1 和 PKCS#8
+func ParseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("storage: invalid PEM private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("storage: parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("storage: private key is not RSA")
+	}
+	return key, nil
+}
+
+// SignURL 实现 URLSigner
+func (s *CloudFrontSigner) SignURL(_, fileKey string, opts SignOptions) (string, error) {
+	objectURL := ObjectURL(s.BaseURL, fileKey)
+	params, err := s.sign(objectURL, opts)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	for _, param := range params {
+		query.Set(param.name, param.value)
+	}
+	separator := "?"
+	if strings.Contains(objectURL, "?") {
+		separator = "&"
+	}
+	return objectURL + separator + query.Encode(), nil
+}
+
+// SignCookies 生成签名 Cookie，用于一次授权访问多个对象（如 HLS 分片），通常与 SignOptions.Resource 通配符一起使用。
+// 返回的 Cookie 未设置 Domain 和 Path，由调用方按站点配置补充
+func (s *CloudFrontSigner) SignCookies(opts SignOptions) ([]*http.Cookie, error) {
+	if opts.Resource == "" {
+		return nil, errors.New("storage: signed cookies require a resource")
+	}
+	params, err := s.sign(ObjectURL(s.BaseURL, ""), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cookies := make([]*http.Cookie, 0, len(params))
+	for _, param := range params {
+		cookies = append(cookies, &http.Cookie{
+			Name:     "CloudFront-" + param.name,
+			Value:    param.value,
+			Secure:   true,
+			HttpOnly: true,
+		})
+	}
+	return cookies, nil
+}
+
+type signParam struct {
+	name, value string
+}
+
+// sign 生成签名参数，URL 查询参数与 Cookie 使用同样的名称（Cookie 加 "CloudFront-" 前缀）
+func (s *CloudFrontSigner) sign(objectURL string, opts SignOptions) ([]signParam, error) {
+	if s.PrivateKey == nil || s.KeyPairID == "" {
+		return nil, errors.New("storage: cloudfront signer requires a key pair id and private key")
+	}
+
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	expires := signExpiry(now, opts).Unix()
+
+	resource := objectURL
+	if opts.Resource != "" {
+		resource = s.resourceURL(opts.Resource)
+	}
+	canned := opts.Resource == "" && opts.ClientIP == "" && opts.NotBefore.IsZero()
+
+	condition := cloudFrontCondition{DateLessThan: &cloudFrontEpoch{Time: expires}}
+	if !opts.NotBefore.IsZero() {
+		condition.DateGreaterThan = &cloudFrontEpoch{Time: opts.NotBefore.Unix()}
+	}
+	if opts.ClientIP != "" {
+		sourceIP, err := cidr(opts.ClientIP)
+		if err != nil {
+			return nil, err
+		}
+		condition.IPAddress = &cloudFrontSourceIP{SourceIP: sourceIP}
+	}
+	// 不转义 HTML 字符，保证签名内容与资源地址字面一致
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(cloudFrontPolicy{Statement: []cloudFrontStatement{{Resource: resource, Condition: condition}}}); err != nil {
+		return nil, err
+	}
+	policy := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+
+	digest := sha1.Sum(policy)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.PrivateKey, crypto.SHA1, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]signParam, 0, 3)
+	if canned {
+		params = append(params, signParam{"Expires", fmt.Sprint(expires)})
+	} else {
+		params = append(params, signParam{"Policy", cloudFrontEncode(policy)})
+	}
+	return append(params,
+		signParam{"Signature", cloudFrontEncode(signature)},
+		signParam{"Key-Pair-Id", s.KeyPairID},
+	), nil
+}
+
+// resourceURL 将相对路径补全为完整地址，已是完整地址时原样返回
+func (s *CloudFrontSigner) resourceURL(resource string) string {
+	if strings.Contains(resource, "://") {
+		return resource
+	}
+	return strings.TrimRight(s.BaseURL, "/") + "/" + strings.TrimLeft(resource, "/")
+}
+
+// cloudFrontPolicy 字段顺序与 CloudFront 文档一致，预设策略要求签名内容与服务端重建的 JSON 完全相同
+type cloudFrontPolicy struct {
+	Statement []cloudFrontStatement `json:"Statement"`
+}
+
+type cloudFrontStatement struct {
+	Resource  string              `json:"Resource"`
+	Condition cloudFrontCondition `json:"Condition"`
+}
+
+type cloudFrontCondition struct {
+	DateLessThan    *cloudFrontEpoch    `json:"DateLessThan"`
+	DateGreaterThan *cloudFrontEpoch    `json:"DateGreaterThan,omitempty"`
+	IPAddress       *cloudFrontSourceIP `json:"IpAddress,omitempty"`
+}
+
+type cloudFrontEpoch struct {
+	Time int64 `json:"AWS:EpochTime"`
+}
+
+type cloudFrontSourceIP struct {
+	SourceIP string `json:"AWS:SourceIp"`
+}
+
+// cloudFrontEncode CloudFront 使用的 Base64 变体：+ = / 分别替换为 - _ ~
+func cloudFrontEncode(data []byte) string {
+	return strings.NewReplacer("+", "-", "=", "_", "/", "~").Replace(base64.StdEncoding.EncodeToString(data))
+}
+
+// cidr 单个 IP 补全为 /32（IPv6 为 /128）
+func cidr(clientIP string) (string, error) {
+	if _, network, err := net.ParseCIDR(clientIP); err == nil {
+		return network.String(), nil
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return "", fmt.Errorf("storage: invalid client ip %q", clientIP)
+	}
+	if ip.To4() != nil {
+		return ip.String() + "/32", nil
+	}
+	return ip.String() + "/128", nil
+}
+
+var _ URLSigner = (*CloudFrontSigner)(nil)
@@ -0,0 +1,354 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// SniffLen 内容嗅探读取的最大字节数
+const SniffLen = 512
+
+// DefaultContentType 无法识别时使用的内容类型
+const DefaultContentType = "application/octet-stream"
+
+// ErrContentTypeNotAllowed 上传内容不在 UploadObjectOptions.AllowedContentTypes 白名单中
+var ErrContentTypeNotAllowed = errors.New("storage: content type not allowed")
+
+// ContentSniffer 根据文件头部字节识别内容类型，无法识别时返回空字符串
+type ContentSniffer func(head []byte) string
+
+// ContentTypeRegistry 内容类型识别表：扩展名映射 + 文件头嗅探
+type ContentTypeRegistry struct {
+	mu         sync.RWMutex
+	extensions map[string]string
+	sniffers   []ContentSniffer
+}
+
+// DefaultContentTypes 默认的内容类型识别表，GetContentType 等包级函数使用它
+var DefaultContentTypes = NewContentTypeRegistry()
+
+// NewContentTypeRegistry 创建包含内置图片、视频、音频、PDF、JSON 规则的识别表
+func NewContentTypeRegistry() *ContentTypeRegistry {
+	r := &ContentTypeRegistry{extensions: make(map[string]string, len(builtinExtensions))}
+	for ext, contentType := range builtinExtensions {
+		r.extensions[ext] = contentType
+	}
+	r.sniffers = append(r.sniffers, builtinSniffers...)
+	return r
+}
+
+// RegisterExtension 注册扩展名（如 ".heic"）对应的内容类型，已存在时覆盖
+func (r *ContentTypeRegistry) RegisterExtension(ext, contentType string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.extensions[normalizeExt(ext)] = contentType
+}
+
+// RegisterSniffer 注册文件头嗅探规则，后注册的优先
+func (r *ContentTypeRegistry) RegisterSniffer(sniffer ContentSniffer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sniffers = append([]ContentSniffer{sniffer}, r.sniffers...)
+}
+
+// ByExtension 按扩展名识别内容类型，未知扩展名返回空字符串
+func (r *ContentTypeRegistry) ByExtension(fileName string) string {
+	ext := normalizeExt(path.Ext(fileName))
+	if ext == "" {
+		return ""
+	}
+
+	r.mu.RLock()
+	contentType, ok := r.extensions[ext]
+	r.mu.RUnlock()
+	if ok {
+		return contentType
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// Sniff 根据文件头部字节识别内容类型，只取前 SniffLen 字节，无法识别时返回空字符串
+func (r *ContentTypeRegistry) Sniff(head []byte) string {
+	if len(head) == 0 {
+		return ""
+	}
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+
+	r.mu.RLock()
+	sniffers := r.sniffers
+	r.mu.RUnlock()
+	for _, sniffer := range sniffers {
+		if contentType := sniffer(head); contentType != "" {
+			return contentType
+		}
+	}
+
+	// 兜底使用标准库嗅探，忽略文本、XML 等无法确定具体格式的结果
+	contentType := http.DetectContentType(head)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == DefaultContentType, mediaType == "text/plain", mediaType == "text/xml", mediaType == "text/html":
+		return ""
+	default:
+		return contentType
+	}
+}
+
+// Detect 识别内容类型：能从文件头确定具体格式时以嗅探结果为准，否则按扩展名，最后返回 DefaultContentType
+func (r *ContentTypeRegistry) Detect(fileName string, head []byte) string {
+	if contentType := r.Sniff(head); contentType != "" {
+		return contentType
+	}
+	if contentType := r.ByExtension(fileName); contentType != "" {
+		return contentType
+	}
+	if len(head) > 0 && utf8Text(head) {
+		return "text/plain; charset=utf-8"
+	}
+	return DefaultContentType
+}
+
+// DetectReader 读取流的前 SniffLen 字节识别内容类型，返回可从头读取完整内容的 reader
+func (r *ContentTypeRegistry) DetectReader(fileName string, reader io.Reader) (string, io.Reader, error) {
+	head, buffered, err := peekHead(reader)
+	if err != nil {
+		return "", nil, err
+	}
+	return r.Detect(fileName, head), buffered, nil
+}
+
+// GetContentType 按文件名扩展名推断内容类型，未知扩展名返回 application/octet-stream
+func GetContentType(fileName string) string {
+	return DefaultContentTypes.Detect(fileName, nil)
+}
+
+// DetectContentType 结合文件名和文件头识别内容类型
+func DetectContentType(fileName string, head []byte) string {
+	return DefaultContentTypes.Detect(fileName, head)
+}
+
+// DetectContentTypeReader 识别流的内容类型，返回可从头读取完整内容的 reader
+func DetectContentTypeReader(fileName string, reader io.Reader) (string, io.Reader, error) {
+	return DefaultContentTypes.DetectReader(fileName, reader)
+}
+
+// ContentTypeAllowed 判断内容类型是否匹配白名单，支持 "image/*" 通配，空白名单表示不限制
+func ContentTypeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "*/*" || pattern == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// PrepareUpload 确定上传的内容类型并按 options.AllowedContentTypes 校验。
+// 配置白名单时，声明的类型和嗅探出的实际类型都必须在白名单内。
+// 返回的 reader 会重放已嗅探的字节，调用方必须用它替代原 reader。
+func PrepareUpload(fileKey string, reader io.Reader, options UploadObjectOptions) (string, io.Reader, error) {
+	if options.ContentType != "" && len(options.AllowedContentTypes) == 0 {
+		return options.ContentType, reader, nil
+	}
+
+	head, buffered, err := peekHead(reader)
+	if err != nil {
+		return "", nil, err
+	}
+	contentType := options.ContentType
+	if contentType == "" {
+		contentType = DetectContentType(fileKey, head)
+	}
+	if len(options.AllowedContentTypes) == 0 {
+		return contentType, buffered, nil
+	}
+
+	actual := DefaultContentTypes.actualContentType(fileKey, head)
+	if !ContentTypeAllowed(actual, options.AllowedContentTypes) {
+		return "", nil, fmt.Errorf("%w: %s", ErrContentTypeNotAllowed, actual)
+	}
+	if !ContentTypeAllowed(contentType, options.AllowedContentTypes) {
+		return "", nil, fmt.Errorf("%w: %s", ErrContentTypeNotAllowed, contentType)
+	}
+	return contentType, buffered, nil
+}
+
+// actualContentType 只依据文件内容判断实际类型，用于白名单校验。
+// 扩展名仅在内容为文本且扩展名对应文本类格式（如 svg、json、csv）时采用，避免伪装扩展名绕过校验。
+func (r *ContentTypeRegistry) actualContentType(fileName string, head []byte) string {
+	if sniffed := r.Sniff(head); sniffed != "" {
+		return sniffed
+	}
+	if len(head) == 0 || !textContent(head) {
+		return DefaultContentType
+	}
+	byExt, _, _ := mime.ParseMediaType(r.ByExtension(fileName))
+	if strings.HasPrefix(byExt, "text/") || byExt == "image/svg+xml" || byExt == "application/json" || byExt == "application/xml" {
+		return r.ByExtension(fileName)
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// peekHead 读取前 SniffLen 字节而不消耗它们
+func peekHead(reader io.Reader) ([]byte, *bufio.Reader, error) {
+	buffered := bufio.NewReaderSize(reader, SniffLen)
+	head, err := buffered.Peek(SniffLen)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, nil, err
+	}
+	return head, buffered, nil
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+func utf8Text(head []byte) bool {
+	return strings.HasPrefix(http.DetectContentType(head), "text/plain")
+}
+
+// textContent 内容是否为文本（含 HTML、XML）
+func textContent(head []byte) bool {
+	return strings.HasPrefix(http.DetectContentType(head), "text/")
+}
+
+var builtinExtensions = map[string]string{
+	// 图片
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".svg":  "image/svg+xml",
+	".bmp":  "image/bmp",
+	".ico":  "image/x-icon",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".avif": "image/avif",
+	".heic": "image/heic",
+	".heif": "image/heif",
+	// 视频
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".mkv":  "video/x-matroska",
+	".avi":  "video/x-msvideo",
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+	// 音频
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".flac": "audio/flac",
+	// 文档
+	".pdf":  "application/pdf",
+	".json": "application/json",
+	".txt":  "text/plain; charset=utf-8",
+	".csv":  "text/csv; charset=utf-8",
+	".html": "text/html; charset=utf-8",
+	".zip":  "application/zip",
+}
+
+var builtinSniffers = []ContentSniffer{
+	sniffISOBaseMedia,
+	sniffMatroska,
+	sniffAudio,
+	sniffJSON,
+}
+
+// sniffISOBaseMedia 识别 ISO BMFF 容器（mp4/mov/m4a/heic/avif），根据 ftyp 的主品牌区分
+func sniffISOBaseMedia(head []byte) string {
+	if len(head) < 12 || string(head[4:8]) != "ftyp" {
+		return ""
+	}
+	switch brand := string(head[8:12]); brand {
+	case "qt  ":
+		return "video/quicktime"
+	case "M4A ", "M4B ":
+		return "audio/mp4"
+	case "avif", "avis":
+		return "image/avif"
+	case "heic", "heix", "heim", "heis":
+		return "image/heic"
+	case "mif1", "msf1":
+		return "image/heif"
+	default:
+		return "video/mp4"
+	}
+}
+
+// sniffMatroska 区分 webm 与 mkv
+func sniffMatroska(head []byte) string {
+	if !bytes.HasPrefix(head, []byte("\x1A\x45\xDF\xA3")) {
+		return ""
+	}
+	if bytes.Contains(head, []byte("webm")) {
+		return "video/webm"
+	}
+	return "video/x-matroska"
+}
+
+func sniffAudio(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return "audio/mpeg"
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xF6 == 0xF0:
+		// ADTS AAC 帧头（layer 位为 0）
+		return "audio/aac"
+	case len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		return "audio/wav"
+	}
+	return ""
+}
+
+// sniffJSON 识别以 { 或 [ 开头的 JSON 文档，内容不完整时只校验开头结构
+func sniffJSON(head []byte) string {
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	if bytes.HasPrefix(trimmed, []byte("\xEF\xBB\xBF")) {
+		trimmed = bytes.TrimLeft(trimmed[3:], " \t\r\n")
+	}
+	if len(trimmed) < 2 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return ""
+	}
+
+	next := bytes.TrimLeft(trimmed[1:], " \t\r\n")
+	if len(next) == 0 {
+		return ""
+	}
+	switch {
+	case trimmed[0] == '{' && (next[0] == '"' || next[0] == '}'):
+		return "application/json"
+	case trimmed[0] == '[' && bytes.IndexByte([]byte(`{["]-0123456789tfn`), next[0]) >= 0:
+		return "application/json"
+	}
+	return ""
+}
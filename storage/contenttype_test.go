@@ -0,0 +1,125 @@
+package storage_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		head     []byte
+		want     string
+	}{
+		{"mp4 by extension", "result/0.mp4", nil, "video/mp4"},
+		{"unknown extension", "blob", nil, "application/octet-stream"},
+		{"upper case extension", "A.PNG", nil, "image/png"},
+		{"mp4 by magic", "noext", []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00"), "video/mp4"},
+		{"mov by brand", "clip", []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00"), "video/quicktime"},
+		{"magic wins over extension", "fake.jpg", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"webm", "v", []byte("\x1A\x45\xDF\xA3\x9F\x42\x86\x81\x01\x42\x82\x84webm"), "video/webm"},
+		{"pdf", "doc", []byte("%PDF-1.7\n"), "application/pdf"},
+		{"flac", "a", []byte("fLaC\x00\x00\x00\x22"), "audio/flac"},
+		{"json object", "data", []byte(" {\"a\": 1}"), "application/json"},
+		{"json text keeps extension", "x.csv", []byte("a,b\n1,2\n"), "text/csv; charset=utf-8"},
+		{"plain text", "notes", []byte("hello world"), "text/plain; charset=utf-8"},
+		{"svg by extension", "icon.svg", []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"), "image/svg+xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := storage.DetectContentType(tt.fileName, tt.head); got != tt.want {
+				t.Fatalf("DetectContentType(%q) = %q, want %q", tt.fileName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryCustomRules(t *testing.T) {
+	registry := storage.NewContentTypeRegistry()
+	registry.RegisterExtension("qqz", "model/gltf-binary")
+	registry.RegisterSniffer(func(head []byte) string {
+		if bytes.HasPrefix(head, []byte("glTF")) {
+			return "model/gltf-binary"
+		}
+		return ""
+	})
+
+	if got := registry.Detect("scene.QQZ", nil); got != "model/gltf-binary" {
+		t.Fatalf("extension = %q", got)
+	}
+	if got := registry.Detect("scene", []byte("glTF\x02\x00\x00\x00")); got != "model/gltf-binary" {
+		t.Fatalf("sniffer = %q", got)
+	}
+	if got := storage.GetContentType("scene.qqz"); got == "model/gltf-binary" {
+		t.Fatal("custom registry leaked into DefaultContentTypes")
+	}
+}
+
+func TestDetectReaderReplaysHead(t *testing.T) {
+	payload := append([]byte("\x00\x00\x00\x18ftypisom"), bytes.Repeat([]byte{1}, 2048)...)
+	contentType, reader, err := storage.DetectContentTypeReader("x", bytes.NewReader(payload))
+	if err != nil || contentType != "video/mp4" {
+		t.Fatalf("DetectContentTypeReader() = %q, %v", contentType, err)
+	}
+	data, _ := io.ReadAll(reader)
+	if !bytes.Equal(data, payload) {
+		t.Fatal("reader did not replay sniffed bytes")
+	}
+}
+
+func TestContentTypeAllowed(t *testing.T) {
+	allowed := []string{"image/*", "video/mp4"}
+	for contentType, want := range map[string]bool{
+		"image/png":                 true,
+		"video/mp4":                 true,
+		"video/webm":                false,
+		"text/plain; charset=utf-8": false,
+		"imagex/png":                false,
+	} {
+		if got := storage.ContentTypeAllowed(contentType, allowed); got != want {
+			t.Errorf("ContentTypeAllowed(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+	if !storage.ContentTypeAllowed("anything/else", nil) {
+		t.Error("empty allow list should allow everything")
+	}
+}
+
+func TestUploadAllowListUsesSniffedType(t *testing.T) {
+	store := memory.NewMemoryService("")
+	options := storage.UploadObjectOptions{AllowedContentTypes: []string{"image/*"}}
+
+	err := store.UploadObjectStreamWithOptions("b", "avatar.png", strings.NewReader("#!/bin/sh\nrm -rf /"), options)
+	if !errors.Is(err, storage.ErrContentTypeNotAllowed) {
+		t.Fatalf("script disguised as png: err = %v, want ErrContentTypeNotAllowed", err)
+	}
+
+	options.ContentType = "image/png"
+	err = store.UploadObjectWithOptions("b", "avatar", []byte("%PDF-1.4"), options)
+	if !errors.Is(err, storage.ErrContentTypeNotAllowed) {
+		t.Fatalf("declared type must match content: err = %v", err)
+	}
+
+	if err := store.UploadObjectWithOptions("b", "avatar", []byte("GIF89a\x01\x00"), storage.UploadObjectOptions{AllowedContentTypes: []string{"image/*"}}); err != nil {
+		t.Fatalf("gif upload error = %v", err)
+	}
+	metadata, _ := store.GetObjectMetadata("b", "avatar")
+	if metadata.ContentType != "image/gif" {
+		t.Fatalf("stored content type = %q, want sniffed image/gif", metadata.ContentType)
+	}
+
+	svg := []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>")
+	if err := store.UploadObjectWithOptions("b", "icon.svg", svg, storage.UploadObjectOptions{AllowedContentTypes: []string{"image/svg+xml"}}); err != nil {
+		t.Fatalf("svg upload error = %v", err)
+	}
+	if err := store.UploadObjectWithOptions("b", "icon.svg", svg, storage.UploadObjectOptions{AllowedContentTypes: []string{"image/png"}}); !errors.Is(err, storage.ErrContentTypeNotAllowed) {
+		t.Fatalf("svg with png allow list: err = %v", err)
+	}
+}
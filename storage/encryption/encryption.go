@@ -0,0 +1,358 @@
+// Package encryption 提供客户端信封加密的存储装饰器：每个对象使用随机数据密钥做 AES-GCM 加密，
+// 数据密钥由 KMS 主密钥包装后与加密参数一起保存在对象元数据中，读取时透明解密。
+// 密文以 "存储桶/对象键" 作为附加认证数据，挪到其他位置的密文无法解密。
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/utils"
+)
+
+// Algorithm 对象内容加密算法
+const Algorithm = "AES-256-GCM"
+
+// 保存在对象元数据中的加密头（小写，与 S3 返回的元数据键一致）
+const (
+	MetadataAlgorithm   = "x-enc-alg"          // 加密算法
+	MetadataKeyID       = "x-enc-key-id"       // 包装数据密钥的主密钥ID
+	MetadataWrappedKey  = "x-enc-key"          // 被包装的数据密钥（Base64）
+	MetadataContentType = "x-enc-content-type" // 明文内容类型
+	MetadataSize        = "x-enc-size"         // 明文长度
+)
+
+var (
+	// ErrPresignEncrypted 加密对象不能生成预签名下载链接，否则下载到的是密文
+	ErrPresignEncrypted = errors.New("encryption: presigned GET is not allowed for encrypted objects")
+	// ErrPresignUpload 预签名上传绕过客户端加密，上传的对象为明文
+	ErrPresignUpload = errors.New("encryption: presigned uploads bypass client-side encryption")
+	// ErrMetadataUnsupported 底层存储不支持上传时写入元数据，无法保存加密头
+	ErrMetadataUnsupported = errors.New("encryption: storage does not support upload metadata")
+)
+
+// Service 客户端加密存储装饰器。
+// 未加密的已有对象原样读取；ListObjects 返回的 Size 为密文长度，GetObjectMetadata 返回明文长度。
+type Service struct {
+	storage.StorageService
+	kms KMS
+}
+
+// New 创建加密装饰器，底层存储需实现 storage.OptionsUploader 以保存加密头
+func New(svc storage.StorageService, kms KMS) (*Service, error) {
+	if _, ok := svc.(storage.OptionsUploader); !ok {
+		return nil, ErrMetadataUnsupported
+	}
+	if kms == nil {
+		return nil, errors.New("encryption: kms is required")
+	}
+	return &Service{StorageService: svc, kms: kms}, nil
+}
+
+// ===== 基础文件操作 =====
+
+// UploadObject 加密后上传
+func (s *Service) UploadObject(bucketName, fileKey string, data []byte) error {
+	return s.UploadObjectWithOptions(bucketName, fileKey, data, storage.UploadObjectOptions{})
+}
+
+// UploadObjectStream 读取全部内容后加密上传（AES-GCM 需要完整明文）
+func (s *Service) UploadObjectStream(bucketName, fileKey string, file io.Reader) error {
+	return s.UploadObjectStreamWithOptions(bucketName, fileKey, file, storage.UploadObjectOptions{})
+}
+
+// UploadObjectWithOptions 加密后上传，内容类型的识别和白名单校验基于明文
+func (s *Service) UploadObjectWithOptions(bucketName, fileKey string, data []byte, options storage.UploadObjectOptions) error {
+	contentType, _, err := storage.PrepareUpload(fileKey, bytes.NewReader(data), options)
+	if err != nil {
+		return err
+	}
+
+	ciphertext, headers, err := s.encrypt(data, bucketName, fileKey)
+	if err != nil {
+		return err
+	}
+	headers[MetadataContentType] = contentType
+
+	metadata := make(map[string]string, len(options.Metadata)+len(headers))
+	for k, v := range options.Metadata {
+		metadata[k] = v
+	}
+	for k, v := range headers {
+		metadata[k] = v
+	}
+
+	return s.StorageService.(storage.OptionsUploader).UploadObjectWithOptions(bucketName, fileKey, ciphertext, storage.UploadObjectOptions{
+		ContentType:  storage.DefaultContentType,
+		CacheControl: options.CacheControl,
+		ACL:          options.ACL,
+		Metadata:     metadata,
+	})
+}
+
+// UploadObjectStreamWithOptions 读取全部内容后加密上传
+func (s *Service) UploadObjectStreamWithOptions(bucketName, fileKey string, file io.Reader, options storage.UploadObjectOptions) error {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	return s.UploadObjectWithOptions(bucketName, fileKey, data, options)
+}
+
+// GetObject 获取并解密对象，未加密的对象原样返回
+func (s *Service) GetObject(bucketName, fileKey string) ([]byte, error) {
+	metadata, err := s.StorageService.GetObjectMetadata(bucketName, fileKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.StorageService.GetObject(bucketName, fileKey)
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted(metadata.Metadata) {
+		return data, nil
+	}
+	return s.decrypt(data, metadata.Metadata, bucketName, fileKey)
+}
+
+// ===== 文件管理操作 =====
+
+// CopyObject 复制对象。原地复制（如修改存储类别）保留源对象的加密头，ContentType 作为明文类型保存；
+// 密文与存储位置绑定，复制到其他位置时解密后重新加密上传
+func (s *Service) CopyObject(input *storage.CopyObjectInput) error {
+	if input == nil {
+		return s.StorageService.CopyObject(input)
+	}
+
+	source, err := s.StorageService.GetObjectMetadata(input.SourceBucket, input.SourceKey)
+	if err != nil {
+		return err
+	}
+	if !encrypted(source.Metadata) {
+		return s.StorageService.CopyObject(input)
+	}
+
+	metadata := input.Metadata
+	if len(metadata) == 0 {
+		metadata = userMetadata(source.Metadata)
+	}
+	contentType := input.ContentType
+	if contentType == "" {
+		contentType = header(source.Metadata, MetadataContentType)
+	}
+
+	if input.SourceBucket != input.DestinationBucket || input.SourceKey != input.DestinationKey {
+		data, err := s.GetObject(input.SourceBucket, input.SourceKey)
+		if err != nil {
+			return err
+		}
+		if err := s.UploadObjectWithOptions(input.DestinationBucket, input.DestinationKey, data, storage.UploadObjectOptions{
+			ContentType: contentType,
+			Metadata:    metadata,
+		}); err != nil {
+			return err
+		}
+		if input.StorageClass == "" {
+			return nil
+		}
+		// 存储类别只能通过原地复制设置
+		return s.CopyObject(&storage.CopyObjectInput{
+			SourceBucket:      input.DestinationBucket,
+			SourceKey:         input.DestinationKey,
+			DestinationBucket: input.DestinationBucket,
+			DestinationKey:    input.DestinationKey,
+			StorageClass:      input.StorageClass,
+		})
+	}
+
+	metadata = withHeaders(metadata, source.Metadata)
+	metadata[MetadataContentType] = contentType
+
+	copyInput := *input
+	copyInput.ContentType = storage.DefaultContentType
+	copyInput.Metadata = metadata
+	return s.StorageService.CopyObject(&copyInput)
+}
+
+// MoveObject 移动对象，加密对象在目标位置重新加密
+func (s *Service) MoveObject(sourceBucket, sourceKey, destBucket, destKey string) error {
+	err := s.CopyObject(&storage.CopyObjectInput{
+		SourceBucket:      sourceBucket,
+		SourceKey:         sourceKey,
+		DestinationBucket: destBucket,
+		DestinationKey:    destKey,
+	})
+	if err != nil {
+		return err
+	}
+	return s.StorageService.DeleteObject(sourceBucket, sourceKey)
+}
+
+// GetObjectMetadata 获取对象元数据，加密对象返回明文的内容类型和长度，并隐藏加密头
+func (s *Service) GetObjectMetadata(bucketName, fileKey string) (*storage.ObjectMetadata, error) {
+	metadata, err := s.StorageService.GetObjectMetadata(bucketName, fileKey)
+	if err != nil || !encrypted(metadata.Metadata) {
+		return metadata, err
+	}
+
+	plain := *metadata
+	plain.ContentType = header(metadata.Metadata, MetadataContentType)
+	if size, err := strconv.ParseInt(header(metadata.Metadata, MetadataSize), 10, 64); err == nil {
+		plain.ContentLength = size
+	}
+	plain.Metadata = userMetadata(metadata.Metadata)
+	return &plain, nil
+}
+
+// ===== 预签名URL操作 =====
+
+// PreSignGetObject 加密对象返回 ErrPresignEncrypted
+func (s *Service) PreSignGetObject(bucketName, fileKey string) (string, error) {
+	metadata, err := s.StorageService.GetObjectMetadata(bucketName, fileKey)
+	if err != nil {
+		return "", err
+	}
+	if encrypted(metadata.Metadata) {
+		return "", ErrPresignEncrypted
+	}
+	return s.StorageService.PreSignGetObject(bucketName, fileKey)
+}
+
+// PreSignPutObject 返回 ErrPresignUpload，通过预签名链接上传的对象不会被加密
+func (s *Service) PreSignPutObject(bucketName, fileKey string) (string, error) {
+	return "", ErrPresignUpload
+}
+
+// BatchPreSignPutObject 不生成预签名上传链接，返回空结果
+func (s *Service) BatchPreSignPutObject(bucketName string, fileKeys []string, isWholeKey bool) map[string]string {
+	return map[string]string{}
+}
+
+// PreSignPostPolicy 返回 ErrPresignUpload，表单直传的对象不会被加密
+func (s *Service) PreSignPostPolicy(bucketName, keyPrefix string, conditions storage.PostPolicyConditions) (*storage.PostPolicy, error) {
+	return nil, ErrPresignUpload
+}
+
+// ===== 高级功能 =====
+
+// SetObjectMetadata 设置用户元数据，保留加密头
+func (s *Service) SetObjectMetadata(bucketName, fileKey string, metadata map[string]string) error {
+	current, err := s.StorageService.GetObjectMetadata(bucketName, fileKey)
+	if err != nil {
+		return err
+	}
+	if !encrypted(current.Metadata) {
+		return s.StorageService.SetObjectMetadata(bucketName, fileKey, metadata)
+	}
+	return s.StorageService.SetObjectMetadata(bucketName, fileKey, withHeaders(metadata, current.Metadata))
+}
+
+// GenerateDownloadURL 加密对象无法直接下载，返回空字符串
+func (s *Service) GenerateDownloadURL(bucketName, fileKey string) string {
+	metadata, err := s.StorageService.GetObjectMetadata(bucketName, fileKey)
+	if err != nil || encrypted(metadata.Metadata) {
+		return ""
+	}
+	return s.StorageService.GenerateDownloadURL(bucketName, fileKey)
+}
+
+// ===== 辅助方法 =====
+
+// encrypt 生成随机数据密钥加密内容，返回密文和加密头
+func (s *Service) encrypt(plaintext []byte, bucketName, fileKey string) ([]byte, map[string]string, error) {
+	dataKey, err := utils.GenerateRandomBytes(32)
+	if err != nil {
+		return nil, nil, err
+	}
+	ciphertext, err := utils.AESGCMEncrypt(plaintext, dataKey, additionalData(bucketName, fileKey))
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, keyID, err := s.kms.WrapKey(context.Background(), dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encryption: wrap data key: %w", err)
+	}
+	return ciphertext, map[string]string{
+		MetadataAlgorithm:  Algorithm,
+		MetadataKeyID:      keyID,
+		MetadataWrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		MetadataSize:       strconv.Itoa(len(plaintext)),
+	}, nil
+}
+
+func (s *Service) decrypt(ciphertext []byte, metadata map[string]string, bucketName, fileKey string) ([]byte, error) {
+	if alg := header(metadata, MetadataAlgorithm); alg != Algorithm {
+		return nil, fmt.Errorf("encryption: unsupported algorithm %q", alg)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(header(metadata, MetadataWrappedKey))
+	if err != nil {
+		return nil, fmt.Errorf("encryption: decode data key: %w", err)
+	}
+	dataKey, err := s.kms.UnwrapKey(context.Background(), header(metadata, MetadataKeyID), wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: unwrap data key: %w", err)
+	}
+	plaintext, err := utils.AESGCMDecrypt(ciphertext, dataKey, additionalData(bucketName, fileKey))
+	if err != nil {
+		return nil, fmt.Errorf("encryption: decrypt object: %w", err)
+	}
+	return plaintext, nil
+}
+
+// additionalData 将密文绑定到对象所在的存储桶和对象键
+func additionalData(bucketName, fileKey string) []byte {
+	return []byte(bucketName + "/" + fileKey)
+}
+
+func encrypted(metadata map[string]string) bool {
+	return header(metadata, MetadataAlgorithm) != ""
+}
+
+// header 忽略大小写读取加密头，部分后端会改写元数据键的大小写
+func header(metadata map[string]string, key string) string {
+	if value, ok := metadata[key]; ok {
+		return value
+	}
+	for k, v := range metadata {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+func isHeader(key string) bool {
+	return strings.HasPrefix(strings.ToLower(key), "x-enc-")
+}
+
+// userMetadata 去掉加密头后的用户元数据
+func userMetadata(metadata map[string]string) map[string]string {
+	result := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if !isHeader(k) {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// withHeaders 在用户元数据上附加 source 中的加密头
+func withHeaders(metadata, source map[string]string) map[string]string {
+	result := userMetadata(metadata)
+	for _, key := range []string{MetadataAlgorithm, MetadataKeyID, MetadataWrappedKey, MetadataContentType, MetadataSize} {
+		result[key] = header(source, key)
+	}
+	return result
+}
+
+var (
+	_ storage.StorageService  = (*Service)(nil)
+	_ storage.OptionsUploader = (*Service)(nil)
+)
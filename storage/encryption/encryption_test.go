@@ -0,0 +1,171 @@
+package encryption_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/encryption"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newService(t *testing.T) (*encryption.Service, *memory.MemoryService, *encryption.Keyring) {
+	t.Helper()
+	keyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	inner := memory.NewMemoryService("")
+	svc, err := encryption.New(inner, keyring)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return svc, inner, keyring
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	svc, inner, _ := newService(t)
+	err := svc.UploadObjectWithOptions("b", "refs/a.png", pngHeader, storage.UploadObjectOptions{
+		Metadata:            map[string]string{"owner": "u1"},
+		AllowedContentTypes: []string{"image/*"},
+	})
+	if err != nil {
+		t.Fatalf("UploadObjectWithOptions() error = %v", err)
+	}
+
+	stored, _ := inner.GetObject("b", "refs/a.png")
+	if bytes.Contains(stored, []byte("PNG")) {
+		t.Fatal("object should be stored encrypted")
+	}
+	data, err := svc.GetObject("b", "refs/a.png")
+	if err != nil || !bytes.Equal(data, pngHeader) {
+		t.Fatalf("GetObject() = %q, %v", data, err)
+	}
+
+	metadata, err := svc.GetObjectMetadata("b", "refs/a.png")
+	if err != nil {
+		t.Fatalf("GetObjectMetadata() error = %v", err)
+	}
+	if metadata.ContentType != "image/png" || metadata.ContentLength != int64(len(pngHeader)) || len(metadata.Metadata) != 1 {
+		t.Fatalf("metadata = %+v", metadata)
+	}
+
+	if _, err := svc.PreSignGetObject("b", "refs/a.png"); !errors.Is(err, encryption.ErrPresignEncrypted) {
+		t.Fatalf("PreSignGetObject() error = %v, want ErrPresignEncrypted", err)
+	}
+	if url := svc.GenerateDownloadURL("b", "refs/a.png"); url != "" {
+		t.Fatalf("GenerateDownloadURL() = %q, want empty", url)
+	}
+}
+
+func TestEncryptedMetadataSurvivesCopyAndUpdate(t *testing.T) {
+	svc, _, _ := newService(t)
+	_ = svc.UploadObject("b", "a.txt", []byte("secret"))
+
+	if err := svc.SetObjectMetadata("b", "a.txt", map[string]string{"k": "v"}); err != nil {
+		t.Fatalf("SetObjectMetadata() error = %v", err)
+	}
+	if err := svc.CopyObject(&storage.CopyObjectInput{
+		SourceBucket: "b", SourceKey: "a.txt",
+		DestinationBucket: "b", DestinationKey: "b.txt",
+		Metadata: map[string]string{"k": "copied"},
+	}); err != nil {
+		t.Fatalf("CopyObject() error = %v", err)
+	}
+
+	for _, key := range []string{"a.txt", "b.txt"} {
+		if data, err := svc.GetObject("b", key); err != nil || string(data) != "secret" {
+			t.Fatalf("GetObject(%s) = %q, %v", key, data, err)
+		}
+	}
+	if metadata, _ := svc.GetObjectMetadata("b", "b.txt"); metadata.Metadata["k"] != "copied" {
+		t.Fatalf("copied metadata = %v", metadata.Metadata)
+	}
+}
+
+func TestCiphertextIsBoundToLocation(t *testing.T) {
+	svc, inner, _ := newService(t)
+	_ = svc.UploadObject("b", "a.txt", []byte("secret"))
+
+	// 绕过装饰器直接复制密文和加密头
+	if err := inner.CopyObject(&storage.CopyObjectInput{
+		SourceBucket: "b", SourceKey: "a.txt",
+		DestinationBucket: "b", DestinationKey: "stolen.txt",
+	}); err != nil {
+		t.Fatalf("inner CopyObject() error = %v", err)
+	}
+	if _, err := svc.GetObject("b", "stolen.txt"); err == nil {
+		t.Fatal("ciphertext moved to another key should not decrypt")
+	}
+
+	if err := svc.MoveObject("b", "a.txt", "archive", "a.txt"); err != nil {
+		t.Fatalf("MoveObject() error = %v", err)
+	}
+	if data, err := svc.GetObject("archive", "a.txt"); err != nil || string(data) != "secret" {
+		t.Fatalf("GetObject() after move = %q, %v", data, err)
+	}
+	if inner.HeadObject("b", "a.txt") {
+		t.Fatal("source should be deleted after move")
+	}
+}
+
+func TestPresignedUploadsAreRejected(t *testing.T) {
+	svc, _, _ := newService(t)
+
+	if _, err := svc.PreSignPutObject("b", "a.txt"); !errors.Is(err, encryption.ErrPresignUpload) {
+		t.Fatalf("PreSignPutObject() error = %v, want ErrPresignUpload", err)
+	}
+	if urls := svc.BatchPreSignPutObject("b", []string{"a.txt"}, true); len(urls) != 0 {
+		t.Fatalf("BatchPreSignPutObject() = %v, want empty", urls)
+	}
+	if _, err := svc.PreSignPostPolicy("b", "uploads/", storage.PostPolicyConditions{}); !errors.Is(err, encryption.ErrPresignUpload) {
+		t.Fatalf("PreSignPostPolicy() error = %v, want ErrPresignUpload", err)
+	}
+}
+
+func TestPlaintextObjectsPassThrough(t *testing.T) {
+	svc, inner, _ := newService(t)
+	_ = inner.UploadObject("b", "legacy.txt", []byte("plain"))
+
+	if data, err := svc.GetObject("b", "legacy.txt"); err != nil || string(data) != "plain" {
+		t.Fatalf("GetObject() = %q, %v", data, err)
+	}
+	if _, err := svc.PreSignGetObject("b", "legacy.txt"); err != nil {
+		t.Fatalf("PreSignGetObject() error = %v", err)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "keyring.json")
+	keyring, err := encryption.CreateKeyringFile(path, "2026-01")
+	if err != nil {
+		t.Fatalf("CreateKeyringFile() error = %v", err)
+	}
+	if _, err := encryption.CreateKeyringFile(path, "2026-01"); err == nil {
+		t.Fatal("CreateKeyringFile() should not overwrite an existing keyring")
+	}
+
+	inner := memory.NewMemoryService("")
+	svc, _ := encryption.New(inner, keyring)
+	_ = svc.UploadObject("b", "a.txt", []byte("old"))
+
+	loaded, err := encryption.LoadKeyring(path)
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+	if reloaded, err := encryption.New(inner, loaded); err != nil {
+		t.Fatalf("New() error = %v", err)
+	} else if plain, err := reloaded.GetObject("b", "a.txt"); err != nil || string(plain) != "old" {
+		t.Fatalf("GetObject() with loaded keyring = %q, %v", plain, err)
+	}
+
+	other, _ := encryption.NewKeyring("k2", map[string][]byte{"k2": bytes.Repeat([]byte{2}, 32)})
+	rotated, _ := encryption.New(inner, other)
+	if _, err := rotated.GetObject("b", "a.txt"); !errors.Is(err, encryption.ErrUnknownKey) {
+		t.Fatalf("GetObject() with unknown key error = %v, want ErrUnknownKey", err)
+	}
+}
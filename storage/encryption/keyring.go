@@ -0,0 +1,129 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/QingsiLiu/baseComponents/utils"
+)
+
+// KMS 密钥管理服务，负责用主密钥加解密（包装）每个对象的数据密钥
+type KMS interface {
+	// WrapKey 用当前主密钥加密数据密钥，返回密文和主密钥ID
+	WrapKey(ctx context.Context, dataKey []byte) (wrapped []byte, keyID string, err error)
+
+	// UnwrapKey 用 keyID 对应的主密钥解密数据密钥
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// ErrUnknownKey 主密钥ID不在密钥环中
+var ErrUnknownKey = errors.New("encryption: unknown master key")
+
+// keyringFile 密钥环文件格式，密钥为 Base64 编码
+type keyringFile struct {
+	Primary string            `json:"primary"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// Keyring 本地密钥环实现的 KMS。Primary 用于包装新数据密钥，其余密钥只用于解密旧对象，
+// 轮换主密钥时新增密钥并修改 Primary 即可。
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring 创建密钥环，主密钥长度必须为 16、24 或 32 字节
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("%w: primary %q", ErrUnknownKey, primary)
+	}
+	copied := make(map[string][]byte, len(keys))
+	for id, key := range keys {
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("encryption: master key %q must be 16, 24 or 32 bytes", id)
+		}
+		copied[id] = append([]byte(nil), key...)
+	}
+	return &Keyring{primary: primary, keys: copied}, nil
+}
+
+// LoadKeyring 从 JSON 文件加载密钥环：{"primary":"k1","keys":{"k1":"<base64>"}}
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("encryption: parse keyring %s: %w", path, err)
+	}
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption: decode master key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return NewKeyring(file.Primary, keys)
+}
+
+// CreateKeyringFile 生成一个随机 256 位主密钥并写入新的密钥环文件（权限 0600），文件已存在时报错
+func CreateKeyringFile(path, keyID string) (*Keyring, error) {
+	key, err := utils.GenerateRandomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	keyring, err := NewKeyring(keyID, map[string][]byte{keyID: key})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(keyringFile{
+		Primary: keyID,
+		Keys:    map[string]string{keyID: base64.StdEncoding.EncodeToString(key)},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return keyring, file.Close()
+}
+
+// WrapKey 用主密钥加密数据密钥，主密钥ID作为附加数据防止密文被换用到其他密钥
+func (k *Keyring) WrapKey(_ context.Context, dataKey []byte) ([]byte, string, error) {
+	wrapped, err := utils.AESGCMEncrypt(dataKey, k.keys[k.primary], []byte(k.primary))
+	if err != nil {
+		return nil, "", err
+	}
+	return wrapped, k.primary, nil
+}
+
+// UnwrapKey 解密数据密钥
+func (k *Keyring) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	return utils.AESGCMDecrypt(wrapped, key, []byte(keyID))
+}
+
+var _ KMS = (*Keyring)(nil)
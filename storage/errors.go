@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"net/http"
+
+	"github.com/QingsiLiu/baseComponents/errors"
+)
+
+// 存储错误码，按 errors 包的 6 位错误码设计：11 为存储服务，00 为通用模块
+const (
+	CodeNotFound           = 110001
+	CodeAccessDenied       = 110002
+	CodePreconditionFailed = 110003
+	CodeBucketNotFound     = 110004
+	CodeThrottled          = 110005
+)
+
+// 分类后的存储错误，使用 errors.Is 判断。各后端将 SDK 错误包装为 *Error，原始错误仍可通过 errors.As 获取。
+// 分类错误注册为 errors 包的错误码，errors.ParseCoderChain 可取得对应的 HTTP 状态码
+var (
+	ErrNotFound           = newKind(CodeNotFound, http.StatusNotFound, "Object not found", "storage: object not found")
+	ErrAccessDenied       = newKind(CodeAccessDenied, http.StatusForbidden, "Access to the object is denied", "storage: access denied")
+	ErrPreconditionFailed = newKind(CodePreconditionFailed, http.StatusPreconditionFailed, "Object precondition failed", "storage: precondition failed")
+	ErrBucketNotFound     = newKind(CodeBucketNotFound, http.StatusNotFound, "Bucket not found", "storage: bucket not found")
+	ErrThrottled          = newKind(CodeThrottled, http.StatusTooManyRequests, "Storage request throttled, please retry later", "storage: request throttled")
+)
+
+// locales 分类错误对外消息的多语言版本，由 errors.Localize 使用
+//
+//go:embed locales
+var locales embed.FS
+
+func init() {
+	if err := errors.LoadMessages(locales, "locales/*.yaml"); err != nil {
+		panic(err)
+	}
+}
+
+// kindMessages 分类错误的内部消息。withCode 的 Error() 返回对外消息，包装原始错误时使用内部消息
+var kindMessages = map[error]string{}
+
+func newKind(code, status int, ext, message string) error {
+	errors.MustRegister(coder{code: code, status: status, ext: ext})
+	kind := errors.WithCode(code, "%s", message)
+	kindMessages[kind] = message
+	return kind
+}
+
+// coder 实现 errors.Coder
+type coder struct {
+	code   int
+	status int
+	ext    string
+}
+
+func (c coder) Code() int         { return c.code }
+func (c coder) HTTPStatus() int   { return c.status }
+func (c coder) String() string    { return c.ext }
+func (c coder) Reference() string { return "" }
+
+// Error 分类后的存储错误，Kind 为 ErrNotFound 等分类错误，Err 为后端原始错误
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", errorMessage(e.Kind), e.Err)
+}
+
+// Unwrap 同时暴露分类错误和原始错误
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// WrapError 将后端原始错误归为 kind 类，err 为 nil 或 kind 为 nil 时原样返回
+func WrapError(kind, err error) error {
+	if err == nil || kind == nil || errors.Is(err, kind) {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// ClassifyHTTPError 按 HTTP 状态码和服务端错误码归类后端错误，无法归类时原样返回。
+// 各后端在提取出 SDK 错误的状态码和错误码后调用
+func ClassifyHTTPError(err error, status int, code string) error {
+	if err == nil {
+		return nil
+	}
+	switch code {
+	case "NoSuchKey", "NoSuchVersion", "NotFound":
+		return WrapError(ErrNotFound, err)
+	case "NoSuchBucket":
+		return WrapError(ErrBucketNotFound, err)
+	case "AccessDenied", "Forbidden", "AllAccessDisabled":
+		return WrapError(ErrAccessDenied, err)
+	case "PreconditionFailed", "ConditionNotMet":
+		return WrapError(ErrPreconditionFailed, err)
+	case "SlowDown", "Throttling", "ThrottlingException", "RequestLimitExceeded", "TooManyRequests", "ExceedAccountQPSLimit", "ExceedBucketQPSLimit":
+		return WrapError(ErrThrottled, err)
+	}
+	switch status {
+	case http.StatusNotFound:
+		return WrapError(ErrNotFound, err)
+	case http.StatusForbidden:
+		return WrapError(ErrAccessDenied, err)
+	case http.StatusPreconditionFailed, http.StatusNotModified:
+		return WrapError(ErrPreconditionFailed, err)
+	case http.StatusTooManyRequests:
+		return WrapError(ErrThrottled, err)
+	}
+	return err
+}
+
+// IsNotFound 判断错误是否表示对象或存储桶不存在
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrBucketNotFound)
+}
+
+// ExistenceChecker 可区分"对象不存在"和"检查失败"的存储实现。
+// 只有对象不存在时返回 false, nil；存储桶不存在返回 ErrBucketNotFound，避免把配置错误当作对象缺失。
+// 后端无法区分两者时（如 S3 的 HEAD 响应没有错误码）按对象不存在处理
+type ExistenceChecker interface {
+	ObjectExists(ctx context.Context, bucketName, fileKey string) (bool, error)
+}
+
+// ObjectExists 检查对象是否存在。与 HeadObject 不同，网络、权限等错误会原样返回而不是当作不存在。
+// 存储未实现 ExistenceChecker 时通过 GetObjectMetadata 判断
+func ObjectExists(ctx context.Context, svc StorageService, bucketName, fileKey string) (bool, error) {
+	if checker, ok := svc.(ExistenceChecker); ok {
+		return checker.ObjectExists(ctx, bucketName, fileKey)
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if _, err := svc.GetObjectMetadata(bucketName, fileKey); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func errorMessage(kind error) string {
+	if message, ok := kindMessages[kind]; ok {
+		return message
+	}
+	return kind.Error()
+}
@@ -0,0 +1,87 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/QingsiLiu/baseComponents/errors"
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+func TestClassifyHTTPError(t *testing.T) {
+	raw := fmt.Errorf("backend error")
+	cases := []struct {
+		status int
+		code   string
+		want   error
+	}{
+		{http.StatusNotFound, "", storage.ErrNotFound},
+		{http.StatusNotFound, "NoSuchBucket", storage.ErrBucketNotFound},
+		{http.StatusForbidden, "", storage.ErrAccessDenied},
+		{http.StatusPreconditionFailed, "", storage.ErrPreconditionFailed},
+		{http.StatusServiceUnavailable, "SlowDown", storage.ErrThrottled},
+		{http.StatusTooManyRequests, "", storage.ErrThrottled},
+	}
+	for _, tc := range cases {
+		err := storage.ClassifyHTTPError(raw, tc.status, tc.code)
+		if !errors.Is(err, tc.want) || !errors.Is(err, raw) {
+			t.Errorf("ClassifyHTTPError(%d, %q) = %v, want %v wrapping the raw error", tc.status, tc.code, err, tc.want)
+		}
+	}
+	if err := storage.ClassifyHTTPError(raw, http.StatusInternalServerError, ""); err != raw {
+		t.Fatalf("unclassified error = %v, want raw error", err)
+	}
+	if !storage.IsTransientError(storage.ClassifyHTTPError(raw, 0, "SlowDown")) {
+		t.Fatal("throttled errors should be transient")
+	}
+}
+
+func TestStorageErrorsParseToHTTPStatus(t *testing.T) {
+	_, err := memory.NewMemoryService("").GetObject("b", "missing.txt")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetObject() error = %v, want ErrNotFound", err)
+	}
+	coder := errors.ParseCoderChain(fmt.Errorf("load avatar: %w", err))
+	if coder.Code() != storage.CodeNotFound || coder.HTTPStatus() != http.StatusNotFound {
+		t.Fatalf("ParseCoderChain() = %d/%d", coder.Code(), coder.HTTPStatus())
+	}
+
+	coder = errors.ParseCoderChain(storage.WrapError(storage.ErrThrottled, fmt.Errorf("slow down")))
+	if coder.HTTPStatus() != http.StatusTooManyRequests {
+		t.Fatalf("throttled HTTP status = %d", coder.HTTPStatus())
+	}
+}
+
+func TestObjectExists(t *testing.T) {
+	inner := memory.NewMemoryService("")
+	_ = inner.UploadObject("b", "a.txt", []byte("a"))
+
+	// 未实现 ExistenceChecker 的存储通过 GetObjectMetadata 判断
+	for _, svc := range []storage.StorageService{inner, struct{ storage.StorageService }{inner}} {
+		exists, err := storage.ObjectExists(context.Background(), svc, "b", "a.txt")
+		if err != nil || !exists {
+			t.Fatalf("ObjectExists(a.txt) = %v, %v", exists, err)
+		}
+		exists, err = storage.ObjectExists(context.Background(), svc, "b", "missing.txt")
+		if err != nil || exists {
+			t.Fatalf("ObjectExists(missing.txt) = %v, %v", exists, err)
+		}
+	}
+
+	noBucket := storage.Chain(inner, storage.Intercept(func(call *storage.Call, next func() error) error {
+		return storage.WrapError(storage.ErrBucketNotFound, fmt.Errorf("no such bucket"))
+	}))
+	if exists, err := storage.ObjectExists(context.Background(), noBucket, "b", "a.txt"); exists || !errors.Is(err, storage.ErrBucketNotFound) {
+		t.Fatalf("ObjectExists() = %v, %v, want bucket not found error", exists, err)
+	}
+
+	denied := storage.Chain(inner, storage.Intercept(func(call *storage.Call, next func() error) error {
+		return storage.WrapError(storage.ErrAccessDenied, fmt.Errorf("forbidden"))
+	}))
+	if exists, err := storage.ObjectExists(context.Background(), denied, "b", "a.txt"); exists || !errors.Is(err, storage.ErrAccessDenied) {
+		t.Fatalf("ObjectExists() = %v, %v, want access denied error", exists, err)
+	}
+}
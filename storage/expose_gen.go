@@ -0,0 +1,139 @@
+// Code generated by "exposegen"; DO NOT EDIT.
+
+package storage
+
+// exposeCapabilities 判断下游是否实现各可选接口，第 i 项对应 exposeTable 下标的第 i 位
+var exposeCapabilities = [...]func(StorageService) bool{
+	func(s StorageService) bool {
+		_, ok := s.(OptionsUploader)
+		return ok
+	},
+	func(s StorageService) bool {
+		_, ok := s.(LifecycleManager)
+		return ok
+	},
+	func(s StorageService) bool {
+		_, ok := s.(ObjectTagger)
+		return ok
+	},
+	func(s StorageService) bool {
+		_, ok := s.(VersionedStorage)
+		return ok
+	},
+}
+
+// exposeTable 按下游实现的可选接口组合返回值，下标为 exposeCapabilities 的位掩码
+var exposeTable = [...]func(w *interceptor) StorageService{
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+		}{w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			OptionsUploader
+		}{w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			LifecycleManager
+		}{w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			OptionsUploader
+			LifecycleManager
+		}{w, w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			ObjectTagger
+		}{w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			OptionsUploader
+			ObjectTagger
+		}{w, w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			LifecycleManager
+			ObjectTagger
+		}{w, w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			OptionsUploader
+			LifecycleManager
+			ObjectTagger
+		}{w, w, w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			VersionedStorage
+		}{w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			OptionsUploader
+			VersionedStorage
+		}{w, w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			LifecycleManager
+			VersionedStorage
+		}{w, w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			OptionsUploader
+			LifecycleManager
+			VersionedStorage
+		}{w, w, w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			ObjectTagger
+			VersionedStorage
+		}{w, w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			OptionsUploader
+			ObjectTagger
+			VersionedStorage
+		}{w, w, w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			LifecycleManager
+			ObjectTagger
+			VersionedStorage
+		}{w, w, w, w}
+	},
+	func(w *interceptor) StorageService {
+		return struct {
+			exposed
+			OptionsUploader
+			LifecycleManager
+			ObjectTagger
+			VersionedStorage
+		}{w, w, w, w, w}
+	},
+}
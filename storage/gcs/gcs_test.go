@@ -0,0 +1,93 @@
+package gcs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+)
+
+// newTestClient 使用临时生成的服务账号密钥创建客户端，签名不需要网络请求
+func newTestClient(t *testing.T) *GCSClient {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	credentials, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "polai-test",
+		"client_email": "uploader@polai-test.iam.gserviceaccount.com",
+		"private_key":  string(privateKey),
+		"token_uri":    "https://oauth2.googleapis.com/token",
+	})
+	if err != nil {
+		t.Fatalf("marshal credentials failed: %v", err)
+	}
+
+	ctx := context.Background()
+	client, err := gcs.NewClient(ctx, option.WithCredentialsJSON(credentials))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return &GCSClient{client: client, projectID: "polai-test", ctx: ctx}
+}
+
+func TestPreSignPostPolicyRestrictsKeyPrefixSizeAndContentType(t *testing.T) {
+	client := newTestClient(t)
+
+	policy, err := client.PreSignPostPolicy("polai-private-media-prod", "uploads/user-1/", storage.PostPolicyConditions{
+		ContentLengthMin:  1,
+		ContentLengthMax:  10 << 20,
+		ContentTypePrefix: "image/",
+		Expires:           10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("PreSignPostPolicy failed: %v", err)
+	}
+
+	if !strings.HasSuffix(policy.URL, "/polai-private-media-prod/") {
+		t.Fatalf("URL = %q, want bucket path", policy.URL)
+	}
+	if got := policy.Fields["key"]; got != "uploads/user-1/${filename}" {
+		t.Fatalf("key field = %q, want prefix with filename placeholder", got)
+	}
+	for _, field := range []string{"policy", "x-goog-signature", "x-goog-credential", "x-goog-algorithm", "x-goog-date"} {
+		if policy.Fields[field] == "" {
+			t.Fatalf("field %s is missing", field)
+		}
+	}
+
+	document, err := base64.StdEncoding.DecodeString(policy.Fields["policy"])
+	if err != nil {
+		t.Fatalf("policy is not base64: %v", err)
+	}
+	for _, condition := range []string{
+		`["starts-with","$key","uploads/user-1/"]`,
+		`["content-length-range",1,10485760]`,
+		`["starts-with","$Content-Type","image/"]`,
+	} {
+		if !strings.Contains(string(document), condition) {
+			t.Fatalf("policy document %s does not contain %s", document, condition)
+		}
+	}
+	// 精确的 key 条件会让客户端无法使用前缀下的其他键
+	if strings.Contains(string(document), `{"key":`) {
+		t.Fatalf("policy document %s pins the exact key", document)
+	}
+}
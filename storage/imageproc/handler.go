@@ -0,0 +1,64 @@
+package imageproc
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+)
+
+// Handler 按需返回图片变体，请求路径为 /<预设>/<原始对象键>，可配合 http.StripPrefix 挂载。
+// 变体首次请求时生成并写回存储桶，之后直接读取缓存。
+func (p *Pipeline) Handler(bucketName string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		presetName, fileKey, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if !ok || presetName == "" || fileKey == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if _, ok := p.presets[presetName]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		variant, data, err := p.Variant(bucketName, fileKey, presetName)
+		if err != nil {
+			status := errorStatus(err)
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		sum := md5.Sum(data)
+		w.Header().Set("Content-Type", variant.ContentType)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		if p.opts.CacheControl != "" {
+			w.Header().Set("Cache-Control", p.opts.CacheControl)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	})
+}
+
+// errorStatus 将处理错误映射为 HTTP 状态码
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUnknownPreset), errors.Is(err, ErrVariantSource), storage.IsNotFound(err):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUnsupportedImage):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,323 @@
+// Package imageproc 纯 Go 图片处理：按预设缩放、裁剪、转换格式并去除 EXIF，
+// 以及在存储上传和读取时生成、缓存图片变体。
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // 注册 GIF 解码器
+	"image/jpeg"
+	"image/png"
+	"math"
+)
+
+// Format 输出格式
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+)
+
+// Fit 缩放方式
+type Fit string
+
+const (
+	// FitInside 等比缩放到宽高范围内，不裁剪
+	FitInside Fit = "inside"
+	// FitCover 等比缩放铺满宽高，居中裁剪多余部分
+	FitCover Fit = "cover"
+)
+
+const (
+	// DefaultQuality JPEG 默认质量
+	DefaultQuality = 85
+	// DefaultMaxPixels 允许解码的最大像素数，防止超大图片耗尽内存
+	DefaultMaxPixels = 50_000_000
+)
+
+var (
+	// ErrUnsupportedImage 内容不是可解码的 JPEG/PNG/GIF 图片
+	ErrUnsupportedImage = errors.New("imageproc: unsupported image")
+	// ErrImageTooLarge 图片像素数超过限制
+	ErrImageTooLarge = errors.New("imageproc: image too large")
+)
+
+// Preset 命名的图片变体预设
+type Preset struct {
+	Name    string `json:"name"`
+	Width   int    `json:"width"`   // 目标宽度，0 表示按高度等比计算
+	Height  int    `json:"height"`  // 目标高度，0 表示按宽度等比计算
+	Fit     Fit    `json:"fit"`     // 宽高都设置时生效，默认 FitInside
+	Format  Format `json:"format"`  // 输出格式，为空时 JPEG 保持 JPEG，其余输出 PNG
+	Quality int    `json:"quality"` // JPEG 质量 1-100，默认 DefaultQuality
+	Enlarge bool   `json:"enlarge"` // 是否允许放大小图
+}
+
+// Validate 校验预设参数
+func (p Preset) Validate() error {
+	if p.Name == "" {
+		return errors.New("imageproc: preset name is required")
+	}
+	if p.Width < 0 || p.Height < 0 {
+		return fmt.Errorf("imageproc: preset %s has negative size", p.Name)
+	}
+	switch p.Fit {
+	case "", FitInside, FitCover:
+	default:
+		return fmt.Errorf("imageproc: preset %s has unknown fit %q", p.Name, p.Fit)
+	}
+	switch p.Format {
+	case "", FormatJPEG, FormatPNG:
+	default:
+		return fmt.Errorf("imageproc: preset %s has unknown format %q", p.Name, p.Format)
+	}
+	if p.Quality < 0 || p.Quality > 100 {
+		return fmt.Errorf("imageproc: preset %s has invalid quality %d", p.Name, p.Quality)
+	}
+	return nil
+}
+
+// Image 处理结果
+type Image struct {
+	Data        []byte
+	Format      Format
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Process 按预设处理图片，JPEG 会先按 EXIF 方向旋转，输出不包含任何 EXIF 信息。
+// GIF 只处理第一帧。
+func Process(data []byte, preset Preset) (*Image, error) {
+	return process(data, preset, DefaultMaxPixels)
+}
+
+// decodable 判断数据是否为已注册解码器的图片格式（JPEG、PNG、GIF）。
+// 格式可识别但内容损坏时同样返回 true，由处理时报告错误
+func decodable(data []byte) bool {
+	_, _, err := image.DecodeConfig(bytes.NewReader(data))
+	return !errors.Is(err, image.ErrFormat)
+}
+
+func process(data []byte, preset Preset, maxPixels int) (*Image, error) {
+	if err := preset.Validate(); err != nil {
+		return nil, err
+	}
+
+	config, sourceFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	if maxPixels > 0 && config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, config.Width, config.Height)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	src := toRGBA(decoded)
+	if sourceFormat == "jpeg" {
+		src = orient(src, jpegOrientation(data))
+	}
+	dst := resize(src, preset)
+
+	format := preset.Format
+	if format == "" {
+		format = FormatPNG
+		if sourceFormat == "jpeg" {
+			format = FormatJPEG
+		}
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatJPEG:
+		quality := preset.Quality
+		if quality == 0 {
+			quality = DefaultQuality
+		}
+		err = jpeg.Encode(&buf, flatten(dst), &jpeg.Options{Quality: quality})
+	default:
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := dst.Bounds()
+	return &Image{
+		Data:        buf.Bytes(),
+		Format:      format,
+		ContentType: format.ContentType(),
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}, nil
+}
+
+// ContentType 格式对应的内容类型
+func (f Format) ContentType() string {
+	if f == FormatJPEG {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// Extension 格式对应的文件扩展名
+func (f Format) Extension() string {
+	if f == FormatJPEG {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// ===== 缩放与裁剪 =====
+
+// resize 按预设计算目标尺寸，FitCover 先居中裁剪到目标宽高比再缩放
+func resize(src *image.RGBA, preset Preset) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw == 0 || sh == 0 || (preset.Width == 0 && preset.Height == 0) {
+		return src
+	}
+
+	width, height := float64(preset.Width), float64(preset.Height)
+	scaleX, scaleY := width/float64(sw), height/float64(sh)
+	switch {
+	case preset.Width == 0:
+		scaleX = scaleY
+	case preset.Height == 0:
+		scaleY = scaleX
+	}
+
+	if preset.Fit == FitCover && preset.Width > 0 && preset.Height > 0 {
+		// 先按目标宽高比居中裁剪，再缩放到目标尺寸
+		cropW := min(sw, max(1, int(math.Round(float64(sh)*width/height))))
+		cropH := min(sh, max(1, int(math.Round(float64(sw)*height/width))))
+		scale := width / float64(cropW)
+		if scale > 1 && !preset.Enlarge {
+			scale = 1
+		}
+		x0, y0 := (sw-cropW)/2, (sh-cropH)/2
+		cropped := src.SubImage(image.Rect(x0, y0, x0+cropW, y0+cropH)).(*image.RGBA)
+		return resample(cropped, max(1, int(math.Round(float64(cropW)*scale))), max(1, int(math.Round(float64(cropH)*scale))))
+	}
+
+	scale := math.Min(scaleX, scaleY)
+	if scale >= 1 && !preset.Enlarge {
+		return src
+	}
+	return resample(src, max(1, int(math.Round(float64(sw)*scale))), max(1, int(math.Round(float64(sh)*scale))))
+}
+
+// contribution 目标像素在源图一个轴上的采样范围和权重
+type contribution struct {
+	start   int
+	weights []float64
+}
+
+// contributions 计算三角滤波的采样权重，缩小时滤波半径随比例放大，相当于区域平均
+func contributions(srcLen, dstLen int) []contribution {
+	scale := float64(srcLen) / float64(dstLen)
+	support := math.Max(scale, 1)
+
+	result := make([]contribution, dstLen)
+	for i := range result {
+		center := (float64(i)+0.5)*scale - 0.5
+		lo := max(0, int(math.Floor(center-support)))
+		hi := min(srcLen-1, int(math.Ceil(center+support)))
+
+		weights := make([]float64, 0, hi-lo+1)
+		sum := 0.0
+		for j := lo; j <= hi; j++ {
+			w := math.Max(0, 1-math.Abs(float64(j)-center)/support)
+			weights = append(weights, w)
+			sum += w
+		}
+		if sum == 0 {
+			// 放大时目标像素可能正好落在源像素中心之外，退化为最近邻
+			nearest := min(srcLen-1, max(0, int(math.Round(center))))
+			result[i] = contribution{start: nearest, weights: []float64{1}}
+			continue
+		}
+		for j := range weights {
+			weights[j] /= sum
+		}
+		result[i] = contribution{start: lo, weights: weights}
+	}
+	return result
+}
+
+// resample 先水平后垂直两次一维重采样，在预乘 alpha 的 RGBA 上计算以避免透明边缘发黑
+func resample(src *image.RGBA, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	if sw == width && sh == height {
+		return src
+	}
+
+	horizontal := image.NewRGBA(image.Rect(0, 0, width, sh))
+	for x, c := range contributions(sw, width) {
+		for y := 0; y < sh; y++ {
+			var acc [4]float64
+			row := src.PixOffset(bounds.Min.X+c.start, bounds.Min.Y+y)
+			for k, w := range c.weights {
+				pixel := src.Pix[row+4*k : row+4*k+4]
+				for ch := range acc {
+					acc[ch] += float64(pixel[ch]) * w
+				}
+			}
+			setPixel(horizontal, x, y, acc)
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, c := range contributions(sh, height) {
+		for x := 0; x < width; x++ {
+			var acc [4]float64
+			for k, w := range c.weights {
+				offset := horizontal.PixOffset(x, c.start+k)
+				pixel := horizontal.Pix[offset : offset+4]
+				for ch := range acc {
+					acc[ch] += float64(pixel[ch]) * w
+				}
+			}
+			setPixel(dst, x, y, acc)
+		}
+	}
+	return dst
+}
+
+func setPixel(img *image.RGBA, x, y int, acc [4]float64) {
+	offset := img.PixOffset(x, y)
+	for ch, value := range acc {
+		img.Pix[offset+ch] = uint8(math.Max(0, math.Min(255, math.Round(value))))
+	}
+}
+
+// ===== 辅助方法 =====
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// flatten 将透明像素合成到白色背景上，JPEG 不支持透明通道
+func flatten(img *image.RGBA) image.Image {
+	if img.Opaque() {
+		return img
+	}
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flat
+}
@@ -0,0 +1,141 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("jpeg.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// halves 左半红右半蓝的测试图片
+func halves(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// withOrientation 在 JPEG 的 SOI 之后插入只包含方向标签的 EXIF 段
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], exifOrientationTag)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	header := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(segment)+2))
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, header...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestProcessResizeInsideKeepsAspect(t *testing.T) {
+	img, err := Process(encodePNG(t, halves(400, 200)), Preset{Name: "thumb", Width: 100, Height: 100})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if img.Width != 100 || img.Height != 50 || img.ContentType != "image/png" {
+		t.Fatalf("Process() = %dx%d %s", img.Width, img.Height, img.ContentType)
+	}
+
+	decoded, err := png.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	if r, _, b, _ := decoded.At(10, 25).RGBA(); r>>8 != 255 || b != 0 {
+		t.Fatalf("left side should stay red, got r=%d b=%d", r>>8, b>>8)
+	}
+	if r, _, b, _ := decoded.At(90, 25).RGBA(); b>>8 != 255 || r != 0 {
+		t.Fatalf("right side should stay blue, got r=%d b=%d", r>>8, b>>8)
+	}
+}
+
+func TestProcessCoverCropsAndConverts(t *testing.T) {
+	img, err := Process(encodePNG(t, halves(400, 200)), Preset{Name: "square", Width: 50, Height: 50, Fit: FitCover, Format: FormatJPEG})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if img.Width != 50 || img.Height != 50 || img.Format != FormatJPEG {
+		t.Fatalf("Process() = %dx%d %s", img.Width, img.Height, img.Format)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(img.Data)); err != nil {
+		t.Fatalf("output is not a JPEG: %v", err)
+	}
+
+	small, _ := Process(encodePNG(t, halves(40, 20)), Preset{Name: "square", Width: 50, Height: 50, Fit: FitCover})
+	if small.Width != 20 || small.Height != 20 {
+		t.Fatalf("cover without enlarge = %dx%d, want 20x20", small.Width, small.Height)
+	}
+}
+
+func TestProcessAppliesAndStripsEXIFOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	_ = jpeg.Encode(&buf, halves(40, 20), &jpeg.Options{Quality: 95})
+	data := withOrientation(buf.Bytes(), 6)
+	if jpegOrientation(data) != 6 {
+		t.Fatalf("jpegOrientation() = %d, want 6", jpegOrientation(data))
+	}
+
+	img, err := Process(data, Preset{Name: "orig"})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if img.Width != 20 || img.Height != 40 {
+		t.Fatalf("rotated size = %dx%d, want 20x40", img.Width, img.Height)
+	}
+	if jpegOrientation(img.Data) != 1 || bytes.Contains(img.Data, []byte("Exif")) {
+		t.Fatal("output should not contain EXIF")
+	}
+	// 顺时针旋转 90° 后，原来左侧的红色在上方
+	decoded, _ := jpeg.Decode(bytes.NewReader(img.Data))
+	if r, _, b, _ := decoded.At(10, 5).RGBA(); r < b {
+		t.Fatalf("top should be red after rotation, got r=%d b=%d", r>>8, b>>8)
+	}
+}
+
+func TestProcessRejectsInvalidInput(t *testing.T) {
+	if _, err := Process([]byte("not an image"), Preset{Name: "x"}); !errors.Is(err, ErrUnsupportedImage) {
+		t.Fatalf("Process(text) error = %v, want ErrUnsupportedImage", err)
+	}
+	if _, err := process(encodePNG(t, halves(100, 100)), Preset{Name: "x"}, 100); !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("process(too large) error = %v, want ErrImageTooLarge", err)
+	}
+	if _, err := Process(nil, Preset{Name: "x", Fit: "fill"}); err == nil {
+		t.Fatal("invalid preset should be rejected")
+	}
+}
@@ -0,0 +1,284 @@
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+)
+
+// DefaultVariantPrefix 默认变体键前缀
+const DefaultVariantPrefix = "_variants/"
+
+var (
+	// ErrUnknownPreset 预设不存在
+	ErrUnknownPreset = errors.New("imageproc: unknown preset")
+	// ErrVariantSource 对象键位于变体键前缀下，不能再生成变体
+	ErrVariantSource = errors.New("imageproc: cannot generate variants of a variant")
+)
+
+// Options 变体管线选项
+type Options struct {
+	Presets      []Preset
+	OnUpload     []string                                   // 上传时立即生成的预设，其余预设在首次读取时生成
+	VariantKey   func(fileKey string, preset Preset) string // 变体对象键，默认 _variants/<预设>/<原始键><扩展名>
+	CacheControl string                                     // 变体对象和 HTTP 响应的 Cache-Control
+	MaxPixels    int                                        // 允许处理的最大像素数，默认 DefaultMaxPixels
+}
+
+// Variant 已生成的变体
+type Variant struct {
+	Preset      string `json:"preset"`
+	Key         string `json:"key"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width,omitempty"` // 从缓存读取时为 0
+	Height      int    `json:"height,omitempty"`
+}
+
+// Pipeline 在上传时按预设生成图片变体并保存到派生键，读取时按需生成并回写缓存
+type Pipeline struct {
+	svc      storage.StorageService
+	opts     Options
+	presets  map[string]Preset
+	prefixes []string // 各预设变体键中原始键之前的部分
+	group    singleflight.Group
+}
+
+// New 创建变体管线
+func New(svc storage.StorageService, opts Options) (*Pipeline, error) {
+	if svc == nil {
+		return nil, errors.New("imageproc: storage service is required")
+	}
+
+	presets := make(map[string]Preset, len(opts.Presets))
+	for _, preset := range opts.Presets {
+		if err := preset.Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := presets[preset.Name]; ok {
+			return nil, fmt.Errorf("imageproc: duplicate preset %s", preset.Name)
+		}
+		presets[preset.Name] = preset
+	}
+	for _, name := range opts.OnUpload {
+		if _, ok := presets[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPreset, name)
+		}
+	}
+	if opts.VariantKey == nil {
+		opts.VariantKey = defaultVariantKey
+	}
+	if opts.MaxPixels <= 0 {
+		opts.MaxPixels = DefaultMaxPixels
+	}
+	return &Pipeline{svc: svc, opts: opts, presets: presets, prefixes: variantPrefixes(opts.VariantKey, presets)}, nil
+}
+
+// variantPrefixes 用占位键调用变体键函数，推导各预设变体键中原始键之前的前缀。
+// 变体键不以固定前缀开头的自定义布局无法识别变体，Variant 不做检查
+func variantPrefixes(variantKey func(string, Preset) string, presets map[string]Preset) []string {
+	const placeholder = "\x00"
+	var prefixes []string
+	for _, preset := range presets {
+		key := variantKey(placeholder, preset)
+		if i := strings.Index(key, placeholder); i > 0 {
+			prefixes = append(prefixes, key[:i])
+		}
+	}
+	return prefixes
+}
+
+// isVariant 判断对象键是否位于某个预设的变体键前缀下
+func (p *Pipeline) isVariant(fileKey string) bool {
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(fileKey, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// VariantKey 返回原始对象在指定预设下的变体键
+func (p *Pipeline) VariantKey(fileKey, presetName string) (string, error) {
+	preset, ok := p.presets[presetName]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownPreset, presetName)
+	}
+	return p.opts.VariantKey(fileKey, preset), nil
+}
+
+// Upload 上传原始对象，是可解码的图片（JPEG、PNG、GIF）时生成 OnUpload 预设的变体，
+// 并删除其余预设可能过期的缓存变体。WebP、SVG 等无法解码的图片只删除旧变体
+func (p *Pipeline) Upload(bucketName, fileKey string, data []byte, options storage.UploadObjectOptions) ([]Variant, error) {
+	if uploader, ok := p.svc.(storage.OptionsUploader); ok {
+		if err := uploader.UploadObjectWithOptions(bucketName, fileKey, data, options); err != nil {
+			return nil, err
+		}
+	} else if err := p.svc.UploadObject(bucketName, fileKey, data); err != nil {
+		return nil, err
+	}
+
+	contentType := options.ContentType
+	if contentType == "" {
+		contentType = storage.DetectContentType(fileKey, data)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, nil
+	}
+	if !decodable(data) {
+		return nil, p.invalidate(bucketName, fileKey, nil)
+	}
+
+	variants, err := p.generate(bucketName, fileKey, data, p.opts.OnUpload)
+	if err != nil {
+		return variants, err
+	}
+	return variants, p.invalidate(bucketName, fileKey, p.opts.OnUpload)
+}
+
+// Generate 为已存在的对象生成指定预设的变体，未指定时生成 OnUpload 预设
+func (p *Pipeline) Generate(bucketName, fileKey string, presetNames ...string) ([]Variant, error) {
+	if len(presetNames) == 0 {
+		presetNames = p.opts.OnUpload
+	}
+	for _, name := range presetNames {
+		if _, ok := p.presets[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPreset, name)
+		}
+	}
+
+	data, err := p.svc.GetObject(bucketName, fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return p.generate(bucketName, fileKey, data, presetNames)
+}
+
+// Variant 读取变体，缓存不存在时从原始对象生成并写回存储。并发请求同一变体只处理一次
+func (p *Pipeline) Variant(bucketName, fileKey, presetName string) (*Variant, []byte, error) {
+	preset, ok := p.presets[presetName]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownPreset, presetName)
+	}
+	if p.isVariant(fileKey) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrVariantSource, fileKey)
+	}
+	variantKey := p.opts.VariantKey(fileKey, preset)
+
+	type result struct {
+		variant *Variant
+		data    []byte
+	}
+	value, err, _ := p.group.Do(bucketName+"/"+variantKey, func() (any, error) {
+		if p.svc.HeadObject(bucketName, variantKey) {
+			data, err := p.svc.GetObject(bucketName, variantKey)
+			if err == nil {
+				return result{variant: &Variant{
+					Preset:      preset.Name,
+					Key:         variantKey,
+					ContentType: storage.DetectContentType(variantKey, data),
+					Size:        int64(len(data)),
+				}, data: data}, nil
+			}
+		}
+
+		source, err := p.svc.GetObject(bucketName, fileKey)
+		if err != nil {
+			return nil, err
+		}
+		variant, data, err := p.store(bucketName, fileKey, source, preset)
+		if err != nil {
+			return nil, err
+		}
+		return result{variant: variant, data: data}, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	r := value.(result)
+	return r.variant, r.data, nil
+}
+
+func (p *Pipeline) generate(bucketName, fileKey string, data []byte, presetNames []string) ([]Variant, error) {
+	variants := make([]Variant, 0, len(presetNames))
+	for _, name := range presetNames {
+		variant, _, err := p.store(bucketName, fileKey, data, p.presets[name])
+		if err != nil {
+			return variants, fmt.Errorf("imageproc: generate %s for %s: %w", name, fileKey, err)
+		}
+		variants = append(variants, *variant)
+	}
+	return variants, nil
+}
+
+// store 处理图片并保存到变体键
+func (p *Pipeline) store(bucketName, fileKey string, source []byte, preset Preset) (*Variant, []byte, error) {
+	preset.Format = outputFormat(fileKey, preset)
+	img, err := process(source, preset, p.opts.MaxPixels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	variantKey := p.opts.VariantKey(fileKey, preset)
+	if uploader, ok := p.svc.(storage.OptionsUploader); ok {
+		err = uploader.UploadObjectStreamWithOptions(bucketName, variantKey, bytes.NewReader(img.Data), storage.UploadObjectOptions{
+			ContentType:  img.ContentType,
+			CacheControl: p.opts.CacheControl,
+		})
+	} else {
+		err = p.svc.UploadObject(bucketName, variantKey, img.Data)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Variant{
+		Preset:      preset.Name,
+		Key:         variantKey,
+		ContentType: img.ContentType,
+		Size:        int64(len(img.Data)),
+		Width:       img.Width,
+		Height:      img.Height,
+	}, img.Data, nil
+}
+
+// invalidate 删除除 keep 以外预设的缓存变体，原始对象被覆盖后它们已过期
+func (p *Pipeline) invalidate(bucketName, fileKey string, keep []string) error {
+	kept := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		kept[name] = true
+	}
+
+	var keys []string
+	for name, preset := range p.presets {
+		if !kept[name] {
+			keys = append(keys, p.opts.VariantKey(fileKey, preset))
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := p.svc.DeleteObjects(bucketName, keys)
+	return err
+}
+
+func defaultVariantKey(fileKey string, preset Preset) string {
+	return DefaultVariantPrefix + preset.Name + "/" + fileKey + outputFormat(fileKey, preset).Extension()
+}
+
+// outputFormat 变体的输出格式。预设未指定时按原始键的扩展名决定（.jpg/.jpeg 为 JPEG，其余为 PNG），
+// 变体键在读取原图之前就要确定，编码时使用同一结果，保证扩展名与内容一致
+func outputFormat(fileKey string, preset Preset) Format {
+	if preset.Format != "" {
+		return preset.Format
+	}
+	if ext := strings.ToLower(fileKey[strings.LastIndex(fileKey, ".")+1:]); ext == "jpg" || ext == "jpeg" {
+		return FormatJPEG
+	}
+	return FormatPNG
+}
@@ -0,0 +1,144 @@
+package imageproc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+func newPipeline(t *testing.T) (*Pipeline, *memory.MemoryService) {
+	t.Helper()
+	svc := memory.NewMemoryService("")
+	pipeline, err := New(svc, Options{
+		Presets: []Preset{
+			{Name: "thumb", Width: 64, Height: 64, Fit: FitCover, Format: FormatJPEG},
+			{Name: "small", Width: 100},
+		},
+		OnUpload:     []string{"thumb"},
+		CacheControl: "public, max-age=86400",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return pipeline, svc
+}
+
+func TestPipelineUploadGeneratesAndInvalidates(t *testing.T) {
+	pipeline, svc := newPipeline(t)
+	source := encodePNG(t, halves(300, 200))
+
+	_ = svc.UploadObject("b", "_variants/small/photos/a.png.png", []byte("stale"))
+	variants, err := pipeline.Upload("b", "photos/a.png", source, storage.UploadObjectOptions{})
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if len(variants) != 1 || variants[0].Key != "_variants/thumb/photos/a.png.jpg" || variants[0].Width != 64 {
+		t.Fatalf("variants = %+v", variants)
+	}
+	if metadata, _ := svc.GetObjectMetadata("b", variants[0].Key); metadata.ContentType != "image/jpeg" {
+		t.Fatalf("variant content type = %s", metadata.ContentType)
+	}
+	if svc.HeadObject("b", "_variants/small/photos/a.png.png") {
+		t.Fatal("stale variant should be deleted on upload")
+	}
+
+	// 扩展名为 .png 的 JPEG 按键名输出 PNG，内容与变体键的扩展名一致
+	_ = svc.UploadObject("b", "photos/b.png", encodeJPEG(t, halves(300, 200)))
+	variant, data, err := pipeline.Variant("b", "photos/b.png", "small")
+	if err != nil {
+		t.Fatalf("Variant() error = %v", err)
+	}
+	if variant.Key != "_variants/small/photos/b.png.png" || variant.ContentType != "image/png" || storage.DetectContentType("", data) != "image/png" {
+		t.Fatalf("variant = %+v, content = %s", variant, storage.DetectContentType("", data))
+	}
+
+	if variants, err := pipeline.Upload("b", "notes.txt", []byte("hello"), storage.UploadObjectOptions{}); err != nil || len(variants) != 0 {
+		t.Fatalf("Upload(text) = %+v, %v", variants, err)
+	}
+}
+
+func TestPipelineSkipsUndecodableImages(t *testing.T) {
+	pipeline, svc := newPipeline(t)
+	_ = svc.UploadObject("b", "_variants/thumb/photos/a.webp.jpg", []byte("stale"))
+
+	webp := []byte("RIFF\x24\x00\x00\x00WEBPVP8 ")
+	variants, err := pipeline.Upload("b", "photos/a.webp", webp, storage.UploadObjectOptions{ContentType: "image/webp"})
+	if err != nil || len(variants) != 0 {
+		t.Fatalf("Upload(webp) = %+v, %v", variants, err)
+	}
+	if !svc.HeadObject("b", "photos/a.webp") {
+		t.Fatal("original should be stored")
+	}
+	if svc.HeadObject("b", "_variants/thumb/photos/a.webp.jpg") {
+		t.Fatal("stale variant should be deleted on upload")
+	}
+}
+
+func TestPipelineRejectsVariantsOfCustomLayout(t *testing.T) {
+	svc := memory.NewMemoryService("")
+	pipeline, err := New(svc, Options{
+		Presets: []Preset{{Name: "thumb", Width: 64, Format: FormatPNG}},
+		VariantKey: func(fileKey string, preset Preset) string {
+			return "thumbs/" + preset.Name + "/" + fileKey
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	_ = svc.UploadObject("b", "photos/a.png", encodePNG(t, halves(300, 200)))
+
+	variant, _, err := pipeline.Variant("b", "photos/a.png", "thumb")
+	if err != nil || variant.Key != "thumbs/thumb/photos/a.png" {
+		t.Fatalf("Variant() = %+v, %v", variant, err)
+	}
+	if _, _, err := pipeline.Variant("b", variant.Key, "thumb"); !errors.Is(err, ErrVariantSource) {
+		t.Fatalf("Variant(variant) error = %v, want ErrVariantSource", err)
+	}
+}
+
+func TestHandlerServesAndCachesVariants(t *testing.T) {
+	pipeline, svc := newPipeline(t)
+	_ = svc.UploadObject("b", "photos/a.png", encodePNG(t, halves(300, 200)))
+	_ = svc.UploadObject("b", "notes.txt", []byte("hello"))
+
+	server := httptest.NewServer(http.StripPrefix("/img", pipeline.Handler("b")))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/img/small/photos/a.png")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" || resp.Header.Get("Cache-Control") == "" {
+		t.Fatalf("response = %d %v", resp.StatusCode, resp.Header)
+	}
+	if !svc.HeadObject("b", "_variants/small/photos/a.png.png") {
+		t.Fatal("variant should be cached back into the bucket")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/img/small/photos/a.png", nil)
+	req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("conditional GET = %v, %v", resp, err)
+	}
+
+	for path, want := range map[string]int{
+		"/img/large/photos/a.png":                     http.StatusNotFound,
+		"/img/small/photos/missing":                   http.StatusNotFound,
+		"/img/small/notes.txt":                        http.StatusUnsupportedMediaType,
+		"/img/small/_variants/small/photos/a.png.png": http.StatusNotFound,
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("GET %s = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// exposegen 生成 storage 中间件按下游可选接口组合返回值的代码。
+//
+// 在 storage 包中通过 go generate 调用：
+//
+//	//go:generate go run ./internal/exposegen -output expose_gen.go
+//
+// 新增需要按下游是否实现来暴露的可选接口时，追加到 capabilities 后重新生成。
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+)
+
+// capabilities 只在下游实现时才暴露的可选接口，顺序决定组合下标中的位
+var capabilities = []string{
+	"OptionsUploader",
+	"LifecycleManager",
+	"ObjectTagger",
+	"VersionedStorage",
+}
+
+func main() {
+	output := flag.String("output", "expose_gen.go", "生成的代码文件")
+	flag.Parse()
+
+	src, err := generate()
+	if err != nil {
+		log.Fatalf("生成失败: %v", err)
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatalf("写入 %s 失败: %v", *output, err)
+	}
+}
+
+func generate() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by \"exposegen\"; DO NOT EDIT.\n\npackage storage\n\n")
+
+	buf.WriteString("// exposeCapabilities 判断下游是否实现各可选接口，第 i 项对应 exposeTable 下标的第 i 位\n")
+	buf.WriteString("var exposeCapabilities = [...]func(StorageService) bool{\n")
+	for _, name := range capabilities {
+		fmt.Fprintf(&buf, "func(s StorageService) bool {\n_, ok := s.(%s)\nreturn ok\n},\n", name)
+	}
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("// exposeTable 按下游实现的可选接口组合返回值，下标为 exposeCapabilities 的位掩码\n")
+	buf.WriteString("var exposeTable = [...]func(w *interceptor) StorageService{\n")
+	for mask := 0; mask < 1<<len(capabilities); mask++ {
+		// exposed 包含 StorageService 和始终暴露的可选接口
+		fields := []string{"exposed"}
+		for i, name := range capabilities {
+			if mask&(1<<i) != 0 {
+				fields = append(fields, name)
+			}
+		}
+		values := strings.TrimSuffix(strings.Repeat("w, ", len(fields)), ", ")
+		fmt.Fprintf(&buf, "func(w *interceptor) StorageService {\nreturn struct {\n%s\n}{%s}\n},\n", strings.Join(fields, "\n"), values)
+	}
+	buf.WriteString("}\n")
+
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,52 @@
+package storage
+
+import "errors"
+
+// 常用存储类别，各后端取值不同时以后端为准（如 GCS 的 NEARLINE、TOS 的 IA）
+const (
+	StorageClassStandard = "STANDARD"
+	StorageClassIA       = "STANDARD_IA"
+	StorageClassGlacier  = "GLACIER"
+)
+
+// LifecycleTransition 存储类别转换动作
+type LifecycleTransition struct {
+	Days         int    `json:"days"`         // 对象创建多少天后转换
+	StorageClass string `json:"storageClass"` // 目标存储类别
+}
+
+// LifecycleRule 存储桶生命周期规则
+type LifecycleRule struct {
+	ID                                 string                `json:"id"`                                 // 规则ID
+	Prefix                             string                `json:"prefix"`                             // 生效的对象前缀，空表示整个存储桶
+	Enabled                            bool                  `json:"enabled"`                            // 是否启用
+	ExpirationDays                     int                   `json:"expirationDays"`                     // 对象创建多少天后删除，0 表示不过期
+	Transitions                        []LifecycleTransition `json:"transitions"`                        // 存储类别转换
+	AbortIncompleteMultipartUploadDays int                   `json:"abortIncompleteMultipartUploadDays"` // 未完成的分片上传多少天后清理，0 表示不清理
+}
+
+// LifecycleManager 支持存储桶原生生命周期规则的存储实现
+type LifecycleManager interface {
+	// GetBucketLifecycle 获取存储桶生命周期规则，未配置时返回空列表
+	GetBucketLifecycle(bucketName string) ([]LifecycleRule, error)
+
+	// PutBucketLifecycle 覆盖设置存储桶生命周期规则
+	PutBucketLifecycle(bucketName string, rules []LifecycleRule) error
+
+	// DeleteBucketLifecycle 删除存储桶全部生命周期规则
+	DeleteBucketLifecycle(bucketName string) error
+}
+
+// SetStorageClass 通过原地 CopyObject 修改对象的存储类别，内容和元数据保持不变
+func SetStorageClass(svc StorageService, bucketName, fileKey, storageClass string) error {
+	if storageClass == "" {
+		return errors.New("storage: storage class is required")
+	}
+	return svc.CopyObject(&CopyObjectInput{
+		SourceBucket:      bucketName,
+		SourceKey:         fileKey,
+		DestinationBucket: bucketName,
+		DestinationKey:    fileKey,
+		StorageClass:      storageClass,
+	})
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/log"
+)
+
+// MetricsRecorder 接收每次存储操作的结果，可对接 Prometheus、OpenTelemetry 等监控系统
+type MetricsRecorder interface {
+	ObserveStorageOp(op, bucketName string, latency time.Duration, err error)
+}
+
+// Metrics 记录每次存储操作的耗时和结果。重试时记录的是包含全部重试的总耗时，
+// 需要单次请求耗时时将 Metrics 放在 Retry 之后。
+func Metrics(recorder MetricsRecorder) Middleware {
+	return Intercept(func(call *Call, next func() error) error {
+		start := time.Now()
+		err := next()
+		recorder.ObserveStorageOp(call.Op, call.Bucket, time.Since(start), err)
+		return err
+	})
+}
+
+// OpStats 单个操作的统计
+type OpStats struct {
+	Count        int64         `json:"count"`
+	Errors       int64         `json:"errors"`
+	TotalLatency time.Duration `json:"totalLatency"`
+	MaxLatency   time.Duration `json:"maxLatency"`
+}
+
+// AvgLatency 平均耗时
+func (s OpStats) AvgLatency() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Count)
+}
+
+// StatsRecorder 按操作名称在内存中汇总统计的 MetricsRecorder
+type StatsRecorder struct {
+	mu    sync.Mutex
+	stats map[string]OpStats
+}
+
+// NewStatsRecorder 创建内存统计
+func NewStatsRecorder() *StatsRecorder {
+	return &StatsRecorder{stats: make(map[string]OpStats)}
+}
+
+// ObserveStorageOp 实现 MetricsRecorder
+func (r *StatsRecorder) ObserveStorageOp(op, _ string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.stats[op]
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.TotalLatency += latency
+	s.MaxLatency = max(s.MaxLatency, latency)
+	r.stats[op] = s
+}
+
+// Snapshot 返回当前统计的副本
+func (r *StatsRecorder) Snapshot() map[string]OpStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make(map[string]OpStats, len(r.stats))
+	for op, s := range r.stats {
+		snapshot[op] = s
+	}
+	return snapshot
+}
+
+// Logging 通过 log 包输出结构化操作日志：成功为 Debug 级别，失败为 Error 级别。
+// logger 为 nil 时使用全局 logger 并命名为 storage。
+func Logging(logger log.Logger) Middleware {
+	if logger == nil {
+		logger = log.WithName("storage")
+	}
+	return Intercept(func(call *Call, next func() error) error {
+		start := time.Now()
+		err := next()
+
+		keysAndValues := []interface{}{"op", call.Op, "bucket", call.Bucket, "latency", time.Since(start)}
+		if call.Key != "" {
+			keysAndValues = append(keysAndValues, "key", call.Key)
+		}
+		if call.SourceKey != "" {
+			keysAndValues = append(keysAndValues, "sourceBucket", call.SourceBucket, "sourceKey", call.SourceKey)
+		}
+		if len(call.Keys) > 0 {
+			keysAndValues = append(keysAndValues, "keys", len(call.Keys))
+		}
+		if err != nil {
+			logger.Errorw("storage operation failed", append(keysAndValues, "error", err)...)
+		} else {
+			logger.Debugw("storage operation", keysAndValues...)
+		}
+		return err
+	})
+}
@@ -0,0 +1,499 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// Middleware 存储装饰器，用于在全部后端上复用重试、监控、日志、缓存等横切逻辑
+type Middleware func(StorageService) StorageService
+
+// Chain 依次应用中间件，第一个中间件位于最外层（最先处理调用）
+func Chain(svc StorageService, middlewares ...Middleware) StorageService {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		svc = middlewares[i](svc)
+	}
+	return svc
+}
+
+// 存储操作名称
+const (
+	OpUploadObject                  = "UploadObject"
+	OpUploadObjectStream            = "UploadObjectStream"
+	OpUploadObjectWithOptions       = "UploadObjectWithOptions"
+	OpUploadObjectStreamWithOptions = "UploadObjectStreamWithOptions"
+	OpGetObject                     = "GetObject"
+	OpGetObjectStream               = "GetObjectStream"
+	OpObjectExists                  = "ObjectExists"
+	OpHeadObject                    = "HeadObject"
+	OpDeleteObject                  = "DeleteObject"
+	OpDeleteObjects                 = "DeleteObjects"
+	OpListObjects                   = "ListObjects"
+	OpCopyObject                    = "CopyObject"
+	OpMoveObject                    = "MoveObject"
+	OpGetObjectMetadata             = "GetObjectMetadata"
+	OpCreateFolder                  = "CreateFolder"
+	OpDeleteFolder                  = "DeleteFolder"
+	OpListFolders                   = "ListFolders"
+	OpPreSignPutObject              = "PreSignPutObject"
+	OpBatchPreSignPutObject         = "BatchPreSignPutObject"
+	OpPreSignGetObject              = "PreSignGetObject"
+	OpPreSignDeleteObject           = "PreSignDeleteObject"
+	OpPreSignPostPolicy             = "PreSignPostPolicy"
+	OpSetObjectACL                  = "SetObjectACL"
+	OpGetObjectACL                  = "GetObjectACL"
+	OpSetObjectMetadata             = "SetObjectMetadata"
+	OpGenerateDownloadURL           = "GenerateDownloadURL"
+	OpGetBucketLifecycle            = "GetBucketLifecycle"
+	OpPutBucketLifecycle            = "PutBucketLifecycle"
+	OpDeleteBucketLifecycle         = "DeleteBucketLifecycle"
+	OpPutObjectTags                 = "PutObjectTags"
+	OpGetObjectTags                 = "GetObjectTags"
+	OpSetBucketVersioning           = "SetBucketVersioning"
+	OpListObjectVersions            = "ListObjectVersions"
+	OpGetObjectVersion              = "GetObjectVersion"
+	OpRestoreObject                 = "RestoreObject"
+)
+
+// Call 一次存储操作。拦截器可以修改 Bucket、Key 等参数，下游使用修改后的值
+type Call struct {
+	Op           string
+	Bucket       string   // 存储桶，CopyObject/MoveObject 为目标桶
+	Key          string   // 对象键，目录操作为目录路径或前缀，CopyObject/MoveObject 为目标键
+	SourceBucket string   // CopyObject/MoveObject 的源存储桶
+	SourceKey    string   // CopyObject/MoveObject 的源对象键
+	Keys         []string // DeleteObjects、BatchPreSignPutObject 的对象键
+	Retryable    bool     // 是否可以安全重试（不可回退的流式上传为 false）
+	Result       any      // 指向返回值的指针（如 GetObject 为 *[]byte），拦截器可直接写入结果并跳过 next
+}
+
+// Interceptor 包裹一次存储操作，调用 next 执行下游操作
+type Interceptor func(call *Call, next func() error) error
+
+// Intercept 将拦截器转换为中间件。返回的存储保留下游实现的可选接口
+// （OptionsUploader、LifecycleManager、ObjectTagger、VersionedStorage），且只暴露下游支持的接口；
+// StreamGetter 和 ExistenceChecker 始终暴露，下游不支持时分别退化为 GetObject 和 GetObjectMetadata。
+func Intercept(fn Interceptor) Middleware {
+	return func(next StorageService) StorageService {
+		return expose(&interceptor{next: next, fn: fn}, next)
+	}
+}
+
+// interceptor 实现全部存储接口，每个方法构造 Call 后交给拦截器
+type interceptor struct {
+	next StorageService
+	fn   Interceptor
+}
+
+//go:generate go run ./internal/exposegen -output expose_gen.go
+
+// exposed 中间件始终暴露的接口，下游不支持的可选接口退化为基础操作
+type exposed interface {
+	StorageService
+	StreamGetter
+	ExistenceChecker
+}
+
+// expose 按下游实现的可选接口组合返回值，避免调用方对不支持的能力做类型断言成功。
+// 组合由 internal/exposegen 生成
+func expose(w *interceptor, next StorageService) StorageService {
+	mask := 0
+	for i, implemented := range exposeCapabilities {
+		if implemented(next) {
+			mask |= 1 << i
+		}
+	}
+	return exposeTable[mask](w)
+}
+
+// ===== 基础文件操作 =====
+
+func (w *interceptor) UploadObject(bucketName, fileKey string, data []byte) error {
+	call := &Call{Op: OpUploadObject, Bucket: bucketName, Key: fileKey, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.UploadObject(call.Bucket, call.Key, data)
+	})
+}
+
+func (w *interceptor) UploadObjectStream(bucketName, fileKey string, file io.Reader) error {
+	call := &Call{Op: OpUploadObjectStream, Bucket: bucketName, Key: fileKey}
+	rewind := rewinder(file, call)
+	return w.fn(call, func() error {
+		if err := rewind(); err != nil {
+			return err
+		}
+		return w.next.UploadObjectStream(call.Bucket, call.Key, file)
+	})
+}
+
+func (w *interceptor) UploadObjectWithOptions(bucketName, fileKey string, data []byte, options UploadObjectOptions) error {
+	call := &Call{Op: OpUploadObjectWithOptions, Bucket: bucketName, Key: fileKey, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.(OptionsUploader).UploadObjectWithOptions(call.Bucket, call.Key, data, options)
+	})
+}
+
+func (w *interceptor) UploadObjectStreamWithOptions(bucketName, fileKey string, file io.Reader, options UploadObjectOptions) error {
+	call := &Call{Op: OpUploadObjectStreamWithOptions, Bucket: bucketName, Key: fileKey}
+	rewind := rewinder(file, call)
+	return w.fn(call, func() error {
+		if err := rewind(); err != nil {
+			return err
+		}
+		return w.next.(OptionsUploader).UploadObjectStreamWithOptions(call.Bucket, call.Key, file, options)
+	})
+}
+
+func (w *interceptor) GetObject(bucketName, fileKey string) ([]byte, error) {
+	var data []byte
+	call := &Call{Op: OpGetObject, Bucket: bucketName, Key: fileKey, Retryable: true, Result: &data}
+	err := w.fn(call, func() (err error) {
+		data, err = w.next.GetObject(call.Bucket, call.Key)
+		return err
+	})
+	return data, err
+}
+
+func (w *interceptor) GetObjectStream(bucketName, fileKey string) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+	call := &Call{Op: OpGetObjectStream, Bucket: bucketName, Key: fileKey, Retryable: true, Result: &reader}
+	err := w.fn(call, func() error {
+		if getter, ok := w.next.(StreamGetter); ok {
+			var err error
+			reader, err = getter.GetObjectStream(call.Bucket, call.Key)
+			return err
+		}
+		data, err := w.next.GetObject(call.Bucket, call.Key)
+		if err != nil {
+			return err
+		}
+		reader = io.NopCloser(bytes.NewReader(data))
+		return nil
+	})
+	return reader, err
+}
+
+func (w *interceptor) ObjectExists(ctx context.Context, bucketName, fileKey string) (bool, error) {
+	var exists bool
+	call := &Call{Op: OpObjectExists, Bucket: bucketName, Key: fileKey, Retryable: true, Result: &exists}
+	err := w.fn(call, func() (err error) {
+		exists, err = ObjectExists(ctx, w.next, call.Bucket, call.Key)
+		return err
+	})
+	return exists, err
+}
+
+func (w *interceptor) HeadObject(bucketName, fileKey string) bool {
+	var exists bool
+	call := &Call{Op: OpHeadObject, Bucket: bucketName, Key: fileKey, Retryable: true, Result: &exists}
+	_ = w.fn(call, func() error {
+		exists = w.next.HeadObject(call.Bucket, call.Key)
+		return nil
+	})
+	return exists
+}
+
+func (w *interceptor) DeleteObject(bucketName, fileKey string) error {
+	call := &Call{Op: OpDeleteObject, Bucket: bucketName, Key: fileKey, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.DeleteObject(call.Bucket, call.Key)
+	})
+}
+
+func (w *interceptor) DeleteObjects(bucketName string, fileKeys []string) ([]string, error) {
+	var keys []string
+	call := &Call{Op: OpDeleteObjects, Bucket: bucketName, Keys: fileKeys, Retryable: true, Result: &keys}
+	err := w.fn(call, func() (err error) {
+		keys, err = w.next.DeleteObjects(call.Bucket, call.Keys)
+		return err
+	})
+	return keys, err
+}
+
+// ===== 文件管理操作 =====
+
+func (w *interceptor) ListObjects(input *ListObjectsInput) (*ListObjectsOutput, error) {
+	if input == nil {
+		return w.next.ListObjects(input)
+	}
+	var output *ListObjectsOutput
+	call := &Call{Op: OpListObjects, Bucket: input.Bucket, Key: input.Prefix, Retryable: true, Result: &output}
+	err := w.fn(call, func() (err error) {
+		listInput := *input
+		listInput.Bucket, listInput.Prefix = call.Bucket, call.Key
+		output, err = w.next.ListObjects(&listInput)
+		return err
+	})
+	return output, err
+}
+
+func (w *interceptor) CopyObject(input *CopyObjectInput) error {
+	if input == nil {
+		return w.next.CopyObject(input)
+	}
+	call := &Call{
+		Op:           OpCopyObject,
+		Bucket:       input.DestinationBucket,
+		Key:          input.DestinationKey,
+		SourceBucket: input.SourceBucket,
+		SourceKey:    input.SourceKey,
+		Retryable:    true,
+	}
+	return w.fn(call, func() error {
+		copyInput := *input
+		copyInput.DestinationBucket, copyInput.DestinationKey = call.Bucket, call.Key
+		copyInput.SourceBucket, copyInput.SourceKey = call.SourceBucket, call.SourceKey
+		return w.next.CopyObject(&copyInput)
+	})
+}
+
+func (w *interceptor) MoveObject(sourceBucket, sourceKey, destBucket, destKey string) error {
+	call := &Call{Op: OpMoveObject, Bucket: destBucket, Key: destKey, SourceBucket: sourceBucket, SourceKey: sourceKey, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.MoveObject(call.SourceBucket, call.SourceKey, call.Bucket, call.Key)
+	})
+}
+
+func (w *interceptor) GetObjectMetadata(bucketName, fileKey string) (*ObjectMetadata, error) {
+	var metadata *ObjectMetadata
+	call := &Call{Op: OpGetObjectMetadata, Bucket: bucketName, Key: fileKey, Retryable: true, Result: &metadata}
+	err := w.fn(call, func() (err error) {
+		metadata, err = w.next.GetObjectMetadata(call.Bucket, call.Key)
+		return err
+	})
+	return metadata, err
+}
+
+// ===== 目录操作 =====
+
+func (w *interceptor) CreateFolder(bucketName, folderPath string) error {
+	call := &Call{Op: OpCreateFolder, Bucket: bucketName, Key: folderPath, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.CreateFolder(call.Bucket, call.Key)
+	})
+}
+
+func (w *interceptor) DeleteFolder(bucketName, folderPath string) error {
+	call := &Call{Op: OpDeleteFolder, Bucket: bucketName, Key: folderPath, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.DeleteFolder(call.Bucket, call.Key)
+	})
+}
+
+func (w *interceptor) ListFolders(bucketName, prefix string) ([]string, error) {
+	var folders []string
+	call := &Call{Op: OpListFolders, Bucket: bucketName, Key: prefix, Retryable: true, Result: &folders}
+	err := w.fn(call, func() (err error) {
+		folders, err = w.next.ListFolders(call.Bucket, call.Key)
+		return err
+	})
+	return folders, err
+}
+
+// ===== 预签名URL操作 =====
+
+func (w *interceptor) PreSignPutObject(bucketName, fileKey string) (string, error) {
+	return w.presign(OpPreSignPutObject, bucketName, fileKey, w.next.PreSignPutObject)
+}
+
+func (w *interceptor) BatchPreSignPutObject(bucketName string, fileKeys []string, isWholeKey bool) map[string]string {
+	var urls map[string]string
+	call := &Call{Op: OpBatchPreSignPutObject, Bucket: bucketName, Keys: fileKeys, Retryable: true, Result: &urls}
+	_ = w.fn(call, func() error {
+		urls = w.next.BatchPreSignPutObject(call.Bucket, call.Keys, isWholeKey)
+		return nil
+	})
+	return urls
+}
+
+func (w *interceptor) PreSignGetObject(bucketName, fileKey string) (string, error) {
+	return w.presign(OpPreSignGetObject, bucketName, fileKey, w.next.PreSignGetObject)
+}
+
+func (w *interceptor) PreSignDeleteObject(bucketName, fileKey string) (string, error) {
+	return w.presign(OpPreSignDeleteObject, bucketName, fileKey, w.next.PreSignDeleteObject)
+}
+
+func (w *interceptor) PreSignPostPolicy(bucketName, keyPrefix string, conditions PostPolicyConditions) (*PostPolicy, error) {
+	var policy *PostPolicy
+	call := &Call{Op: OpPreSignPostPolicy, Bucket: bucketName, Key: keyPrefix, Retryable: true, Result: &policy}
+	err := w.fn(call, func() (err error) {
+		policy, err = w.next.PreSignPostPolicy(call.Bucket, call.Key, conditions)
+		return err
+	})
+	return policy, err
+}
+
+// ===== 高级功能 =====
+
+func (w *interceptor) SetObjectACL(bucketName, fileKey, acl string) error {
+	call := &Call{Op: OpSetObjectACL, Bucket: bucketName, Key: fileKey, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.SetObjectACL(call.Bucket, call.Key, acl)
+	})
+}
+
+func (w *interceptor) GetObjectACL(bucketName, fileKey string) (string, error) {
+	var acl string
+	call := &Call{Op: OpGetObjectACL, Bucket: bucketName, Key: fileKey, Retryable: true, Result: &acl}
+	err := w.fn(call, func() (err error) {
+		acl, err = w.next.GetObjectACL(call.Bucket, call.Key)
+		return err
+	})
+	return acl, err
+}
+
+func (w *interceptor) SetObjectMetadata(bucketName, fileKey string, metadata map[string]string) error {
+	call := &Call{Op: OpSetObjectMetadata, Bucket: bucketName, Key: fileKey, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.SetObjectMetadata(call.Bucket, call.Key, metadata)
+	})
+}
+
+func (w *interceptor) GenerateDownloadURL(bucketName, fileKey string) string {
+	var url string
+	call := &Call{Op: OpGenerateDownloadURL, Bucket: bucketName, Key: fileKey, Retryable: true, Result: &url}
+	_ = w.fn(call, func() error {
+		url = w.next.GenerateDownloadURL(call.Bucket, call.Key)
+		return nil
+	})
+	return url
+}
+
+// ===== 生命周期管理 =====
+
+func (w *interceptor) GetBucketLifecycle(bucketName string) ([]LifecycleRule, error) {
+	var rules []LifecycleRule
+	call := &Call{Op: OpGetBucketLifecycle, Bucket: bucketName, Retryable: true, Result: &rules}
+	err := w.fn(call, func() (err error) {
+		rules, err = w.next.(LifecycleManager).GetBucketLifecycle(call.Bucket)
+		return err
+	})
+	return rules, err
+}
+
+func (w *interceptor) PutBucketLifecycle(bucketName string, rules []LifecycleRule) error {
+	call := &Call{Op: OpPutBucketLifecycle, Bucket: bucketName, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.(LifecycleManager).PutBucketLifecycle(call.Bucket, rules)
+	})
+}
+
+func (w *interceptor) DeleteBucketLifecycle(bucketName string) error {
+	call := &Call{Op: OpDeleteBucketLifecycle, Bucket: bucketName, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.(LifecycleManager).DeleteBucketLifecycle(call.Bucket)
+	})
+}
+
+// ===== 标签与版本控制 =====
+
+func (w *interceptor) PutObjectTags(bucketName, fileKey string, tags map[string]string) error {
+	call := &Call{Op: OpPutObjectTags, Bucket: bucketName, Key: fileKey, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.(ObjectTagger).PutObjectTags(call.Bucket, call.Key, tags)
+	})
+}
+
+func (w *interceptor) GetObjectTags(bucketName, fileKey string) (map[string]string, error) {
+	var tags map[string]string
+	call := &Call{Op: OpGetObjectTags, Bucket: bucketName, Key: fileKey, Retryable: true, Result: &tags}
+	err := w.fn(call, func() (err error) {
+		tags, err = w.next.(ObjectTagger).GetObjectTags(call.Bucket, call.Key)
+		return err
+	})
+	return tags, err
+}
+
+func (w *interceptor) SetBucketVersioning(bucketName string, enabled bool) error {
+	call := &Call{Op: OpSetBucketVersioning, Bucket: bucketName, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.(VersionedStorage).SetBucketVersioning(call.Bucket, enabled)
+	})
+}
+
+func (w *interceptor) ListObjectVersions(bucketName, prefix string) ([]ObjectVersion, error) {
+	var versions []ObjectVersion
+	call := &Call{Op: OpListObjectVersions, Bucket: bucketName, Key: prefix, Retryable: true, Result: &versions}
+	err := w.fn(call, func() (err error) {
+		versions, err = w.next.(VersionedStorage).ListObjectVersions(call.Bucket, call.Key)
+		return err
+	})
+	return versions, err
+}
+
+func (w *interceptor) GetObjectVersion(bucketName, fileKey, versionID string) ([]byte, error) {
+	var data []byte
+	call := &Call{Op: OpGetObjectVersion, Bucket: bucketName, Key: fileKey, Retryable: true, Result: &data}
+	err := w.fn(call, func() (err error) {
+		data, err = w.next.(VersionedStorage).GetObjectVersion(call.Bucket, call.Key, versionID)
+		return err
+	})
+	return data, err
+}
+
+func (w *interceptor) RestoreObject(bucketName, fileKey, versionID string) error {
+	call := &Call{Op: OpRestoreObject, Bucket: bucketName, Key: fileKey, Retryable: true}
+	return w.fn(call, func() error {
+		return w.next.(VersionedStorage).RestoreObject(call.Bucket, call.Key, versionID)
+	})
+}
+
+// ===== 辅助方法 =====
+
+func (w *interceptor) presign(op, bucketName, fileKey string, sign func(bucketName, fileKey string) (string, error)) (string, error) {
+	var url string
+	call := &Call{Op: op, Bucket: bucketName, Key: fileKey, Retryable: true, Result: &url}
+	err := w.fn(call, func() (err error) {
+		url, err = sign(call.Bucket, call.Key)
+		return err
+	})
+	return url, err
+}
+
+// rewinder 返回每次执行前将流回退到起始位置的函数。流不可 Seek 时标记为不可重试
+func rewinder(file io.Reader, call *Call) func() error {
+	seeker, ok := file.(io.Seeker)
+	if !ok {
+		return func() error { return nil }
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return func() error { return nil }
+	}
+	call.Retryable = true
+	return func() error {
+		_, err := seeker.Seek(start, io.SeekStart)
+		return err
+	}
+}
+
+var (
+	_ StorageService   = (*interceptor)(nil)
+	_ OptionsUploader  = (*interceptor)(nil)
+	_ LifecycleManager = (*interceptor)(nil)
+	_ ObjectTagger     = (*interceptor)(nil)
+	_ VersionedStorage = (*interceptor)(nil)
+)
+
+// RewriteBucket 改写存储桶名称，用于多区域别名等场景。目标桶和源桶都会被改写
+func RewriteBucket(rewrite func(bucketName string) string) Middleware {
+	return Intercept(func(call *Call, next func() error) error {
+		call.Bucket = rewrite(call.Bucket)
+		if call.SourceBucket != "" {
+			call.SourceBucket = rewrite(call.SourceBucket)
+		}
+		return next()
+	})
+}
+
+// BucketAliases 按别名表改写存储桶名称，不在表中的名称保持不变
+func BucketAliases(aliases map[string]string) Middleware {
+	return RewriteBucket(func(bucketName string) string {
+		if actual, ok := aliases[bucketName]; ok {
+			return actual
+		}
+		return bucketName
+	})
+}
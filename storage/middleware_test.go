@@ -0,0 +1,254 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+// statusError 模拟 SDK 返回的带 HTTP 状态码的错误
+type statusError int
+
+func (e statusError) Error() string       { return "status error" }
+func (e statusError) HTTPStatusCode() int { return int(e) }
+
+// flaky 在前 failures 次调用时返回错误
+func flaky(failures int, err error) (storage.Middleware, *int) {
+	calls := 0
+	return storage.Intercept(func(call *storage.Call, next func() error) error {
+		calls++
+		if calls <= failures {
+			return err
+		}
+		return next()
+	}), &calls
+}
+
+func TestChainOrderAndOptionalInterfaces(t *testing.T) {
+	var order []string
+	trace := func(name string) storage.Middleware {
+		return storage.Intercept(func(call *storage.Call, next func() error) error {
+			order = append(order, name+":"+call.Op)
+			return next()
+		})
+	}
+
+	svc := storage.Chain(memory.NewMemoryService(""), trace("outer"), trace("inner"))
+	uploader, ok := svc.(storage.OptionsUploader)
+	if !ok {
+		t.Fatal("wrapped memory storage should keep OptionsUploader")
+	}
+	if _, ok := svc.(storage.VersionedStorage); !ok {
+		t.Fatal("wrapped memory storage should keep VersionedStorage")
+	}
+	if err := uploader.UploadObjectWithOptions("b", "a.txt", []byte("a"), storage.UploadObjectOptions{}); err != nil {
+		t.Fatalf("UploadObjectWithOptions() error = %v", err)
+	}
+	want := []string{"outer:UploadObjectWithOptions", "inner:UploadObjectWithOptions"}
+	if len(order) != 2 || order[0] != want[0] || order[1] != want[1] {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+
+	// 只实现基础接口的存储包装后不应暴露可选接口
+	plain := storage.Chain(struct{ storage.StorageService }{memory.NewMemoryService("")}, trace("x"))
+	if _, ok := plain.(storage.OptionsUploader); ok {
+		t.Fatal("wrapper should not expose interfaces the inner storage lacks")
+	}
+}
+
+func TestChainForwardsStreamAndExistence(t *testing.T) {
+	var ops []string
+	record := storage.Intercept(func(call *storage.Call, next func() error) error {
+		ops = append(ops, call.Op)
+		return next()
+	})
+
+	inner := memory.NewMemoryService("")
+	_ = inner.UploadObject("b", "a.txt", []byte("a"))
+	for _, svc := range []storage.StorageService{
+		storage.Chain(inner, record),
+		// 只实现基础接口的存储退化为 GetObject 和 GetObjectMetadata
+		storage.Chain(struct{ storage.StorageService }{inner}, record),
+	} {
+		ops = nil
+		getter, ok := svc.(storage.StreamGetter)
+		if !ok {
+			t.Fatal("wrapped storage should expose StreamGetter")
+		}
+		reader, err := getter.GetObjectStream("b", "a.txt")
+		if err != nil {
+			t.Fatalf("GetObjectStream() error = %v", err)
+		}
+		data, _ := io.ReadAll(reader)
+		_ = reader.Close()
+		if string(data) != "a" {
+			t.Fatalf("GetObjectStream() = %q", data)
+		}
+
+		if _, ok := svc.(storage.ExistenceChecker); !ok {
+			t.Fatal("wrapped storage should expose ExistenceChecker")
+		}
+		exists, err := storage.ObjectExists(context.Background(), svc, "b", "a.txt")
+		if err != nil || !exists {
+			t.Fatalf("ObjectExists() = %v, %v", exists, err)
+		}
+		if exists, err := storage.ObjectExists(context.Background(), svc, "b", "missing"); err != nil || exists {
+			t.Fatalf("ObjectExists(missing) = %v, %v", exists, err)
+		}
+		if len(ops) != 3 || ops[0] != storage.OpGetObjectStream || ops[1] != storage.OpObjectExists {
+			t.Fatalf("ops = %v, want calls through the interceptor", ops)
+		}
+	}
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	var sleeps []time.Duration
+	retry := storage.Retry(storage.RetryOptions{MaxAttempts: 3, Sleep: func(d time.Duration) { sleeps = append(sleeps, d) }})
+
+	failing, calls := flaky(2, statusError(503))
+	svc := storage.Chain(memory.NewMemoryService(""), retry, failing)
+	if err := svc.UploadObject("b", "a.txt", []byte("a")); err != nil {
+		t.Fatalf("UploadObject() error = %v", err)
+	}
+	if *calls != 3 || len(sleeps) != 2 {
+		t.Fatalf("calls = %d, sleeps = %v", *calls, sleeps)
+	}
+
+	failing, calls = flaky(1, statusError(403))
+	svc = storage.Chain(memory.NewMemoryService(""), retry, failing)
+	if err := svc.UploadObject("b", "a.txt", []byte("a")); err == nil || *calls != 1 {
+		t.Fatalf("non-transient error should not be retried, calls = %d, err = %v", *calls, err)
+	}
+}
+
+func TestRetryStreamRequiresSeeker(t *testing.T) {
+	retry := storage.Retry(storage.RetryOptions{Sleep: func(time.Duration) {}})
+	inner := memory.NewMemoryService("")
+
+	failing, calls := flaky(1, statusError(500))
+	svc := storage.Chain(inner, retry, failing)
+	if err := svc.UploadObjectStream("b", "seek.txt", bytes.NewReader([]byte("data"))); err != nil || *calls != 2 {
+		t.Fatalf("seekable stream should be retried, calls = %d, err = %v", *calls, err)
+	}
+	if data, _ := inner.GetObject("b", "seek.txt"); string(data) != "data" {
+		t.Fatalf("retried upload = %q, stream should be rewound", data)
+	}
+
+	failing, calls = flaky(1, statusError(500))
+	svc = storage.Chain(inner, retry, failing)
+	reader := io.MultiReader(bytes.NewReader([]byte("data")))
+	if err := svc.UploadObjectStream("b", "pipe.txt", reader); err == nil || *calls != 1 {
+		t.Fatalf("non-seekable stream should not be retried, calls = %d, err = %v", *calls, err)
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	cases := map[error]bool{
+		nil:                        false,
+		errors.New("boom"):         false,
+		statusError(429):           true,
+		statusError(502):           true,
+		statusError(404):           false,
+		io.ErrUnexpectedEOF:        true,
+		storage.ErrVersionNotFound: false,
+	}
+	for err, want := range cases {
+		if got := storage.IsTransientError(err); got != want {
+			t.Errorf("IsTransientError(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
+
+func TestMetricsRecordsOps(t *testing.T) {
+	stats := storage.NewStatsRecorder()
+	svc := storage.Chain(memory.NewMemoryService(""), storage.Metrics(stats))
+	_ = svc.UploadObject("b", "a.txt", []byte("a"))
+	_, _ = svc.GetObject("b", "a.txt")
+	_, _ = svc.GetObject("b", "missing.txt")
+
+	snapshot := stats.Snapshot()
+	if got := snapshot[storage.OpGetObject]; got.Count != 2 || got.Errors != 1 {
+		t.Fatalf("GetObject stats = %+v", got)
+	}
+	if got := snapshot[storage.OpUploadObject]; got.Count != 1 || got.Errors != 0 {
+		t.Fatalf("UploadObject stats = %+v", got)
+	}
+}
+
+func TestCacheServesReadsAndInvalidatesOnWrite(t *testing.T) {
+	stats := storage.NewStatsRecorder()
+	inner := memory.NewMemoryService("")
+	svc := storage.Chain(inner, storage.Cache(storage.CacheOptions{}), storage.Metrics(stats))
+
+	_ = svc.UploadObject("b", "dir/a.txt", []byte("v1"))
+	for i := 0; i < 3; i++ {
+		data, err := svc.GetObject("b", "dir/a.txt")
+		if err != nil || string(data) != "v1" {
+			t.Fatalf("GetObject() = %q, %v", data, err)
+		}
+		data[0] = 'x' // 修改返回值不应影响缓存
+	}
+	if got := stats.Snapshot()[storage.OpGetObject].Count; got != 1 {
+		t.Fatalf("backend GetObject calls = %d, want 1", got)
+	}
+
+	_ = svc.UploadObject("b", "dir/a.txt", []byte("v2"))
+	if data, _ := svc.GetObject("b", "dir/a.txt"); string(data) != "v2" {
+		t.Fatalf("GetObject() after upload = %q, want v2", data)
+	}
+
+	_, _ = svc.GetObjectMetadata("b", "dir/a.txt")
+	if err := svc.DeleteFolder("b", "dir"); err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+	if _, err := svc.GetObject("b", "dir/a.txt"); err == nil {
+		t.Fatal("GetObject() should miss after DeleteFolder")
+	}
+	if _, err := svc.GetObjectMetadata("b", "dir/a.txt"); err == nil {
+		t.Fatal("GetObjectMetadata() should miss after DeleteFolder")
+	}
+}
+
+func TestCacheTTLAndEviction(t *testing.T) {
+	now := time.Unix(0, 0)
+	stats := storage.NewStatsRecorder()
+	inner := memory.NewMemoryService("")
+	svc := storage.Chain(inner,
+		storage.Cache(storage.CacheOptions{MaxEntries: 1, TTL: time.Minute, Now: func() time.Time { return now }}),
+		storage.Metrics(stats))
+
+	_ = inner.UploadObject("b", "a", []byte("a"))
+	_ = inner.UploadObject("b", "b", []byte("b"))
+	_, _ = svc.GetObject("b", "a")
+	_, _ = svc.GetObject("b", "b") // 淘汰 a
+	_, _ = svc.GetObject("b", "a")
+	if got := stats.Snapshot()[storage.OpGetObject].Count; got != 3 {
+		t.Fatalf("backend GetObject calls = %d, want 3", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	_, _ = svc.GetObject("b", "a")
+	if got := stats.Snapshot()[storage.OpGetObject].Count; got != 4 {
+		t.Fatalf("expired entry should be reloaded, calls = %d", got)
+	}
+}
+
+func TestBucketAliases(t *testing.T) {
+	inner := memory.NewMemoryService("")
+	svc := storage.Chain(inner, storage.BucketAliases(map[string]string{"assets": "assets-eu-west-1"}))
+
+	_ = svc.UploadObject("assets", "a.txt", []byte("a"))
+	if !inner.HeadObject("assets-eu-west-1", "a.txt") {
+		t.Fatal("upload should be rewritten to the aliased bucket")
+	}
+	err := svc.CopyObject(&storage.CopyObjectInput{SourceBucket: "assets", SourceKey: "a.txt", DestinationBucket: "other", DestinationKey: "b.txt"})
+	if err != nil || !inner.HeadObject("other", "b.txt") {
+		t.Fatalf("CopyObject() error = %v, source bucket should be rewritten", err)
+	}
+}
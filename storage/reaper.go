@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReaperRule 回收规则，同一规则内的条件需全部满足才会删除
+type ReaperRule struct {
+	Prefix   string                // 扫描的对象前缀
+	MaxAge   time.Duration         // 最后修改时间超过该时长，0 表示不按时间判断
+	Metadata map[string]string     // 用户元数据需全部匹配（每个候选对象额外一次 GetObjectMetadata）
+	Tags     map[string]string     // 对象标签需全部匹配，存储实现需支持 ObjectTagGetter
+	Match    func(ObjectInfo) bool // 自定义条件
+}
+
+// ReaperOptions 回收器选项
+type ReaperOptions struct {
+	Bucket    string
+	Rules     []ReaperRule
+	DryRun    bool             // 只统计不删除
+	BatchSize int              // 每批删除数量，默认 1000
+	Now       func() time.Time // 当前时间，默认 time.Now
+	OnDelete  func(key string) // 每个待删除对象的回调（DryRun 时同样调用）
+}
+
+// ReapReport 单次回收结果
+type ReapReport struct {
+	Scanned int      `json:"scanned"`
+	Matched int      `json:"matched"`
+	Deleted int      `json:"deleted"`
+	Failed  []string `json:"failed,omitempty"` // 批量删除中失败的对象键，下次运行会再次匹配
+}
+
+// Reaper 为不支持原生生命周期的存储（或本地存储）按前缀扫描并删除过期对象
+type Reaper struct {
+	svc  StorageService
+	opts ReaperOptions
+}
+
+// NewReaper 创建回收器，每条规则至少需要一个条件，避免误删整个前缀
+func NewReaper(svc StorageService, opts ReaperOptions) (*Reaper, error) {
+	if svc == nil {
+		return nil, errors.New("storage: reaper requires a storage service")
+	}
+	if opts.Bucket == "" {
+		return nil, errors.New("storage: reaper requires a bucket")
+	}
+	for i, rule := range opts.Rules {
+		if rule.MaxAge <= 0 && len(rule.Metadata) == 0 && len(rule.Tags) == 0 && rule.Match == nil {
+			return nil, fmt.Errorf("storage: reaper rule %d has no condition", i)
+		}
+		if _, ok := svc.(ObjectTagGetter); len(rule.Tags) > 0 && !ok {
+			return nil, fmt.Errorf("storage: reaper rule %d matches tags but storage does not support tagging", i)
+		}
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultPageSize
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Reaper{svc: svc, opts: opts}, nil
+}
+
+// ReaperRulesFromLifecycle 将生命周期规则中的过期动作转换为回收规则，供不支持原生生命周期的存储使用
+func ReaperRulesFromLifecycle(rules []LifecycleRule) []ReaperRule {
+	var reaperRules []ReaperRule
+	for _, rule := range rules {
+		if !rule.Enabled || rule.ExpirationDays <= 0 {
+			continue
+		}
+		reaperRules = append(reaperRules, ReaperRule{
+			Prefix: rule.Prefix,
+			MaxAge: time.Duration(rule.ExpirationDays) * 24 * time.Hour,
+		})
+	}
+	return reaperRules
+}
+
+// Run 依次执行全部规则，目录占位对象不会被删除
+func (r *Reaper) Run(ctx context.Context) (*ReapReport, error) {
+	report := &ReapReport{}
+	now := r.opts.Now()
+
+	for _, rule := range r.opts.Rules {
+		keys := make([]string, 0, r.opts.BatchSize)
+		flush := func() error {
+			if len(keys) == 0 || r.opts.DryRun {
+				keys = keys[:0]
+				return nil
+			}
+			failed, err := r.svc.DeleteObjects(r.opts.Bucket, keys)
+			if err != nil {
+				return err
+			}
+			report.Deleted += len(keys) - len(failed)
+			report.Failed = append(report.Failed, failed...)
+			keys = keys[:0]
+			return nil
+		}
+
+		err := Walk(ctx, r.svc, &ListObjectsInput{Bucket: r.opts.Bucket, Prefix: rule.Prefix}, func(object ObjectInfo) error {
+			if object.IsDir {
+				return nil
+			}
+			report.Scanned++
+
+			matched, err := r.matches(rule, object, now)
+			if err != nil || !matched {
+				return err
+			}
+			report.Matched++
+			if r.opts.OnDelete != nil {
+				r.opts.OnDelete(object.Key)
+			}
+
+			keys = append(keys, object.Key)
+			if len(keys) < r.opts.BatchSize {
+				return nil
+			}
+			return flush()
+		}, WithRecursive(true))
+		if err != nil {
+			return report, err
+		}
+		if err := flush(); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// matches 先判断无需额外请求的条件，再按需读取元数据和标签
+func (r *Reaper) matches(rule ReaperRule, object ObjectInfo, now time.Time) (bool, error) {
+	if rule.MaxAge > 0 && now.Sub(object.LastModified) < rule.MaxAge {
+		return false, nil
+	}
+	if rule.Match != nil && !rule.Match(object) {
+		return false, nil
+	}
+
+	if len(rule.Metadata) > 0 {
+		metadata, err := r.svc.GetObjectMetadata(r.opts.Bucket, object.Key)
+		if err != nil {
+			return false, err
+		}
+		// S3 等后端返回的元数据键为小写，这里忽略键的大小写
+		if !containsAll(lowerKeys(metadata.Metadata), lowerKeys(rule.Metadata)) {
+			return false, nil
+		}
+	}
+
+	if len(rule.Tags) > 0 {
+		tags, err := r.svc.(ObjectTagGetter).GetObjectTags(r.opts.Bucket, object.Key)
+		if err != nil {
+			return false, err
+		}
+		if !containsAll(tags, rule.Tags) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func lowerKeys(values map[string]string) map[string]string {
+	lowered := make(map[string]string, len(values))
+	for key, value := range values {
+		lowered[strings.ToLower(key)] = value
+	}
+	return lowered
+}
+
+func containsAll(values, want map[string]string) bool {
+	for key, value := range want {
+		if got, ok := values[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package storage_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+type taggedService struct {
+	*countingService
+	tags map[string]map[string]string
+}
+
+func (t *taggedService) GetObjectTags(bucketName, fileKey string) (map[string]string, error) {
+	return t.tags[fileKey], nil
+}
+
+func TestReaperDeletesByAgeMetadataAndTags(t *testing.T) {
+	svc := &taggedService{
+		countingService: newCountingService(t, "tmp/a", "tmp/b", "tmp/c", "keep/a", "tmp/dir/"),
+		tags:            map[string]map[string]string{"tmp/c": {"retention": "short"}},
+	}
+	_ = svc.UploadObjectWithOptions("b", "gen/x", []byte("x"), storage.UploadObjectOptions{Metadata: map[string]string{"Source": "preview"}})
+	_ = svc.UploadObject("b", "gen/y", []byte("y"))
+
+	var planned []string
+	reaper, err := storage.NewReaper(svc, storage.ReaperOptions{
+		Bucket: "b",
+		Rules: []storage.ReaperRule{
+			{Prefix: "tmp/", MaxAge: 24 * time.Hour, Match: func(o storage.ObjectInfo) bool { return o.Key != "tmp/b" }},
+			{Prefix: "gen/", Metadata: map[string]string{"source": "preview"}},
+			{Prefix: "tmp/", Tags: map[string]string{"retention": "short"}},
+		},
+		BatchSize: 1,
+		Now:       func() time.Time { return time.Now().Add(48 * time.Hour) },
+		OnDelete:  func(key string) { planned = append(planned, key) },
+	})
+	if err != nil {
+		t.Fatalf("NewReaper() error = %v", err)
+	}
+
+	report, err := reaper.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := strings.Join(planned, ","); got != "tmp/a,tmp/c,gen/x" {
+		t.Fatalf("deleted %s, want tmp/a,tmp/c,gen/x", got)
+	}
+	if report.Deleted != 3 || len(svc.deleteCalls) != 3 {
+		t.Fatalf("report = %+v, delete calls = %v", report, svc.deleteCalls)
+	}
+	for _, key := range []string{"tmp/b", "keep/a", "gen/y", "tmp/dir/"} {
+		if !svc.HeadObject("b", key) {
+			t.Fatalf("%s should be kept", key)
+		}
+	}
+}
+
+// partialDelete 批量删除时保留指定对象并作为失败键返回
+type partialDelete struct {
+	*countingService
+	locked string
+}
+
+func (p *partialDelete) DeleteObjects(bucketName string, fileKeys []string) ([]string, error) {
+	var keys, failed []string
+	for _, key := range fileKeys {
+		if key == p.locked {
+			failed = append(failed, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if _, err := p.countingService.DeleteObjects(bucketName, keys); err != nil {
+		return nil, err
+	}
+	return failed, nil
+}
+
+func TestReaperReportsFailedDeletes(t *testing.T) {
+	svc := &partialDelete{countingService: newCountingService(t, "tmp/a", "tmp/b", "tmp/c"), locked: "tmp/b"}
+	reaper, err := storage.NewReaper(svc, storage.ReaperOptions{
+		Bucket: "b",
+		Rules:  []storage.ReaperRule{{Prefix: "tmp/", MaxAge: time.Hour}},
+		Now:    func() time.Time { return time.Now().Add(2 * time.Hour) },
+	})
+	if err != nil {
+		t.Fatalf("NewReaper() error = %v", err)
+	}
+
+	report, err := reaper.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Matched != 3 || report.Deleted != 2 || len(report.Failed) != 1 || report.Failed[0] != "tmp/b" {
+		t.Fatalf("report = %+v, want 2 deleted and tmp/b failed", report)
+	}
+	if !svc.HeadObject("b", "tmp/b") {
+		t.Fatal("failed key should still exist")
+	}
+}
+
+func TestReaperDryRunAndValidation(t *testing.T) {
+	svc := newCountingService(t, "old/a")
+	reaper, _ := storage.NewReaper(svc, storage.ReaperOptions{
+		Bucket: "b",
+		Rules:  storage.ReaperRulesFromLifecycle([]storage.LifecycleRule{{Prefix: "old/", Enabled: true, ExpirationDays: 1}, {Prefix: "", ExpirationDays: 1}}),
+		DryRun: true,
+		Now:    func() time.Time { return time.Now().Add(25 * time.Hour) },
+	})
+	report, err := reaper.Run(context.Background())
+	if err != nil || report.Matched != 1 || report.Deleted != 0 || !svc.HeadObject("b", "old/a") {
+		t.Fatalf("dry run report = %+v, err = %v", report, err)
+	}
+
+	if _, err := storage.NewReaper(svc, storage.ReaperOptions{Bucket: "b", Rules: []storage.ReaperRule{{Prefix: "old/"}}}); err == nil {
+		t.Fatal("rule without conditions should be rejected")
+	}
+	// 只暴露 StorageService，隐藏内存存储的标签能力
+	untagged := struct{ storage.StorageService }{svc}
+	if _, err := storage.NewReaper(untagged, storage.ReaperOptions{Bucket: "b", Rules: []storage.ReaperRule{{Tags: map[string]string{"a": "b"}}}}); err == nil {
+		t.Fatal("tag rule without tagging support should be rejected")
+	}
+}
+
+func TestSetStorageClass(t *testing.T) {
+	store := memory.NewMemoryService("")
+	_ = store.UploadObjectWithOptions("b", "video.mp4", []byte("v"), storage.UploadObjectOptions{Metadata: map[string]string{"k": "v"}})
+
+	if err := storage.SetStorageClass(store, "b", "video.mp4", storage.StorageClassGlacier); err != nil {
+		t.Fatalf("SetStorageClass() error = %v", err)
+	}
+	metadata, _ := store.GetObjectMetadata("b", "video.mp4")
+	if metadata.StorageClass != storage.StorageClassGlacier || metadata.Metadata["k"] != "v" {
+		t.Fatalf("metadata = %+v, want glacier class with metadata kept", metadata)
+	}
+	if err := storage.SetStorageClass(store, "b", "video.mp4", ""); err == nil {
+		t.Fatal("empty storage class should fail")
+	}
+}
@@ -0,0 +1,285 @@
+package replica
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// TaskOp 复制任务类型
+type TaskOp string
+
+const (
+	// TaskSync 将主存储中对象的当前状态（内容和元数据）同步到副本，主存储中不存在时删除副本对象
+	TaskSync TaskOp = "sync"
+	// TaskCreateFolder 在副本中创建目录
+	TaskCreateFolder TaskOp = "createFolder"
+	// TaskDeleteFolder 删除副本中的目录及其内容
+	TaskDeleteFolder TaskOp = "deleteFolder"
+	// TaskSetACL 设置副本对象的 ACL
+	TaskSetACL TaskOp = "setACL"
+)
+
+// Task 复制到单个副本的任务。任务不携带对象内容，执行时从主存储读取最新状态，因此可以安全重放
+type Task struct {
+	ID      uint64 `json:"id"`
+	Backend string `json:"backend"` // 副本名称
+	Op      TaskOp `json:"op"`
+	Bucket  string `json:"bucket"`
+	Key     string `json:"key"`
+	ACL     string `json:"acl,omitempty"`
+}
+
+// Queue 复制重试队列。任务执行成功或放弃后才会 Ack，进程重启后通过 Pending 恢复未完成的任务
+type Queue interface {
+	// Push 保存任务并分配 ID
+	Push(task Task) (Task, error)
+	// Ack 删除已完成的任务
+	Ack(id uint64) error
+	// Pending 返回全部未完成的任务，按 ID 升序
+	Pending() ([]Task, error)
+}
+
+// MemoryQueue 内存队列，进程退出后未完成的任务会丢失
+type MemoryQueue struct {
+	mu     sync.Mutex
+	nextID uint64
+	tasks  map[uint64]Task
+}
+
+// NewMemoryQueue 创建内存队列
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{tasks: make(map[uint64]Task)}
+}
+
+// Push 实现 Queue
+func (q *MemoryQueue) Push(task Task) (Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.nextID++
+	task.ID = q.nextID
+	q.tasks[task.ID] = task
+	return task, nil
+}
+
+// Ack 实现 Queue
+func (q *MemoryQueue) Ack(id uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.tasks, id)
+	return nil
+}
+
+// Pending 实现 Queue
+func (q *MemoryQueue) Pending() ([]Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return sortedTasks(q.tasks), nil
+}
+
+// fileRecord 队列日志中的一条记录
+type fileRecord struct {
+	Task *Task  `json:"task,omitempty"`
+	Ack  uint64 `json:"ack,omitempty"`
+}
+
+// FileQueue 基于追加日志文件的持久化队列，每次 Push/Ack 追加一行 JSON 并 fsync。
+// 已完成的记录超过 compactThreshold 时重写日志
+type FileQueue struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	nextID   uint64
+	tasks    map[uint64]Task
+	acked    int
+	compactN int
+}
+
+// compactThreshold 触发日志重写的已完成记录数
+const compactThreshold = 1024
+
+// OpenFileQueue 打开或创建持久化队列，并从日志恢复未完成的任务
+func OpenFileQueue(path string) (*FileQueue, error) {
+	q := &FileQueue{path: path, tasks: make(map[uint64]Task), compactN: compactThreshold}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	q.file = file
+	return q, nil
+}
+
+// Push 实现 Queue
+func (q *FileQueue) Push(task Task) (Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	task.ID = q.nextID + 1
+	if err := q.append(fileRecord{Task: &task}); err != nil {
+		return Task{}, err
+	}
+	q.nextID = task.ID
+	q.tasks[task.ID] = task
+	return task, nil
+}
+
+// Ack 实现 Queue
+func (q *FileQueue) Ack(id uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.tasks[id]; !ok {
+		return nil
+	}
+	if err := q.append(fileRecord{Ack: id}); err != nil {
+		return err
+	}
+	delete(q.tasks, id)
+	q.acked++
+	if q.acked >= q.compactN {
+		return q.compact()
+	}
+	return nil
+}
+
+// Pending 实现 Queue
+func (q *FileQueue) Pending() ([]Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return sortedTasks(q.tasks), nil
+}
+
+// Close 关闭日志文件
+func (q *FileQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.file.Close()
+}
+
+func (q *FileQueue) load() error {
+	file, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// good 为最后一条完整记录之后的偏移
+	var good int64
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(data) > 0 {
+				// 最后一行在写入时被中断，截断后再追加，避免新记录接在不完整的尾行之后
+				return os.Truncate(q.path, good)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var record fileRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			if _, perr := reader.Peek(1); errors.Is(perr, io.EOF) {
+				return os.Truncate(q.path, good)
+			}
+			return fmt.Errorf("replica: corrupt queue %s at line %d: %w", q.path, line, err)
+		}
+		good += int64(len(data))
+		switch {
+		case record.Task != nil:
+			q.tasks[record.Task.ID] = *record.Task
+			q.nextID = max(q.nextID, record.Task.ID)
+		case record.Ack != 0:
+			delete(q.tasks, record.Ack)
+			q.acked++
+		}
+	}
+}
+
+func (q *FileQueue) append(record fileRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	info, err := q.file.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err = q.file.Write(append(line, '\n')); err == nil {
+		err = q.file.Sync()
+	}
+	if err != nil {
+		// 截断到写入前的大小，避免留下不完整的一行
+		if terr := q.file.Truncate(info.Size()); terr != nil {
+			return errors.Join(err, terr)
+		}
+		return err
+	}
+	return nil
+}
+
+// compact 只保留未完成的任务重写日志，先写临时文件再原子替换
+func (q *FileQueue) compact() error {
+	tmp := q.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for _, task := range sortedTasks(q.tasks) {
+		line, err := json.Marshal(fileRecord{Task: &task})
+		if err == nil {
+			_, err = writer.Write(append(line, '\n'))
+		}
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err := errors.Join(writer.Flush(), file.Sync(), file.Close()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return err
+	}
+
+	reopened, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_ = q.file.Close()
+	q.file = reopened
+	q.acked = 0
+	return nil
+}
+
+func sortedTasks(tasks map[uint64]Task) []Task {
+	result := make([]Task, 0, len(tasks))
+	for _, task := range tasks {
+		result = append(result, task)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+var (
+	_ Queue = (*MemoryQueue)(nil)
+	_ Queue = (*FileQueue)(nil)
+)
@@ -0,0 +1,75 @@
+package replica
+
+import (
+	"context"
+	"errors"
+	stdsync "sync"
+
+	storagesync "github.com/QingsiLiu/baseComponents/storage/sync"
+)
+
+// ReconcileOptions 差异校验选项
+type ReconcileOptions struct {
+	Bucket  string
+	Prefix  string
+	Compare storagesync.CompareMode // 比较方式，默认 storagesync.CompareDefault
+	Repair  bool                    // 以主存储为准修复副本，false 时只报告差异
+}
+
+// ReconcileReport 单个副本的差异
+type ReconcileReport struct {
+	Backend string `json:"backend"`
+	// Divergent 与主存储不一致的对象：ActionCopy 为副本缺失或过期，ActionDelete 为副本多余。
+	// Repair 时 Err 非空表示修复失败
+	Divergent []storagesync.Action `json:"divergent"`
+	Scanned   int                  `json:"scanned"`
+}
+
+// Reconcile 对比主存储和每个副本，报告（或修复）差异。单个副本出错不影响其余副本的校验
+func (s *Storage) Reconcile(ctx context.Context, opts ReconcileOptions) ([]ReconcileReport, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("replica: reconcile requires a bucket")
+	}
+
+	reports := make([]ReconcileReport, 0, len(s.replicas))
+	var errs []error
+	for _, replica := range s.replicas {
+		report := ReconcileReport{Backend: replica.Name, Divergent: []storagesync.Action{}}
+		var mu stdsync.Mutex
+		result, err := storagesync.Run(ctx,
+			storagesync.Endpoint{Service: s.primary.Service, Bucket: opts.Bucket, Prefix: opts.Prefix},
+			storagesync.Endpoint{Service: replica.Service, Bucket: opts.Bucket, Prefix: opts.Prefix},
+			storagesync.Options{
+				Compare:          opts.Compare,
+				DryRun:           !opts.Repair,
+				DeleteExtraneous: true,
+				OnAction: func(action storagesync.Action) {
+					if action.Type == storagesync.ActionSkip {
+						return
+					}
+					action.Key = opts.Prefix + action.Key
+					mu.Lock()
+					report.Divergent = append(report.Divergent, action)
+					mu.Unlock()
+				},
+			})
+		if result != nil {
+			report.Scanned = result.Scanned
+		}
+		if err != nil && !errors.Is(err, storagesync.ErrIncomplete) {
+			errs = append(errs, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, errors.Join(errs...)
+}
+
+// Diverged 报告中是否存在差异
+func Diverged(reports []ReconcileReport) bool {
+	for _, report := range reports {
+		if len(report.Divergent) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,646 @@
+// Package replica 多后端复制存储：写入主存储后复制到一个或多个副本（同步或经持久化队列异步），
+// 读取时主存储出错回退到副本，可按延迟就近读取，并提供与主存储的差异校验。
+package replica
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+)
+
+// WriteMode 副本写入方式
+type WriteMode int
+
+const (
+	// WriteSync 主存储写入成功后同步写入全部副本，副本失败时返回 ErrReplicaWrite 并进入重试队列
+	WriteSync WriteMode = iota
+	// WriteAsync 主存储写入成功即返回，副本写入进入重试队列由后台协程执行
+	WriteAsync
+)
+
+const (
+	defaultWorkers    = 4
+	defaultRetryDelay = time.Second
+	maxRetryDelay     = 5 * time.Minute
+	// latencyWeight 读取延迟指数移动平均中新样本的权重
+	latencyWeight = 0.2
+)
+
+// ErrReplicaWrite 主存储已写入成功，但部分副本写入失败，失败的副本已进入重试队列
+var ErrReplicaWrite = errors.New("replica: replica write failed")
+
+// Backend 命名的存储后端。名称用于持久化队列中的任务，重启前后需保持一致。
+// 各后端存储桶名称不同时可用 storage.BucketAliases 包装副本
+type Backend struct {
+	Name    string
+	Service storage.StorageService
+}
+
+// Options 复制存储选项
+type Options struct {
+	Mode        WriteMode
+	Queue       Queue                      // 重试队列，默认 NewMemoryQueue，需要进程重启后继续复制时使用 OpenFileQueue
+	Workers     int                        // 后台复制并发数，默认 4。同一对象的任务总由同一协程顺序执行
+	RetryDelay  time.Duration              // 首次重试等待时长，之后按指数增长，默认 1s
+	MaxAttempts int                        // 单个任务最大尝试次数，0 表示一直重试直到成功
+	Nearest     bool                       // 就近读取：按最近的读取延迟排序后端，而不是固定先读主存储
+	OnError     func(task Task, err error) // 任务执行失败时回调（每次失败都会调用）
+}
+
+// Storage 复制存储，实现 storage.StorageService。写操作只在主存储成功后才复制到副本；
+// 预签名上传/删除链接只指向主存储，通过链接写入的对象需要 Reconcile 修复
+type Storage struct {
+	primary  Backend
+	replicas []Backend
+	byName   map[string]Backend
+	opts     Options
+
+	latency []atomic.Int64 // 与 backends() 顺序对应的读取延迟（纳秒），0 表示尚未测量
+
+	shards  []chan Task
+	wg      sync.WaitGroup
+	pending sync.WaitGroup
+	closeMu sync.RWMutex
+	closed  bool
+	stop    chan struct{}
+}
+
+// New 创建复制存储，并恢复队列中未完成的任务。使用完毕后调用 Close 停止后台复制
+func New(primary Backend, replicas []Backend, opts Options) (*Storage, error) {
+	if primary.Service == nil {
+		return nil, errors.New("replica: primary service is required")
+	}
+	if len(replicas) == 0 {
+		return nil, errors.New("replica: at least one replica is required")
+	}
+	byName := make(map[string]Backend, len(replicas)+1)
+	for _, backend := range append([]Backend{primary}, replicas...) {
+		if backend.Name == "" || backend.Service == nil {
+			return nil, errors.New("replica: backend name and service are required")
+		}
+		if _, ok := byName[backend.Name]; ok {
+			return nil, fmt.Errorf("replica: duplicate backend %s", backend.Name)
+		}
+		byName[backend.Name] = backend
+	}
+	if opts.Queue == nil {
+		opts.Queue = NewMemoryQueue()
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultRetryDelay
+	}
+
+	s := &Storage{
+		primary:  primary,
+		replicas: replicas,
+		byName:   byName,
+		opts:     opts,
+		latency:  make([]atomic.Int64, len(replicas)+1),
+		shards:   make([]chan Task, opts.Workers),
+		stop:     make(chan struct{}),
+	}
+	for i := range s.shards {
+		s.shards[i] = make(chan Task, 64)
+		s.wg.Add(1)
+		go s.work(s.shards[i])
+	}
+
+	pending, err := opts.Queue.Pending()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	for _, task := range pending {
+		if _, ok := byName[task.Backend]; !ok {
+			// 副本已从配置中移除
+			_ = opts.Queue.Ack(task.ID)
+			continue
+		}
+		s.dispatch(task)
+	}
+	return s, nil
+}
+
+// Flush 等待队列中的复制任务全部完成（包括重试），ctx 结束时提前返回
+func (s *Storage) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 停止后台复制。未完成的任务保留在队列中，持久化队列可在下次 New 时恢复
+func (s *Storage) Close() error {
+	s.closeMu.Lock()
+	if s.closed {
+		s.closeMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.stop)
+	s.closeMu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+// ===== 写操作 =====
+
+// UploadObject 上传文件
+func (s *Storage) UploadObject(bucketName, fileKey string, data []byte) error {
+	return s.UploadObjectWithOptions(bucketName, fileKey, data, storage.UploadObjectOptions{})
+}
+
+// UploadObjectWithOptions 上传文件并设置对象属性。同步模式直接把内容写入副本
+func (s *Storage) UploadObjectWithOptions(bucketName, fileKey string, data []byte, options storage.UploadObjectOptions) error {
+	if err := upload(s.primary.Service, bucketName, fileKey, data, options); err != nil {
+		return err
+	}
+	if s.opts.Mode == WriteAsync {
+		return s.enqueue(Task{Op: TaskSync, Bucket: bucketName, Key: fileKey})
+	}
+	return s.replicate(Task{Op: TaskSync, Bucket: bucketName, Key: fileKey}, func(replica Backend) error {
+		return upload(replica.Service, bucketName, fileKey, data, options)
+	})
+}
+
+// UploadObjectStream 流式上传文件
+func (s *Storage) UploadObjectStream(bucketName, fileKey string, file io.Reader) error {
+	return s.UploadObjectStreamWithOptions(bucketName, fileKey, file, storage.UploadObjectOptions{})
+}
+
+// UploadObjectStreamWithOptions 流式上传文件并设置对象属性。流只能读取一次，副本从主存储复制
+func (s *Storage) UploadObjectStreamWithOptions(bucketName, fileKey string, file io.Reader, options storage.UploadObjectOptions) error {
+	var err error
+	if uploader, ok := s.primary.Service.(storage.OptionsUploader); ok {
+		err = uploader.UploadObjectStreamWithOptions(bucketName, fileKey, file, options)
+	} else {
+		err = s.primary.Service.UploadObjectStream(bucketName, fileKey, file)
+	}
+	if err != nil {
+		return err
+	}
+	return s.write(Task{Op: TaskSync, Bucket: bucketName, Key: fileKey})
+}
+
+// DeleteObject 删除单个对象
+func (s *Storage) DeleteObject(bucketName, fileKey string) error {
+	if err := s.primary.Service.DeleteObject(bucketName, fileKey); err != nil {
+		return err
+	}
+	return s.write(Task{Op: TaskSync, Bucket: bucketName, Key: fileKey})
+}
+
+// DeleteObjects 批量删除对象，返回值与主存储一致
+func (s *Storage) DeleteObjects(bucketName string, fileKeys []string) ([]string, error) {
+	result, err := s.primary.Service.DeleteObjects(bucketName, fileKeys)
+	if err != nil {
+		return result, err
+	}
+	tasks := make([]Task, len(fileKeys))
+	for i, fileKey := range fileKeys {
+		tasks[i] = Task{Op: TaskSync, Bucket: bucketName, Key: fileKey}
+	}
+	return result, s.write(tasks...)
+}
+
+// CopyObject 复制对象
+func (s *Storage) CopyObject(input *storage.CopyObjectInput) error {
+	if err := s.primary.Service.CopyObject(input); err != nil {
+		return err
+	}
+	return s.write(Task{Op: TaskSync, Bucket: input.DestinationBucket, Key: input.DestinationKey})
+}
+
+// MoveObject 移动对象
+func (s *Storage) MoveObject(sourceBucket, sourceKey, destBucket, destKey string) error {
+	if err := s.primary.Service.MoveObject(sourceBucket, sourceKey, destBucket, destKey); err != nil {
+		return err
+	}
+	return s.write(
+		Task{Op: TaskSync, Bucket: destBucket, Key: destKey},
+		Task{Op: TaskSync, Bucket: sourceBucket, Key: sourceKey},
+	)
+}
+
+// CreateFolder 创建文件夹
+func (s *Storage) CreateFolder(bucketName, folderPath string) error {
+	if err := s.primary.Service.CreateFolder(bucketName, folderPath); err != nil {
+		return err
+	}
+	return s.write(Task{Op: TaskCreateFolder, Bucket: bucketName, Key: folderPath})
+}
+
+// DeleteFolder 删除文件夹及其所有内容
+func (s *Storage) DeleteFolder(bucketName, folderPath string) error {
+	if err := s.primary.Service.DeleteFolder(bucketName, folderPath); err != nil {
+		return err
+	}
+	return s.write(Task{Op: TaskDeleteFolder, Bucket: bucketName, Key: folderPath})
+}
+
+// SetObjectACL 设置对象ACL
+func (s *Storage) SetObjectACL(bucketName, fileKey, acl string) error {
+	if err := s.primary.Service.SetObjectACL(bucketName, fileKey, acl); err != nil {
+		return err
+	}
+	return s.write(Task{Op: TaskSetACL, Bucket: bucketName, Key: fileKey, ACL: acl})
+}
+
+// SetObjectMetadata 设置对象元数据
+func (s *Storage) SetObjectMetadata(bucketName, fileKey string, metadata map[string]string) error {
+	if err := s.primary.Service.SetObjectMetadata(bucketName, fileKey, metadata); err != nil {
+		return err
+	}
+	return s.write(Task{Op: TaskSync, Bucket: bucketName, Key: fileKey})
+}
+
+// ===== 读操作 =====
+
+// GetObject 获取文件，失败时回退到其他后端
+func (s *Storage) GetObject(bucketName, fileKey string) ([]byte, error) {
+	var data []byte
+	err := s.read(func(svc storage.StorageService) (err error) {
+		data, err = svc.GetObject(bucketName, fileKey)
+		return err
+	})
+	return data, err
+}
+
+// HeadObject 检查对象是否存在
+func (s *Storage) HeadObject(bucketName, fileKey string) bool {
+	exists, _ := s.ObjectExists(context.Background(), bucketName, fileKey)
+	return exists
+}
+
+// ObjectExists 检查对象是否存在，检查出错时回退到其他后端
+func (s *Storage) ObjectExists(ctx context.Context, bucketName, fileKey string) (bool, error) {
+	var exists bool
+	err := s.read(func(svc storage.StorageService) (err error) {
+		exists, err = storage.ObjectExists(ctx, svc, bucketName, fileKey)
+		if err == nil && !exists {
+			// 交给 read 判断副本中不存在时是否继续查找
+			return storage.ErrNotFound
+		}
+		return err
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	return exists, err
+}
+
+// ListObjects 列举对象
+func (s *Storage) ListObjects(input *storage.ListObjectsInput) (*storage.ListObjectsOutput, error) {
+	var output *storage.ListObjectsOutput
+	err := s.read(func(svc storage.StorageService) (err error) {
+		output, err = svc.ListObjects(input)
+		return err
+	})
+	return output, err
+}
+
+// GetObjectMetadata 获取对象元数据
+func (s *Storage) GetObjectMetadata(bucketName, fileKey string) (*storage.ObjectMetadata, error) {
+	var metadata *storage.ObjectMetadata
+	err := s.read(func(svc storage.StorageService) (err error) {
+		metadata, err = svc.GetObjectMetadata(bucketName, fileKey)
+		return err
+	})
+	return metadata, err
+}
+
+// ListFolders 列出文件夹
+func (s *Storage) ListFolders(bucketName, prefix string) ([]string, error) {
+	var folders []string
+	err := s.read(func(svc storage.StorageService) (err error) {
+		folders, err = svc.ListFolders(bucketName, prefix)
+		return err
+	})
+	return folders, err
+}
+
+// GetObjectACL 获取对象ACL
+func (s *Storage) GetObjectACL(bucketName, fileKey string) (string, error) {
+	var acl string
+	err := s.read(func(svc storage.StorageService) (err error) {
+		acl, err = svc.GetObjectACL(bucketName, fileKey)
+		return err
+	})
+	return acl, err
+}
+
+// PreSignGetObject 生成预签名下载链接，就近模式下指向延迟最低的后端
+func (s *Storage) PreSignGetObject(bucketName, fileKey string) (string, error) {
+	var url string
+	err := s.read(func(svc storage.StorageService) (err error) {
+		url, err = svc.PreSignGetObject(bucketName, fileKey)
+		return err
+	})
+	return url, err
+}
+
+// GenerateDownloadURL 生成下载链接，就近模式下指向延迟最低的后端
+func (s *Storage) GenerateDownloadURL(bucketName, fileKey string) string {
+	return s.readOrder()[0].Service.GenerateDownloadURL(bucketName, fileKey)
+}
+
+// ===== 只作用于主存储的操作 =====
+
+// PreSignPutObject 生成主存储的预签名上传链接
+func (s *Storage) PreSignPutObject(bucketName, fileKey string) (string, error) {
+	return s.primary.Service.PreSignPutObject(bucketName, fileKey)
+}
+
+// BatchPreSignPutObject 批量生成主存储的预签名上传链接
+func (s *Storage) BatchPreSignPutObject(bucketName string, fileKeys []string, isWholeKey bool) map[string]string {
+	return s.primary.Service.BatchPreSignPutObject(bucketName, fileKeys, isWholeKey)
+}
+
+// PreSignDeleteObject 生成主存储的预签名删除链接
+func (s *Storage) PreSignDeleteObject(bucketName, fileKey string) (string, error) {
+	return s.primary.Service.PreSignDeleteObject(bucketName, fileKey)
+}
+
+// PreSignPostPolicy 生成主存储的表单直传策略
+func (s *Storage) PreSignPostPolicy(bucketName, keyPrefix string, conditions storage.PostPolicyConditions) (*storage.PostPolicy, error) {
+	return s.primary.Service.PreSignPostPolicy(bucketName, keyPrefix, conditions)
+}
+
+// ===== 就近读取 =====
+
+// Probe 通过列举一个对象测量各后端的延迟，就近模式据此选择读取后端
+func (s *Storage) Probe(ctx context.Context, bucketName string) map[string]time.Duration {
+	result := make(map[string]time.Duration)
+	for i, backend := range s.backends() {
+		if ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
+		if _, err := backend.Service.ListObjects(&storage.ListObjectsInput{Bucket: bucketName, MaxKeys: 1}); err != nil {
+			continue
+		}
+		latency := time.Since(start)
+		s.observe(i, latency)
+		result[backend.Name] = latency
+	}
+	return result
+}
+
+// Latencies 返回各后端当前的读取延迟估计，尚未测量的后端不包含在内
+func (s *Storage) Latencies() map[string]time.Duration {
+	result := make(map[string]time.Duration)
+	for i, backend := range s.backends() {
+		if latency := s.latency[i].Load(); latency > 0 {
+			result[backend.Name] = time.Duration(latency)
+		}
+	}
+	return result
+}
+
+// ===== 辅助方法 =====
+
+// backends 主存储在前的全部后端
+func (s *Storage) backends() []Backend {
+	return append([]Backend{s.primary}, s.replicas...)
+}
+
+type indexedBackend struct {
+	Backend
+	index int
+}
+
+// readOrder 读取顺序：默认主存储优先，就近模式按延迟排序（未测量的后端排在最后）
+func (s *Storage) readOrder() []indexedBackend {
+	backends := s.backends()
+	order := make([]indexedBackend, len(backends))
+	for i, backend := range backends {
+		order[i] = indexedBackend{Backend: backend, index: i}
+	}
+	if s.opts.Nearest {
+		sort.SliceStable(order, func(i, j int) bool {
+			a, b := s.latency[order[i].index].Load(), s.latency[order[j].index].Load()
+			return a > 0 && (b == 0 || a < b)
+		})
+	}
+	return order
+}
+
+// read 按读取顺序尝试各后端。主存储返回 ErrNotFound 时以主存储为准直接返回，
+// 副本返回 ErrNotFound 可能只是复制尚未完成，继续尝试下一个后端
+func (s *Storage) read(fn func(svc storage.StorageService) error) error {
+	var firstErr error
+	for _, backend := range s.readOrder() {
+		start := time.Now()
+		err := fn(backend.Service)
+		if err == nil {
+			s.observe(backend.index, time.Since(start))
+			return nil
+		}
+		if backend.index == 0 && errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		if firstErr == nil || backend.index == 0 {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (s *Storage) observe(index int, latency time.Duration) {
+	for {
+		old := s.latency[index].Load()
+		updated := int64(latency)
+		if old > 0 {
+			updated = int64(float64(old)*(1-latencyWeight) + float64(latency)*latencyWeight)
+		}
+		if s.latency[index].CompareAndSwap(old, max(updated, 1)) {
+			return
+		}
+	}
+}
+
+// write 将复制任务应用到全部副本：异步模式入队，同步模式立即执行
+func (s *Storage) write(tasks ...Task) error {
+	var errs []error
+	for _, task := range tasks {
+		if s.opts.Mode == WriteAsync {
+			errs = append(errs, s.enqueue(task))
+			continue
+		}
+		errs = append(errs, s.replicate(task, func(replica Backend) error {
+			return s.apply(replica, task)
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+// replicate 同步写入全部副本，失败的副本进入重试队列
+func (s *Storage) replicate(task Task, fn func(replica Backend) error) error {
+	var errs []error
+	for _, replica := range s.replicas {
+		if err := fn(replica); err != nil {
+			task.Backend = replica.Name
+			errs = append(errs, fmt.Errorf("%w: %s: %v", ErrReplicaWrite, replica.Name, err))
+			if s.opts.OnError != nil {
+				s.opts.OnError(task, err)
+			}
+			if err := s.push(task); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// enqueue 为每个副本保存一个任务并交给后台协程
+func (s *Storage) enqueue(task Task) error {
+	for _, replica := range s.replicas {
+		task.Backend = replica.Name
+		if err := s.push(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) push(task Task) error {
+	task, err := s.opts.Queue.Push(task)
+	if err != nil {
+		return fmt.Errorf("replica: enqueue %s %s/%s: %w", task.Op, task.Bucket, task.Key, err)
+	}
+	s.dispatch(task)
+	return nil
+}
+
+// dispatch 将新任务交给后台协程，任务完成或放弃前计入 Flush
+func (s *Storage) dispatch(task Task) {
+	s.pending.Add(1)
+	s.send(task)
+}
+
+// send 按对象键分片投递，保证同一对象的任务由同一协程顺序执行
+func (s *Storage) send(task Task) {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		s.pending.Done()
+		return
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(task.Bucket + "/" + task.Key))
+	s.shards[hash.Sum32()%uint32(len(s.shards))] <- task
+}
+
+func (s *Storage) work(tasks <-chan Task) {
+	defer s.wg.Done()
+
+	attempts := make(map[uint64]int)
+	for {
+		select {
+		case <-s.stop:
+			return
+		case task := <-tasks:
+			err := s.apply(s.byName[task.Backend], task)
+			if err == nil {
+				delete(attempts, task.ID)
+				_ = s.opts.Queue.Ack(task.ID)
+				s.pending.Done()
+				continue
+			}
+
+			if s.opts.OnError != nil {
+				s.opts.OnError(task, err)
+			}
+			attempts[task.ID]++
+			if s.opts.MaxAttempts > 0 && attempts[task.ID] >= s.opts.MaxAttempts {
+				// 放弃该任务，差异留给 Reconcile 发现
+				delete(attempts, task.ID)
+				_ = s.opts.Queue.Ack(task.ID)
+				s.pending.Done()
+				continue
+			}
+			s.retryLater(task, attempts[task.ID])
+		}
+	}
+}
+
+// retryLater 退避后重新投递任务，等待期间任务仍计入 Flush
+func (s *Storage) retryLater(task Task, attempt int) {
+	delay := maxRetryDelay
+	if shift := attempt - 1; shift < 20 && s.opts.RetryDelay<<shift < maxRetryDelay {
+		delay = s.opts.RetryDelay << shift
+	}
+	time.AfterFunc(delay, func() { s.send(task) })
+}
+
+// apply 在副本上执行任务
+func (s *Storage) apply(replica Backend, task Task) error {
+	switch task.Op {
+	case TaskSync:
+		return s.syncObject(replica.Service, task.Bucket, task.Key)
+	case TaskCreateFolder:
+		return replica.Service.CreateFolder(task.Bucket, task.Key)
+	case TaskDeleteFolder:
+		return replica.Service.DeleteFolder(task.Bucket, task.Key)
+	case TaskSetACL:
+		return replica.Service.SetObjectACL(task.Bucket, task.Key, task.ACL)
+	default:
+		return fmt.Errorf("replica: unknown task op %q", task.Op)
+	}
+}
+
+// syncObject 将主存储中对象的当前内容和元数据写入副本，主存储中不存在时删除副本对象
+func (s *Storage) syncObject(replica storage.StorageService, bucketName, fileKey string) error {
+	data, err := s.primary.Service.GetObject(bucketName, fileKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		if err := replica.DeleteObject(bucketName, fileKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	metadata, err := s.primary.Service.GetObjectMetadata(bucketName, fileKey)
+	if err != nil {
+		return err
+	}
+	return upload(replica, bucketName, fileKey, data, storage.UploadObjectOptions{
+		ContentType: metadata.ContentType,
+		Metadata:    metadata.Metadata,
+	})
+}
+
+func upload(svc storage.StorageService, bucketName, fileKey string, data []byte, options storage.UploadObjectOptions) error {
+	if uploader, ok := svc.(storage.OptionsUploader); ok {
+		return uploader.UploadObjectWithOptions(bucketName, fileKey, data, options)
+	}
+	return svc.UploadObject(bucketName, fileKey, data)
+}
+
+var (
+	_ storage.StorageService   = (*Storage)(nil)
+	_ storage.OptionsUploader  = (*Storage)(nil)
+	_ storage.ExistenceChecker = (*Storage)(nil)
+)
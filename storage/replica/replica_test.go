@@ -0,0 +1,301 @@
+package replica
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+	storagesync "github.com/QingsiLiu/baseComponents/storage/sync"
+)
+
+var errUnavailable = errors.New("backend unavailable")
+
+// faulty 按 fail 决定操作是否失败，并统计调用次数
+func faulty(svc storage.StorageService, fail func(op string) bool) (storage.StorageService, *atomic.Int64) {
+	calls := new(atomic.Int64)
+	return storage.Chain(svc, storage.Intercept(func(call *storage.Call, next func() error) error {
+		calls.Add(1)
+		if fail(call.Op) {
+			return errUnavailable
+		}
+		return next()
+	})), calls
+}
+
+func flush(t *testing.T, s *Storage) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+}
+
+func TestSyncWriteReplicatesToAllReplicas(t *testing.T) {
+	primary, r1, r2 := memory.NewMemoryService(""), memory.NewMemoryService(""), memory.NewMemoryService("")
+	s, err := New(Backend{"primary", primary}, []Backend{{"r1", r1}, {"r2", r2}}, Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+
+	options := storage.UploadObjectOptions{ContentType: "text/plain", Metadata: map[string]string{"owner": "u1"}}
+	if err := s.UploadObjectWithOptions("b", "a.txt", []byte("hello"), options); err != nil {
+		t.Fatalf("UploadObjectWithOptions() error = %v", err)
+	}
+	if err := s.UploadObjectStream("b", "dir/b.txt", bytes.NewReader([]byte("stream"))); err != nil {
+		t.Fatalf("UploadObjectStream() error = %v", err)
+	}
+	for _, replica := range []*memory.MemoryService{r1, r2} {
+		metadata, err := replica.GetObjectMetadata("b", "a.txt")
+		if err != nil || metadata.Metadata["owner"] != "u1" {
+			t.Fatalf("replica metadata = %+v, %v", metadata, err)
+		}
+		if data, _ := replica.GetObject("b", "dir/b.txt"); string(data) != "stream" {
+			t.Fatalf("replica stream object = %q", data)
+		}
+	}
+
+	if err := s.DeleteFolder("b", "dir"); err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+	if err := s.DeleteObject("b", "a.txt"); err != nil {
+		t.Fatalf("DeleteObject() error = %v", err)
+	}
+	if r1.HeadObject("b", "a.txt") || r2.HeadObject("b", "dir/b.txt") {
+		t.Fatal("deletes should be replicated")
+	}
+}
+
+func TestSyncWriteFailureIsRetried(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	inner := memory.NewMemoryService("")
+	replica, _ := faulty(inner, func(string) bool { return down.Load() })
+
+	var failures atomic.Int64
+	s, _ := New(Backend{"primary", memory.NewMemoryService("")}, []Backend{{"r1", replica}}, Options{
+		RetryDelay: time.Millisecond,
+		OnError:    func(Task, error) { failures.Add(1) },
+	})
+	defer s.Close()
+
+	err := s.UploadObject("b", "a.txt", []byte("a"))
+	if !errors.Is(err, ErrReplicaWrite) {
+		t.Fatalf("UploadObject() error = %v, want ErrReplicaWrite", err)
+	}
+	if data, err := s.GetObject("b", "a.txt"); err != nil || string(data) != "a" {
+		t.Fatalf("primary write should succeed, got %q, %v", data, err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	down.Store(false)
+	flush(t, s)
+	if data, _ := inner.GetObject("b", "a.txt"); string(data) != "a" {
+		t.Fatalf("replica after retry = %q", data)
+	}
+	if failures.Load() == 0 {
+		t.Fatal("OnError should be called for failed attempts")
+	}
+}
+
+func TestAsyncWriteUsesDurableQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replica.queue")
+	primary, inner := memory.NewMemoryService(""), memory.NewMemoryService("")
+	replica, _ := faulty(inner, func(string) bool { return true })
+
+	queue, err := OpenFileQueue(path)
+	if err != nil {
+		t.Fatalf("OpenFileQueue() error = %v", err)
+	}
+	s, _ := New(Backend{"primary", primary}, []Backend{{"r1", replica}}, Options{Mode: WriteAsync, Queue: queue, RetryDelay: time.Hour})
+	if err := s.UploadObject("b", "a.txt", []byte("a")); err != nil {
+		t.Fatalf("async UploadObject() error = %v", err)
+	}
+	if err := s.SetObjectACL("b", "a.txt", "private"); err != nil {
+		t.Fatalf("async SetObjectACL() error = %v", err)
+	}
+	_ = s.Close()
+	_ = queue.Close()
+
+	// 重启后副本恢复，未完成的任务从队列恢复执行
+	queue, err = OpenFileQueue(path)
+	if err != nil {
+		t.Fatalf("reopen queue error = %v", err)
+	}
+	defer queue.Close()
+	if pending, _ := queue.Pending(); len(pending) != 2 || pending[0].Op != TaskSync || pending[1].ACL != "private" {
+		t.Fatalf("Pending() = %+v", pending)
+	}
+	s, _ = New(Backend{"primary", primary}, []Backend{{"r1", inner}}, Options{Mode: WriteAsync, Queue: queue})
+	defer s.Close()
+	flush(t, s)
+
+	if data, _ := inner.GetObject("b", "a.txt"); string(data) != "a" {
+		t.Fatalf("replica after restart = %q", data)
+	}
+	if pending, _ := queue.Pending(); len(pending) != 0 {
+		t.Fatalf("Pending() after flush = %+v", pending)
+	}
+}
+
+func TestFileQueueCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replica.queue")
+	queue, err := OpenFileQueue(path)
+	if err != nil {
+		t.Fatalf("OpenFileQueue() error = %v", err)
+	}
+	queue.compactN = 2
+
+	var last Task
+	for i := 0; i < 3; i++ {
+		last, _ = queue.Push(Task{Backend: "r1", Op: TaskSync, Bucket: "b", Key: "k"})
+	}
+	_ = queue.Ack(1)
+	_ = queue.Ack(2)
+	_ = queue.Close()
+
+	queue, err = OpenFileQueue(path)
+	if err != nil {
+		t.Fatalf("reopen queue error = %v", err)
+	}
+	defer queue.Close()
+	pending, _ := queue.Pending()
+	if len(pending) != 1 || pending[0].ID != last.ID {
+		t.Fatalf("Pending() after compaction = %+v", pending)
+	}
+	if next, _ := queue.Push(Task{Backend: "r1", Op: TaskSync}); next.ID != last.ID+1 {
+		t.Fatalf("next ID = %d, want %d", next.ID, last.ID+1)
+	}
+}
+
+func TestFileQueueTruncatesTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replica.queue")
+	queue, err := OpenFileQueue(path)
+	if err != nil {
+		t.Fatalf("OpenFileQueue() error = %v", err)
+	}
+	first, _ := queue.Push(Task{Backend: "r1", Op: TaskSync, Bucket: "b", Key: "a"})
+	_ = queue.Close()
+
+	// 模拟写入中断留下的不完整尾行
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.WriteString(`{"task":{"id":2,"backend":"r1"`)
+	_ = file.Close()
+
+	for i, key := range []string{"b", "c"} {
+		queue, err = OpenFileQueue(path)
+		if err != nil {
+			t.Fatalf("reopen %d error = %v", i, err)
+		}
+		if _, err := queue.Push(Task{Backend: "r1", Op: TaskSync, Bucket: "b", Key: key}); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+		_ = queue.Close()
+	}
+
+	queue, err = OpenFileQueue(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer queue.Close()
+	pending, _ := queue.Pending()
+	if len(pending) != 3 || pending[0].ID != first.ID || pending[1].Key != "b" || pending[2].Key != "c" {
+		t.Fatalf("Pending() after torn line = %+v", pending)
+	}
+}
+
+func TestReadFallsBackToReplica(t *testing.T) {
+	inner := memory.NewMemoryService("")
+	var down atomic.Bool
+	primary, _ := faulty(inner, func(op string) bool { return down.Load() && op != storage.OpUploadObjectWithOptions })
+	replica := memory.NewMemoryService("")
+	s, _ := New(Backend{"primary", primary}, []Backend{{"r1", replica}}, Options{})
+	defer s.Close()
+
+	_ = s.UploadObject("b", "a.txt", []byte("a"))
+	down.Store(true)
+	if data, err := s.GetObject("b", "a.txt"); err != nil || string(data) != "a" {
+		t.Fatalf("GetObject() with primary down = %q, %v", data, err)
+	}
+	if exists, err := s.ObjectExists(context.Background(), "b", "a.txt"); err != nil || !exists {
+		t.Fatalf("ObjectExists() with primary down = %v, %v", exists, err)
+	}
+
+	// 主存储可用时，主存储中不存在以主存储为准
+	down.Store(false)
+	_ = replica.UploadObject("b", "stale.txt", []byte("x"))
+	if _, err := s.GetObject("b", "stale.txt"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetObject() error = %v, want ErrNotFound from primary", err)
+	}
+}
+
+func TestNearestReadPrefersLowLatencyBackend(t *testing.T) {
+	slow := storage.Chain(memory.NewMemoryService(""), storage.Intercept(func(call *storage.Call, next func() error) error {
+		time.Sleep(20 * time.Millisecond)
+		return next()
+	}))
+	replica, calls := faulty(memory.NewMemoryService(""), func(string) bool { return false })
+	s, _ := New(Backend{"primary", slow}, []Backend{{"r1", replica}}, Options{Nearest: true})
+	defer s.Close()
+
+	_ = s.UploadObject("b", "a.txt", []byte("a"))
+	latencies := s.Probe(context.Background(), "b")
+	if latencies["primary"] <= latencies["r1"] {
+		t.Fatalf("Probe() = %v, primary should be slower", latencies)
+	}
+
+	before := calls.Load()
+	if data, err := s.GetObject("b", "a.txt"); err != nil || string(data) != "a" {
+		t.Fatalf("GetObject() = %q, %v", data, err)
+	}
+	if calls.Load() != before+1 {
+		t.Fatal("nearest read should be served by the replica")
+	}
+}
+
+func TestReconcileReportsAndRepairsDivergence(t *testing.T) {
+	primary, replica := memory.NewMemoryService(""), memory.NewMemoryService("")
+	s, _ := New(Backend{"primary", primary}, []Backend{{"r1", replica}}, Options{})
+	defer s.Close()
+
+	_ = s.UploadObject("b", "data/same.txt", []byte("same"))
+	_ = primary.UploadObject("b", "data/missing.txt", []byte("missing"))
+	_ = replica.UploadObject("b", "data/extra.txt", []byte("extra"))
+
+	reports, err := s.Reconcile(context.Background(), ReconcileOptions{Bucket: "b", Prefix: "data/"})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if !Diverged(reports) || len(reports[0].Divergent) != 2 {
+		t.Fatalf("Reconcile() = %+v", reports)
+	}
+	actions := map[string]storagesync.ActionType{}
+	for _, action := range reports[0].Divergent {
+		actions[action.Key] = action.Type
+	}
+	if actions["data/missing.txt"] != storagesync.ActionCopy || actions["data/extra.txt"] != storagesync.ActionDelete {
+		t.Fatalf("divergent actions = %v", actions)
+	}
+	if replica.HeadObject("b", "data/missing.txt") {
+		t.Fatal("report-only reconcile should not modify the replica")
+	}
+
+	if _, err := s.Reconcile(context.Background(), ReconcileOptions{Bucket: "b", Prefix: "data/", Repair: true}); err != nil {
+		t.Fatalf("Reconcile(repair) error = %v", err)
+	}
+	reports, _ = s.Reconcile(context.Background(), ReconcileOptions{Bucket: "b", Prefix: "data/"})
+	if Diverged(reports) {
+		t.Fatalf("replica still diverged after repair: %+v", reports)
+	}
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RetryOptions 重试选项
+type RetryOptions struct {
+	MaxAttempts int                   // 最大尝试次数（含首次），默认 3
+	BaseDelay   time.Duration         // 首次重试的退避时长，之后按指数增长，默认 100ms
+	MaxDelay    time.Duration         // 退避时长上限，默认 5s
+	Retryable   func(err error) bool  // 判断错误是否可重试，默认 IsTransientError
+	Sleep       func(d time.Duration) // 等待函数，默认 time.Sleep
+}
+
+// Retry 对瞬时错误按指数退避（带随机抖动）重试。
+// 不可回退的流式上传（Reader 未实现 io.Seeker）不会重试。
+func Retry(opts RetryOptions) Middleware {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 100 * time.Millisecond
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 5 * time.Second
+	}
+	if opts.Retryable == nil {
+		opts.Retryable = IsTransientError
+	}
+	if opts.Sleep == nil {
+		opts.Sleep = time.Sleep
+	}
+
+	return Intercept(func(call *Call, next func() error) error {
+		err := next()
+		for attempt := 1; err != nil && call.Retryable && attempt < opts.MaxAttempts && opts.Retryable(err); attempt++ {
+			opts.Sleep(backoff(opts.BaseDelay, opts.MaxDelay, attempt))
+			err = next()
+		}
+		return err
+	})
+}
+
+// backoff 第 attempt 次重试前的等待时长，在 [d/2, d] 内随机抖动
+func backoff(base, maxDelay time.Duration, attempt int) time.Duration {
+	delay := maxDelay
+	if shift := attempt - 1; shift < 32 && base<<shift > 0 && base<<shift < maxDelay {
+		delay = base << shift
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// IsTransientError 判断错误是否为可重试的瞬时错误：ErrThrottled、网络超时、连接重置、
+// HTTP 429 限流和 5xx 服务端错误
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrThrottled) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		return isTransientStatus(statusErr.HTTPStatusCode())
+	}
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) {
+		return temporary.Temporary()
+	}
+	return false
+}
+
+func isTransientStatus(code int) bool {
+	return code == 429 || code >= 500
+}
@@ -3,6 +3,7 @@ package s3
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
@@ -17,9 +18,14 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 )
 
-const defaultPresignTTL = 15 * time.Minute
+const (
+	defaultPresignTTL = 15 * time.Minute
+	// maxDeleteObjects DeleteObjects 单次请求的对象数量上限
+	maxDeleteObjects = 1000
+)
 
 // S3Options configures AWS S3-compatible object stores such as DigitalOcean Spaces.
 type S3Options struct {
@@ -34,12 +40,7 @@ type S3Options struct {
 }
 
 // UploadObjectOptions configures optional object metadata for uploads.
-type UploadObjectOptions struct {
-	ContentType  string
-	CacheControl string
-	ACL          string
-	Metadata     map[string]string
-}
+type UploadObjectOptions = storage.UploadObjectOptions
 
 // PreSignPutObjectRequest contains a presigned PUT URL and the headers the
 // caller must send with that PUT request.
@@ -153,9 +154,9 @@ func (s *S3Service) UploadObjectStream(bucketName, fileKey string, file io.Reade
 
 // UploadObjectStreamWithOptions 流式上传文件并设置可选对象元数据。
 func (s *S3Service) UploadObjectStreamWithOptions(bucketName, fileKey string, file io.Reader, options UploadObjectOptions) error {
-	contentType := options.ContentType
-	if contentType == "" {
-		contentType = storage.GetContentType(fileKey)
+	contentType, file, err := storage.PrepareUpload(fileKey, file, options)
+	if err != nil {
+		return err
 	}
 
 	input := &s3.PutObjectInput{
@@ -174,9 +175,9 @@ func (s *S3Service) UploadObjectStreamWithOptions(bucketName, fileKey string, fi
 		input.Metadata = options.Metadata
 	}
 
-	_, err := s.uploader.Upload(context.TODO(), input)
+	_, err = s.uploader.Upload(context.TODO(), input)
 
-	return err
+	return mapError(err)
 }
 
 // GetObject 从S3获取文件
@@ -186,13 +187,26 @@ func (s *S3Service) GetObject(bucketName, fileKey string) ([]byte, error) {
 		Key:    aws.String(fileKey),
 	})
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 	defer result.Body.Close()
 
 	return io.ReadAll(result.Body)
 }
 
+// GetObjectStream 获取文件内容的读取流
+func (s *S3Service) GetObjectStream(bucketName, fileKey string) (io.ReadCloser, error) {
+	result, err := s.client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(fileKey),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return result.Body, nil
+}
+
 // PreSignPutObject 生成预签名上传URL
 func (s *S3Service) PreSignPutObject(bucketName, fileKey string) (string, error) {
 	return s.PreSignPutObjectWithOptions(bucketName, fileKey, UploadObjectOptions{})
@@ -212,6 +226,11 @@ func (s *S3Service) PreSignPutObjectWithOptions(bucketName, fileKey string, opti
 func (s *S3Service) PreSignPutObjectRequestWithOptions(bucketName, fileKey string, options UploadObjectOptions) (*PreSignPutObjectRequest, error) {
 	presignClient := s3.NewPresignClient(s.client)
 
+	// 预签名时无法嗅探内容，配置白名单时必须声明 ContentType，由签名约束上传头
+	if len(options.AllowedContentTypes) > 0 && (options.ContentType == "" || !storage.ContentTypeAllowed(options.ContentType, options.AllowedContentTypes)) {
+		return nil, fmt.Errorf("%w: %q", storage.ErrContentTypeNotAllowed, options.ContentType)
+	}
+
 	input := &s3.PutObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(fileKey),
@@ -241,7 +260,7 @@ func (s *S3Service) PreSignPutObjectRequestWithOptions(bucketName, fileKey strin
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	return &PreSignPutObjectRequest{
@@ -302,29 +321,31 @@ func (s *S3Service) PreSignGetObject(bucketName, fileKey string) (string, error)
 	})
 
 	if err != nil {
-		return "", err
+		return "", mapError(err)
 	}
 
 	return request.URL, nil
 }
 
-// HeadObject 检查对象是否存在
+// HeadObject 检查对象是否存在，检查失败时同样返回 false，需要区分时使用 ObjectExists
 func (s *S3Service) HeadObject(bucketName, fileKey string) bool {
-	_, err := s.client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+	exists, _ := s.ObjectExists(context.TODO(), bucketName, fileKey)
+	return exists
+}
+
+// ObjectExists 检查对象是否存在，对象不存在时返回 false，存储桶不存在等其余错误原样返回
+func (s *S3Service) ObjectExists(ctx context.Context, bucketName, fileKey string) (bool, error) {
+	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(fileKey),
 	})
-
 	if err != nil {
-		// 检查是否是NoSuchKey错误或NotFound错误
-		if strings.Contains(err.Error(), "NoSuchKey") || strings.Contains(err.Error(), "NotFound") {
-			return false
+		if err = mapError(err); errors.Is(err, storage.ErrNotFound) {
+			return false, nil
 		}
-		// 其他错误也认为对象不存在
-		return false
+		return false, err
 	}
-
-	return true
+	return true, nil
 }
 
 // DeleteObject 删除单个对象
@@ -333,10 +354,10 @@ func (s *S3Service) DeleteObject(bucketName, fileKey string) error {
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(fileKey),
 	})
-	return err
+	return mapError(err)
 }
 
-// DeleteObjects 批量删除对象
+// DeleteObjects 批量删除对象，返回删除失败的对象key
 func (s *S3Service) DeleteObjects(bucketName string, fileKeys []string) ([]string, error) {
 	if len(fileKeys) == 0 {
 		return []string{}, nil
@@ -359,18 +380,18 @@ func (s *S3Service) DeleteObjects(bucketName string, fileKeys []string) ([]strin
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 
-	// 收集成功删除的对象键
-	var deletedKeys []string
-	for _, deleted := range result.Deleted {
-		if deleted.Key != nil {
-			deletedKeys = append(deletedKeys, *deleted.Key)
+	// 收集删除失败的对象键，与其他后端的返回值一致
+	failedKeys := []string{}
+	for _, deleteErr := range result.Errors {
+		if deleteErr.Key != nil {
+			failedKeys = append(failedKeys, *deleteErr.Key)
 		}
 	}
 
-	return deletedKeys, nil
+	return failedKeys, nil
 }
 
 // ListObjects 列出对象
@@ -388,13 +409,16 @@ func (s *S3Service) ListObjects(input *storage.ListObjectsInput) (*storage.ListO
 	if input.MaxKeys > 0 {
 		listInput.MaxKeys = aws.Int32(input.MaxKeys)
 	}
+	if input.StartAfter != "" {
+		listInput.StartAfter = aws.String(input.StartAfter)
+	}
 	if input.ContinuationToken != "" {
 		listInput.ContinuationToken = aws.String(input.ContinuationToken)
 	}
 
 	result, err := s.client.ListObjectsV2(context.TODO(), listInput)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	output := &storage.ListObjectsOutput{
@@ -431,15 +455,45 @@ func (s *S3Service) ListObjects(input *storage.ListObjectsInput) (*storage.ListO
 
 // CopyObject 复制对象
 func (s *S3Service) CopyObject(input *storage.CopyObjectInput) error {
-	copySource := fmt.Sprintf("%s/%s", input.SourceBucket, input.SourceKey)
-
-	_, err := s.client.CopyObject(context.TODO(), &s3.CopyObjectInput{
+	copyInput := &s3.CopyObjectInput{
 		Bucket:     aws.String(input.DestinationBucket),
 		Key:        aws.String(input.DestinationKey),
-		CopySource: aws.String(copySource),
-	})
+		CopySource: aws.String(copySource(input.SourceBucket, input.SourceKey)),
+	}
+	if input.StorageClass != "" {
+		copyInput.StorageClass = types.StorageClass(input.StorageClass)
+	}
+	if len(input.Metadata) > 0 {
+		// REPLACE 会重置全部系统元数据，未指定的 Content-Type 和 Cache-Control 沿用源对象
+		headResult, err := s.client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+			Bucket: aws.String(input.SourceBucket),
+			Key:    aws.String(input.SourceKey),
+		})
+		if err != nil {
+			return mapError(err)
+		}
+		copyInput.MetadataDirective = types.MetadataDirectiveReplace
+		copyInput.Metadata = input.Metadata
+		copyInput.ContentType = headResult.ContentType
+		copyInput.CacheControl = headResult.CacheControl
+		if input.ContentType != "" {
+			copyInput.ContentType = aws.String(input.ContentType)
+		}
+	}
+
+	_, err := s.client.CopyObject(context.TODO(), copyInput)
 
-	return err
+	return mapError(err)
+}
+
+// copySource 构造 x-amz-copy-source，对象键按路径段转义。
+// S3 会把 copy source 中的 "+" 解码为空格，需要单独转义
+func copySource(bucketName, fileKey string) string {
+	segments := strings.Split(fileKey, "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(url.PathEscape(segment), "+", "%2B")
+	}
+	return bucketName + "/" + strings.Join(segments, "/")
 }
 
 // MoveObject 移动对象（复制后删除源对象）
@@ -473,7 +527,7 @@ func (s *S3Service) GetObjectMetadata(bucketName, fileKey string) (*storage.Obje
 		Key:    aws.String(fileKey),
 	})
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	metadata := &storage.ObjectMetadata{
@@ -513,7 +567,7 @@ func (s *S3Service) CreateFolder(bucketName, folderPath string) error {
 		Body:   bytes.NewReader([]byte{}),
 	})
 
-	return err
+	return mapError(err)
 }
 
 // DeleteFolder 删除文件夹及其所有内容
@@ -523,59 +577,13 @@ func (s *S3Service) DeleteFolder(bucketName, folderPath string) error {
 		folderPath += "/"
 	}
 
-	// 列出文件夹下的所有对象
-	listInput := &storage.ListObjectsInput{
-		Bucket:  bucketName,
-		Prefix:  folderPath,
-		MaxKeys: 1000,
-	}
-
-	var allKeys []string
-	for {
-		result, err := s.ListObjects(listInput)
-		if err != nil {
-			return err
-		}
-
-		// 收集所有对象键
-		for _, obj := range result.Objects {
-			allKeys = append(allKeys, obj.Key)
-		}
-
-		// 如果没有更多对象，退出循环
-		if !result.IsTruncated {
-			break
-		}
-
-		listInput.ContinuationToken = result.NextContinuationToken
-	}
-
-	// 批量删除所有对象
-	if len(allKeys) > 0 {
-		_, err := s.DeleteObjects(bucketName, allKeys)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	// 边列举边删除，每批不超过 DeleteObjects 的 1000 个上限
+	return storage.DeletePrefix(context.TODO(), s, bucketName, folderPath, maxDeleteObjects)
 }
 
 // ListFolders 列出文件夹
 func (s *S3Service) ListFolders(bucketName, prefix string) ([]string, error) {
-	listInput := &storage.ListObjectsInput{
-		Bucket:    bucketName,
-		Prefix:    prefix,
-		Delimiter: "/",
-		MaxKeys:   1000,
-	}
-
-	result, err := s.ListObjects(listInput)
-	if err != nil {
-		return nil, err
-	}
-
-	return result.CommonPrefixes, nil
+	return storage.ListPrefixes(context.TODO(), s, bucketName, prefix)
 }
 
 // PreSignDeleteObject 生成删除对象的预签名URL
@@ -590,12 +598,49 @@ func (s *S3Service) PreSignDeleteObject(bucketName, fileKey string) (string, err
 	})
 
 	if err != nil {
-		return "", err
+		return "", mapError(err)
 	}
 
 	return request.URL, nil
 }
 
+// PreSignPostPolicy 生成浏览器表单直传的 POST Policy。
+// 表单中的 key 默认为 keyPrefix + ${filename}，客户端也可以改写为 keyPrefix 下的任意键。
+func (s *S3Service) PreSignPostPolicy(bucketName, keyPrefix string, conditions storage.PostPolicyConditions) (*storage.PostPolicy, error) {
+	presignClient := s3.NewPresignClient(s.client)
+
+	policyConditions := []interface{}{
+		[]interface{}{"starts-with", "$key", keyPrefix},
+	}
+	if conditions.ContentLengthMax > 0 {
+		policyConditions = append(policyConditions, []interface{}{"content-length-range", conditions.ContentLengthMin, conditions.ContentLengthMax})
+	}
+	if conditions.ContentTypePrefix != "" {
+		policyConditions = append(policyConditions, []interface{}{"starts-with", "$Content-Type", conditions.ContentTypePrefix})
+	}
+
+	expires := conditions.Expires
+	if expires <= 0 {
+		expires = s.presignDuration()
+	}
+
+	request, err := presignClient.PresignPostObject(context.TODO(), &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(keyPrefix + storage.PostPolicyFilename),
+	}, func(opts *s3.PresignPostOptions) {
+		opts.Expires = expires
+		opts.Conditions = policyConditions
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return &storage.PostPolicy{
+		URL:    request.URL,
+		Fields: request.Values,
+	}, nil
+}
+
 // SetObjectACL 设置对象ACL
 func (s *S3Service) SetObjectACL(bucketName, fileKey, acl string) error {
 	_, err := s.client.PutObjectAcl(context.TODO(), &s3.PutObjectAclInput{
@@ -603,7 +648,7 @@ func (s *S3Service) SetObjectACL(bucketName, fileKey, acl string) error {
 		Key:    aws.String(fileKey),
 		ACL:    types.ObjectCannedACL(acl),
 	})
-	return err
+	return mapError(err)
 }
 
 // GetObjectACL 获取对象ACL
@@ -613,7 +658,7 @@ func (s *S3Service) GetObjectACL(bucketName, fileKey string) (string, error) {
 		Key:    aws.String(fileKey),
 	})
 	if err != nil {
-		return "", err
+		return "", mapError(err)
 	}
 
 	// 简化返回，实际使用中可能需要更详细的ACL信息
@@ -632,24 +677,25 @@ func (s *S3Service) SetObjectMetadata(bucketName, fileKey string, metadata map[s
 		Key:    aws.String(fileKey),
 	})
 	if err != nil {
-		return err
+		return mapError(err)
 	}
 
 	// 复制对象并设置新的元数据
-	copySource := fmt.Sprintf("%s/%s", bucketName, fileKey)
 	_, err = s.client.CopyObject(context.TODO(), &s3.CopyObjectInput{
 		Bucket:            aws.String(bucketName),
 		Key:               aws.String(fileKey),
-		CopySource:        aws.String(copySource),
+		CopySource:        aws.String(copySource(bucketName, fileKey)),
 		Metadata:          metadata,
 		MetadataDirective: types.MetadataDirectiveReplace,
 		ContentType:       headResult.ContentType,
+		CacheControl:      headResult.CacheControl,
 	})
 
-	return err
+	return mapError(err)
 }
 
 // GenerateDownloadURL 生成下载URL。配置 PublicBaseURL 时返回公开 CDN URL，否则返回预签名 URL。
+// 签名的 CDN 链接使用 storage.SignDownloadURLs 中间件
 func (s *S3Service) GenerateDownloadURL(bucketName, fileKey string) string {
 	if s.publicBaseURL != "" {
 		publicURL, err := url.JoinPath(s.publicBaseURL, fileKey)
@@ -665,9 +711,236 @@ func (s *S3Service) GenerateDownloadURL(bucketName, fileKey string) string {
 	return url
 }
 
+// PutObjectTags 覆盖设置对象标签
+func (s *S3Service) PutObjectTags(bucketName, fileKey string, tags map[string]string) error {
+	tagSet := make([]types.Tag, 0, len(tags))
+	for key, value := range tags {
+		tagSet = append(tagSet, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	_, err := s.client.PutObjectTagging(context.TODO(), &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(bucketName),
+		Key:     aws.String(fileKey),
+		Tagging: &types.Tagging{TagSet: tagSet},
+	})
+	return mapError(err)
+}
+
+// GetObjectTags 获取对象标签
+func (s *S3Service) GetObjectTags(bucketName, fileKey string) (map[string]string, error) {
+	result, err := s.client.GetObjectTagging(context.TODO(), &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(fileKey),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	tags := make(map[string]string, len(result.TagSet))
+	for _, tag := range result.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags, nil
+}
+
+// ===== 版本控制 =====
+
+// SetBucketVersioning 开启或暂停存储桶版本控制（S3 开启后只能暂停，不能关闭）
+func (s *S3Service) SetBucketVersioning(bucketName string, enabled bool) error {
+	status := types.BucketVersioningStatusSuspended
+	if enabled {
+		status = types.BucketVersioningStatusEnabled
+	}
+	_, err := s.client.PutBucketVersioning(context.TODO(), &s3.PutBucketVersioningInput{
+		Bucket:                  aws.String(bucketName),
+		VersioningConfiguration: &types.VersioningConfiguration{Status: status},
+	})
+	return mapError(err)
+}
+
+// ListObjectVersions 列举前缀下全部对象的版本和删除标记，自动翻页
+func (s *S3Service) ListObjectVersions(bucketName, prefix string) ([]storage.ObjectVersion, error) {
+	paginator := s3.NewListObjectVersionsPaginator(s.client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	})
+
+	versions := []storage.ObjectVersion{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, mapError(err)
+		}
+		for _, version := range page.Versions {
+			versions = append(versions, storage.ObjectVersion{
+				Key:          aws.ToString(version.Key),
+				VersionID:    aws.ToString(version.VersionId),
+				Size:         aws.ToInt64(version.Size),
+				LastModified: aws.ToTime(version.LastModified),
+				ETag:         aws.ToString(version.ETag),
+				IsLatest:     aws.ToBool(version.IsLatest),
+			})
+		}
+		for _, marker := range page.DeleteMarkers {
+			versions = append(versions, storage.ObjectVersion{
+				Key:            aws.ToString(marker.Key),
+				VersionID:      aws.ToString(marker.VersionId),
+				LastModified:   aws.ToTime(marker.LastModified),
+				IsLatest:       aws.ToBool(marker.IsLatest),
+				IsDeleteMarker: true,
+			})
+		}
+	}
+	return versions, nil
+}
+
+// GetObjectVersion 获取指定版本的内容
+func (s *S3Service) GetObjectVersion(bucketName, fileKey, versionID string) ([]byte, error) {
+	result, err := s.client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(fileKey),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer result.Body.Close()
+
+	return io.ReadAll(result.Body)
+}
+
+// RestoreObject 将指定版本复制为当前版本，versionID 为空时恢复最近一个非删除标记的版本
+func (s *S3Service) RestoreObject(bucketName, fileKey, versionID string) error {
+	if versionID == "" {
+		versions, err := s.ListObjectVersions(bucketName, fileKey)
+		if err != nil {
+			return err
+		}
+		version, err := storage.FindRestoreVersion(versions, fileKey, "")
+		if err != nil {
+			return err
+		}
+		versionID = version.VersionID
+	}
+
+	_, err := s.client.CopyObject(context.TODO(), &s3.CopyObjectInput{
+		Bucket:     aws.String(bucketName),
+		Key:        aws.String(fileKey),
+		CopySource: aws.String(copySource(bucketName, fileKey) + "?versionId=" + url.QueryEscape(versionID)),
+	})
+	return mapError(err)
+}
+
+// ===== 生命周期管理 =====
+
+// GetBucketLifecycle 获取存储桶生命周期规则，未配置时返回空列表
+func (s *S3Service) GetBucketLifecycle(bucketName string) ([]storage.LifecycleRule, error) {
+	result, err := s.client.GetBucketLifecycleConfiguration(context.TODO(), &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchLifecycleConfiguration" {
+			return []storage.LifecycleRule{}, nil
+		}
+		return nil, mapError(err)
+	}
+
+	rules := make([]storage.LifecycleRule, 0, len(result.Rules))
+	for _, rule := range result.Rules {
+		converted := storage.LifecycleRule{
+			ID:      aws.ToString(rule.ID),
+			Prefix:  aws.ToString(rule.Prefix),
+			Enabled: rule.Status == types.ExpirationStatusEnabled,
+		}
+		if rule.Filter != nil && rule.Filter.Prefix != nil {
+			converted.Prefix = *rule.Filter.Prefix
+		}
+		if rule.Expiration != nil {
+			converted.ExpirationDays = int(aws.ToInt32(rule.Expiration.Days))
+		}
+		for _, transition := range rule.Transitions {
+			converted.Transitions = append(converted.Transitions, storage.LifecycleTransition{
+				Days:         int(aws.ToInt32(transition.Days)),
+				StorageClass: string(transition.StorageClass),
+			})
+		}
+		if rule.AbortIncompleteMultipartUpload != nil {
+			converted.AbortIncompleteMultipartUploadDays = int(aws.ToInt32(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation))
+		}
+		rules = append(rules, converted)
+	}
+	return rules, nil
+}
+
+// PutBucketLifecycle 覆盖设置存储桶生命周期规则
+func (s *S3Service) PutBucketLifecycle(bucketName string, rules []storage.LifecycleRule) error {
+	if len(rules) == 0 {
+		return s.DeleteBucketLifecycle(bucketName)
+	}
+
+	s3Rules := make([]types.LifecycleRule, 0, len(rules))
+	for _, rule := range rules {
+		s3Rule := types.LifecycleRule{
+			ID:     aws.String(rule.ID),
+			Filter: &types.LifecycleRuleFilter{Prefix: aws.String(rule.Prefix)},
+			Status: types.ExpirationStatusDisabled,
+		}
+		if rule.Enabled {
+			s3Rule.Status = types.ExpirationStatusEnabled
+		}
+		if rule.ExpirationDays > 0 {
+			s3Rule.Expiration = &types.LifecycleExpiration{Days: aws.Int32(int32(rule.ExpirationDays))}
+		}
+		for _, transition := range rule.Transitions {
+			s3Rule.Transitions = append(s3Rule.Transitions, types.Transition{
+				Days:         aws.Int32(int32(transition.Days)),
+				StorageClass: types.TransitionStorageClass(transition.StorageClass),
+			})
+		}
+		if rule.AbortIncompleteMultipartUploadDays > 0 {
+			s3Rule.AbortIncompleteMultipartUpload = &types.AbortIncompleteMultipartUpload{
+				DaysAfterInitiation: aws.Int32(int32(rule.AbortIncompleteMultipartUploadDays)),
+			}
+		}
+		s3Rules = append(s3Rules, s3Rule)
+	}
+
+	_, err := s.client.PutBucketLifecycleConfiguration(context.TODO(), &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(bucketName),
+		LifecycleConfiguration: &types.BucketLifecycleConfiguration{Rules: s3Rules},
+	})
+	return mapError(err)
+}
+
+// DeleteBucketLifecycle 删除存储桶全部生命周期规则
+func (s *S3Service) DeleteBucketLifecycle(bucketName string) error {
+	_, err := s.client.DeleteBucketLifecycle(context.TODO(), &s3.DeleteBucketLifecycleInput{
+		Bucket: aws.String(bucketName),
+	})
+	return mapError(err)
+}
+
 func (s *S3Service) presignDuration() time.Duration {
 	if s.presignTTL > 0 {
 		return s.presignTTL
 	}
 	return defaultPresignTTL
 }
+
+// mapError 将 AWS SDK 错误归类为 storage.ErrNotFound 等分类错误
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var code string
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code = apiErr.ErrorCode()
+	}
+	var status int
+	var respErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &respErr) {
+		status = respErr.HTTPStatusCode()
+	}
+	return storage.ClassifyHTTPError(err, status, code)
+}
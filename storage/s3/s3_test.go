@@ -3,7 +3,12 @@ package s3
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"strings"
@@ -11,7 +16,10 @@ import (
 	"time"
 
 	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/utils"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 )
 
 // TestNewS3Service 测试创建S3服务实例
@@ -137,6 +145,190 @@ func TestGenerateDownloadURLUsesPublicBaseURL(t *testing.T) {
 	}
 }
 
+func TestGenerateDownloadURLWithSignDownloadURLs(t *testing.T) {
+	service, err := NewS3ServiceWithOptions(S3Options{
+		Region:          "nyc3",
+		Endpoint:        "https://nyc3.digitaloceanspaces.com",
+		AccessKeyID:     "test-access-key",
+		SecretAccessKey: "test-secret-key",
+		PublicBaseURL:   "https://cdn.polai.example/media",
+	})
+	if err != nil {
+		t.Fatalf("NewS3ServiceWithOptions failed: %v", err)
+	}
+	signed := storage.Chain(service, storage.SignDownloadURLs(&storage.TokenSigner{
+		BaseURL: "https://cdn.polai.example",
+		Type:    storage.TokenAuthC,
+		Key:     "secret",
+		Now:     func() time.Time { return time.Unix(0x5f000000, 0) },
+	}, storage.SignOptions{}))
+
+	got := signed.GenerateDownloadURL("polai-public-media-prod", "results/output.jpg")
+	want := "https://cdn.polai.example/" + utils.MD5String("secret/results/output.jpg5f000000") + "/5f000000/results/output.jpg"
+	if got != want {
+		t.Fatalf("GenerateDownloadURL = %q, want %q", got, want)
+	}
+}
+
+func TestPreSignPostPolicyRestrictsKeyPrefixSizeAndContentType(t *testing.T) {
+	service, err := NewS3ServiceWithOptions(S3Options{
+		Region:          "nyc3",
+		Endpoint:        "https://nyc3.digitaloceanspaces.com",
+		AccessKeyID:     "test-access-key",
+		SecretAccessKey: "test-secret-key",
+	})
+	if err != nil {
+		t.Fatalf("NewS3ServiceWithOptions failed: %v", err)
+	}
+
+	policy, err := service.PreSignPostPolicy("polai-private-media-prod", "uploads/user-1/", storage.PostPolicyConditions{
+		ContentLengthMin:  1,
+		ContentLengthMax:  10 << 20,
+		ContentTypePrefix: "image/",
+		Expires:           10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("PreSignPostPolicy failed: %v", err)
+	}
+
+	parsed, err := url.Parse(policy.URL)
+	if err != nil {
+		t.Fatalf("post policy URL is invalid: %v", err)
+	}
+	if parsed.Host != "polai-private-media-prod.nyc3.digitaloceanspaces.com" {
+		t.Fatalf("host = %q, want virtual-hosted DO endpoint", parsed.Host)
+	}
+	if got := policy.Fields["key"]; got != "uploads/user-1/${filename}" {
+		t.Fatalf("key field = %q, want prefix with filename placeholder", got)
+	}
+	for _, field := range []string{"policy", "X-Amz-Signature", "X-Amz-Credential", "X-Amz-Algorithm", "X-Amz-Date"} {
+		if policy.Fields[field] == "" {
+			t.Fatalf("field %s is missing", field)
+		}
+	}
+
+	document, err := base64.StdEncoding.DecodeString(policy.Fields["policy"])
+	if err != nil {
+		t.Fatalf("policy is not base64: %v", err)
+	}
+	for _, condition := range []string{
+		`["starts-with","$key","uploads/user-1/"]`,
+		`["content-length-range",1,10485760]`,
+		`["starts-with","$Content-Type","image/"]`,
+	} {
+		if !strings.Contains(string(document), condition) {
+			t.Fatalf("policy document %s does not contain %s", document, condition)
+		}
+	}
+}
+
+func TestCopyObjectKeepsContentTypeAndEscapesKey(t *testing.T) {
+	var copyHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead:
+			w.Header().Set("Content-Type", "video/mp4")
+			w.Header().Set("Cache-Control", "max-age=3600")
+		case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+			copyHeaders = r.Header.Clone()
+			_, _ = io.WriteString(w, `<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	service, err := NewS3ServiceWithOptions(S3Options{
+		Region:          "us-east-1",
+		Endpoint:        server.URL,
+		AccessKeyID:     "test-access-key",
+		SecretAccessKey: "test-secret-key",
+		UsePathStyle:    true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3ServiceWithOptions failed: %v", err)
+	}
+
+	err = service.CopyObject(&storage.CopyObjectInput{
+		SourceBucket:      "src",
+		SourceKey:         "videos/a b+c?.mp4",
+		DestinationBucket: "dst",
+		DestinationKey:    "videos/copy.mp4",
+		Metadata:          map[string]string{"owner": "ops"},
+	})
+	if err != nil {
+		t.Fatalf("CopyObject failed: %v", err)
+	}
+	if got := copyHeaders.Get("X-Amz-Copy-Source"); got != "src/videos/a%20b%2Bc%3F.mp4" {
+		t.Fatalf("copy source = %q, want escaped key", got)
+	}
+	if got := copyHeaders.Get("X-Amz-Metadata-Directive"); got != "REPLACE" {
+		t.Fatalf("metadata directive = %q, want REPLACE", got)
+	}
+	if got := copyHeaders.Get("Content-Type"); got != "video/mp4" {
+		t.Fatalf("content type = %q, want source content type", got)
+	}
+	if got := copyHeaders.Get("Cache-Control"); got != "max-age=3600" {
+		t.Fatalf("cache control = %q, want source cache control", got)
+	}
+}
+
+func TestReaperReportsS3DeleteResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+			_, _ = io.WriteString(w, `<ListBucketResult><Name>bucket</Name><KeyCount>3</KeyCount><IsTruncated>false</IsTruncated>`+
+				`<Contents><Key>tmp/a</Key><Size>1</Size><LastModified>2020-01-01T00:00:00Z</LastModified></Contents>`+
+				`<Contents><Key>tmp/b</Key><Size>1</Size><LastModified>2020-01-01T00:00:00Z</LastModified></Contents>`+
+				`<Contents><Key>tmp/c</Key><Size>1</Size><LastModified>2020-01-01T00:00:00Z</LastModified></Contents>`+
+				`</ListBucketResult>`)
+		case r.Method == http.MethodPost && r.URL.Query().Has("delete"):
+			// S3 在 Deleted 中返回成功的键，在 Error 中返回失败的键
+			_, _ = io.WriteString(w, `<DeleteResult>`+
+				`<Deleted><Key>tmp/a</Key></Deleted><Deleted><Key>tmp/c</Key></Deleted>`+
+				`<Error><Key>tmp/b</Key><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`+
+				`</DeleteResult>`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	service, err := NewS3ServiceWithOptions(S3Options{
+		Region:          "us-east-1",
+		Endpoint:        server.URL,
+		AccessKeyID:     "test-access-key",
+		SecretAccessKey: "test-secret-key",
+		UsePathStyle:    true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3ServiceWithOptions failed: %v", err)
+	}
+
+	failed, err := service.DeleteObjects("bucket", []string{"tmp/a", "tmp/b", "tmp/c"})
+	if err != nil {
+		t.Fatalf("DeleteObjects failed: %v", err)
+	}
+	if len(failed) != 1 || failed[0] != "tmp/b" {
+		t.Fatalf("failed keys = %v, want [tmp/b]", failed)
+	}
+
+	reaper, err := storage.NewReaper(service, storage.ReaperOptions{
+		Bucket: "bucket",
+		Rules:  []storage.ReaperRule{{Prefix: "tmp/", MaxAge: time.Hour}},
+	})
+	if err != nil {
+		t.Fatalf("NewReaper failed: %v", err)
+	}
+	report, err := reaper.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Deleted != 2 || len(report.Failed) != 1 || report.Failed[0] != "tmp/b" {
+		t.Fatalf("report = %+v, want 2 deleted and tmp/b failed", report)
+	}
+}
+
 // TestS3ServiceWithRealAWS 集成测试（需要真实的AWS凭证和测试桶）
 func TestS3ServiceWithRealAWS(t *testing.T) {
 	// 检查环境变量
@@ -483,13 +675,13 @@ func TestS3ServiceWithRealAWS(t *testing.T) {
 		}
 
 		// 批量删除
-		deletedKeys, err := service.DeleteObjects(bucketName, testKeys)
+		failedKeys, err := service.DeleteObjects(bucketName, testKeys)
 		if err != nil {
 			t.Fatalf("Failed to batch delete objects: %v", err)
 		}
 
-		if len(deletedKeys) != len(testKeys) {
-			t.Fatalf("Expected %d deleted keys, got %d", len(testKeys), len(deletedKeys))
+		if len(failedKeys) != 0 {
+			t.Fatalf("Expected no failed keys, got %v", failedKeys)
 		}
 
 		// 验证文件已删除
@@ -533,3 +725,29 @@ func TestConfigLoading(t *testing.T) {
 		t.Log("Warning: No AWS region configured")
 	}
 }
+
+func TestMapErrorClassifiesAPIErrors(t *testing.T) {
+	cases := []struct {
+		err  error
+		want error
+	}{
+		{&types.NoSuchKey{}, storage.ErrNotFound},
+		{&types.NoSuchBucket{}, storage.ErrBucketNotFound},
+		{&smithy.GenericAPIError{Code: "AccessDenied"}, storage.ErrAccessDenied},
+		{&smithy.GenericAPIError{Code: "PreconditionFailed"}, storage.ErrPreconditionFailed},
+		{&smithy.GenericAPIError{Code: "SlowDown"}, storage.ErrThrottled},
+	}
+	for _, tc := range cases {
+		got := mapError(tc.err)
+		if !errors.Is(got, tc.want) {
+			t.Errorf("mapError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+		var apiErr smithy.APIError
+		if !errors.As(got, &apiErr) {
+			t.Errorf("mapError(%v) should keep the SDK error", tc.err)
+		}
+	}
+	if err := mapError(&smithy.GenericAPIError{Code: "InternalError"}); storage.IsNotFound(err) {
+		t.Fatalf("unclassified error = %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTrashPrefix 软删除对象默认移动到的前缀
+	DefaultTrashPrefix = ".trash/"
+	// MetadataDeletedAt 软删除时写入的删除时间（RFC3339）
+	MetadataDeletedAt = "deleted-at"
+)
+
+// SoftDeleteOptions 软删除选项
+type SoftDeleteOptions struct {
+	TrashPrefix string           // 回收站前缀，默认 DefaultTrashPrefix
+	TTL         time.Duration    // 回收站对象保留时长，Purge 删除超过该时长的对象；0 表示不清理
+	Now         func() time.Time // 当前时间，默认 time.Now
+}
+
+// SoftDeleteStorage 软删除包装：删除对象时先移动到回收站前缀，可在 TTL 内恢复。
+// 列举时隐藏回收站中的对象；回收站内的对象被删除时直接永久删除。
+type SoftDeleteStorage struct {
+	StorageService
+	opts SoftDeleteOptions
+}
+
+// NewSoftDeleteStorage 创建软删除包装
+func NewSoftDeleteStorage(svc StorageService, opts SoftDeleteOptions) *SoftDeleteStorage {
+	if opts.TrashPrefix == "" {
+		opts.TrashPrefix = DefaultTrashPrefix
+	}
+	if !strings.HasSuffix(opts.TrashPrefix, "/") {
+		opts.TrashPrefix += "/"
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &SoftDeleteStorage{StorageService: svc, opts: opts}
+}
+
+// TrashKey 返回对象在回收站中的键
+func (s *SoftDeleteStorage) TrashKey(fileKey string) string {
+	return s.opts.TrashPrefix + fileKey
+}
+
+// DeleteObject 将对象移动到回收站，对象不存在时不报错
+func (s *SoftDeleteStorage) DeleteObject(bucketName, fileKey string) error {
+	if s.inTrash(fileKey) {
+		return s.StorageService.DeleteObject(bucketName, fileKey)
+	}
+	exists, err := ObjectExists(context.Background(), s.StorageService, bucketName, fileKey)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	metadata, err := s.StorageService.GetObjectMetadata(bucketName, fileKey)
+	if err != nil {
+		return err
+	}
+	trashMetadata := make(map[string]string, len(metadata.Metadata)+1)
+	for k, v := range metadata.Metadata {
+		trashMetadata[k] = v
+	}
+	trashMetadata[MetadataDeletedAt] = s.opts.Now().UTC().Format(time.RFC3339)
+
+	if err := s.StorageService.CopyObject(&CopyObjectInput{
+		SourceBucket:      bucketName,
+		SourceKey:         fileKey,
+		DestinationBucket: bucketName,
+		DestinationKey:    s.TrashKey(fileKey),
+		ContentType:       metadata.ContentType,
+		Metadata:          trashMetadata,
+	}); err != nil {
+		return fmt.Errorf("failed to move object to trash: %w", err)
+	}
+	return s.StorageService.DeleteObject(bucketName, fileKey)
+}
+
+// DeleteObjects 逐个软删除对象，返回删除失败的对象key
+func (s *SoftDeleteStorage) DeleteObjects(bucketName string, fileKeys []string) ([]string, error) {
+	failed := []string{}
+	var errs []error
+	for _, key := range fileKeys {
+		if err := s.DeleteObject(bucketName, key); err != nil {
+			failed = append(failed, key)
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return failed, errors.Join(errs...)
+}
+
+// DeleteFolder 将文件夹下的全部对象移动到回收站
+func (s *SoftDeleteStorage) DeleteFolder(bucketName, folderPath string) error {
+	if !strings.HasSuffix(folderPath, "/") {
+		folderPath += "/"
+	}
+
+	var keys []string
+	err := Walk(context.Background(), s, &ListObjectsInput{Bucket: bucketName, Prefix: folderPath}, func(object ObjectInfo) error {
+		keys = append(keys, object.Key)
+		return nil
+	}, WithRecursive(true))
+	if err != nil {
+		return err
+	}
+	_, err = s.DeleteObjects(bucketName, keys)
+	return err
+}
+
+// ListObjects 列举对象，隐藏回收站中的对象；直接列举回收站前缀时不过滤
+func (s *SoftDeleteStorage) ListObjects(input *ListObjectsInput) (*ListObjectsOutput, error) {
+	if input == nil || s.inTrash(input.Prefix) {
+		return s.StorageService.ListObjects(input)
+	}
+
+	listInput := *input
+	for {
+		page, err := s.StorageService.ListObjects(&listInput)
+		if err != nil {
+			return nil, err
+		}
+
+		filtered := *page
+		filtered.Objects = make([]ObjectInfo, 0, len(page.Objects))
+		filtered.CommonPrefixes = make([]string, 0, len(page.CommonPrefixes))
+		for _, object := range page.Objects {
+			if !s.inTrash(object.Key) {
+				filtered.Objects = append(filtered.Objects, object)
+			}
+		}
+		for _, prefix := range page.CommonPrefixes {
+			if !s.inTrash(prefix) {
+				filtered.CommonPrefixes = append(filtered.CommonPrefixes, prefix)
+			}
+		}
+		filtered.KeyCount = int32(len(filtered.Objects) + len(filtered.CommonPrefixes))
+
+		// 整页都在回收站中时继续翻页，避免调用方误以为列举结束
+		if filtered.KeyCount > 0 || !page.IsTruncated {
+			return &filtered, nil
+		}
+		last := ""
+		if n := len(page.Objects); n > 0 {
+			last = page.Objects[n-1].Key
+		}
+		if n := len(page.CommonPrefixes); n > 0 && page.CommonPrefixes[n-1] > last {
+			last = page.CommonPrefixes[n-1]
+		}
+		if page.NextContinuationToken != "" {
+			listInput.ContinuationToken = page.NextContinuationToken
+		} else if last != "" {
+			listInput.ContinuationToken = ""
+			listInput.StartAfter = last
+		} else {
+			return &filtered, nil
+		}
+	}
+}
+
+// ListFolders 列举文件夹，不包含回收站
+func (s *SoftDeleteStorage) ListFolders(bucketName, prefix string) ([]string, error) {
+	return ListPrefixes(context.Background(), s, bucketName, prefix)
+}
+
+// Restore 将回收站中的对象恢复到原位置，并移除删除时间元数据
+func (s *SoftDeleteStorage) Restore(bucketName, fileKey string) error {
+	trashKey := s.TrashKey(fileKey)
+	metadata, err := s.StorageService.GetObjectMetadata(bucketName, trashKey)
+	if err != nil {
+		return err
+	}
+	restored := make(map[string]string, len(metadata.Metadata))
+	for k, v := range metadata.Metadata {
+		if !strings.EqualFold(k, MetadataDeletedAt) {
+			restored[k] = v
+		}
+	}
+
+	if err := s.StorageService.CopyObject(&CopyObjectInput{
+		SourceBucket:      bucketName,
+		SourceKey:         trashKey,
+		DestinationBucket: bucketName,
+		DestinationKey:    fileKey,
+		ContentType:       metadata.ContentType,
+		Metadata:          restored,
+	}); err != nil {
+		return fmt.Errorf("failed to restore object from trash: %w", err)
+	}
+	// 元数据为空时 CopyObject 保留源元数据，需要单独清除删除时间
+	if len(restored) == 0 {
+		if err := s.StorageService.SetObjectMetadata(bucketName, fileKey, restored); err != nil {
+			return err
+		}
+	}
+	return s.StorageService.DeleteObject(bucketName, trashKey)
+}
+
+// ListTrash 列举回收站中 prefix 下的对象，返回的 Key 为原始键
+func (s *SoftDeleteStorage) ListTrash(ctx context.Context, bucketName, prefix string) ([]ObjectInfo, error) {
+	objects := []ObjectInfo{}
+	err := Walk(ctx, s.StorageService, &ListObjectsInput{Bucket: bucketName, Prefix: s.TrashKey(prefix)}, func(object ObjectInfo) error {
+		object.Key = strings.TrimPrefix(object.Key, s.opts.TrashPrefix)
+		objects = append(objects, object)
+		return nil
+	}, WithRecursive(true))
+	return objects, err
+}
+
+// Purge 永久删除回收站中超过 TTL 的对象。对象移入回收站时重新写入，最后修改时间即删除时间
+func (s *SoftDeleteStorage) Purge(ctx context.Context, bucketName string) (*ReapReport, error) {
+	if s.opts.TTL <= 0 {
+		return nil, errors.New("storage: soft delete TTL is not set")
+	}
+	reaper, err := NewReaper(s.StorageService, ReaperOptions{
+		Bucket: bucketName,
+		Rules:  []ReaperRule{{Prefix: s.opts.TrashPrefix, MaxAge: s.opts.TTL}},
+		Now:    s.opts.Now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reaper.Run(ctx)
+}
+
+func (s *SoftDeleteStorage) inTrash(key string) bool {
+	return strings.HasPrefix(key, s.opts.TrashPrefix)
+}
@@ -0,0 +1,101 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+func TestSoftDeleteMovesToTrashAndRestores(t *testing.T) {
+	inner := memory.NewMemoryService("")
+	_ = inner.UploadObjectWithOptions("b", "docs/a.txt", []byte("a"), storage.UploadObjectOptions{Metadata: map[string]string{"owner": "u1"}})
+	_ = inner.UploadObject("b", "docs/b.txt", []byte("b"))
+	_ = inner.UploadObject("b", "keep.txt", []byte("k"))
+
+	svc := storage.NewSoftDeleteStorage(inner, storage.SoftDeleteOptions{TTL: time.Hour})
+	if err := svc.DeleteFolder("b", "docs"); err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+	if inner.HeadObject("b", "docs/a.txt") || !inner.HeadObject("b", ".trash/docs/a.txt") {
+		t.Fatal("deleted object should be moved to trash")
+	}
+	metadata, _ := inner.GetObjectMetadata("b", ".trash/docs/a.txt")
+	if metadata.Metadata["owner"] != "u1" || metadata.Metadata[storage.MetadataDeletedAt] == "" {
+		t.Fatalf("trash metadata = %v", metadata.Metadata)
+	}
+
+	page, err := svc.ListObjects(&storage.ListObjectsInput{Bucket: "b", Delimiter: "/"})
+	if err != nil {
+		t.Fatalf("ListObjects() error = %v", err)
+	}
+	if len(page.Objects) != 1 || page.Objects[0].Key != "keep.txt" || len(page.CommonPrefixes) != 0 {
+		t.Fatalf("ListObjects() = %+v, trash should be hidden", page)
+	}
+	trash, err := svc.ListTrash(context.Background(), "b", "docs/")
+	if err != nil || len(trash) != 2 || trash[0].Key != "docs/a.txt" {
+		t.Fatalf("ListTrash() = %+v, %v", trash, err)
+	}
+
+	if err := svc.Restore("b", "docs/a.txt"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	metadata, _ = inner.GetObjectMetadata("b", "docs/a.txt")
+	if _, ok := metadata.Metadata[storage.MetadataDeletedAt]; ok || metadata.Metadata["owner"] != "u1" {
+		t.Fatalf("restored metadata = %v", metadata.Metadata)
+	}
+	if inner.HeadObject("b", ".trash/docs/a.txt") {
+		t.Fatal("restored object should leave the trash")
+	}
+}
+
+func TestSoftDeletePurgeExpiredTrash(t *testing.T) {
+	inner := memory.NewMemoryService("")
+	_ = inner.UploadObject("b", "a.txt", []byte("a"))
+
+	now := time.Now()
+	svc := storage.NewSoftDeleteStorage(inner, storage.SoftDeleteOptions{
+		TrashPrefix: "recycle",
+		TTL:         24 * time.Hour,
+		Now:         func() time.Time { return now },
+	})
+	_ = svc.DeleteObject("b", "a.txt")
+
+	if report, err := svc.Purge(context.Background(), "b"); err != nil || report.Deleted != 0 {
+		t.Fatalf("Purge() before TTL = %+v, %v", report, err)
+	}
+	now = now.Add(25 * time.Hour)
+	report, err := svc.Purge(context.Background(), "b")
+	if err != nil || report.Deleted != 1 || inner.HeadObject("b", "recycle/a.txt") {
+		t.Fatalf("Purge() after TTL = %+v, %v", report, err)
+	}
+
+	if _, err := storage.NewSoftDeleteStorage(inner, storage.SoftDeleteOptions{}).Purge(context.Background(), "b"); err == nil {
+		t.Fatal("Purge() without TTL should fail")
+	}
+}
+
+// unreachable 模拟存在性检查暂时失败的存储
+type unreachable struct {
+	*memory.MemoryService
+}
+
+func (u *unreachable) ObjectExists(ctx context.Context, bucketName, fileKey string) (bool, error) {
+	return false, errors.New("connection reset")
+}
+
+func TestSoftDeleteReportsExistenceCheckErrors(t *testing.T) {
+	inner := &unreachable{MemoryService: memory.NewMemoryService("")}
+	_ = inner.UploadObject("b", "a.txt", []byte("a"))
+
+	svc := storage.NewSoftDeleteStorage(inner, storage.SoftDeleteOptions{})
+	if err := svc.DeleteObject("b", "a.txt"); err == nil {
+		t.Fatal("DeleteObject() should report the failed existence check")
+	}
+	if !inner.HeadObject("b", "a.txt") {
+		t.Fatal("object should be kept when the existence check fails")
+	}
+}
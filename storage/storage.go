@@ -2,7 +2,6 @@ package storage
 
 import (
 	"io"
-	"strings"
 	"time"
 )
 
@@ -14,6 +13,7 @@ type ObjectInfo struct {
 	ETag         string    `json:"etag"`         // ETag值
 	ContentType  string    `json:"contentType"`  // 内容类型
 	IsDir        bool      `json:"isDir"`        // 是否为目录
+	IsPrefix     bool      `json:"isPrefix"`     // 是否为公共前缀（非递归遍历时的子目录，非真实对象）
 }
 
 // ListObjectsInput 列举对象输入参数
@@ -43,6 +43,18 @@ type CopyObjectInput struct {
 	DestinationKey    string            `json:"destinationKey"`    // 目标对象键
 	Metadata          map[string]string `json:"metadata"`          // 元数据
 	ContentType       string            `json:"contentType"`       // 内容类型
+	StorageClass      string            `json:"storageClass"`      // 目标存储类别，为空时使用存储桶默认值
+}
+
+// UploadObjectOptions 上传时可选的对象属性
+type UploadObjectOptions struct {
+	ContentType  string            `json:"contentType"`  // 内容类型，为空时按文件头和文件名识别
+	CacheControl string            `json:"cacheControl"` // Cache-Control 头
+	ACL          string            `json:"acl"`          // 预设访问控制（如 public-read）
+	Metadata     map[string]string `json:"metadata"`     // 用户自定义元数据
+	// AllowedContentTypes 内容类型白名单（如 "image/*"、"video/mp4"），为空不校验；
+	// 上传时按文件头嗅探实际类型校验，不符合时返回 ErrContentTypeNotAllowed
+	AllowedContentTypes []string `json:"allowedContentTypes"`
 }
 
 // ObjectMetadata 对象元数据
@@ -56,6 +68,23 @@ type ObjectMetadata struct {
 	ServerSideEncryption string            `json:"serverSideEncryption"` // 服务端加密
 }
 
+// PostPolicyFilename 表单直传时由对象存储替换为上传文件名的占位符
+const PostPolicyFilename = "${filename}"
+
+// PostPolicyConditions 表单直传（POST Policy）约束条件
+type PostPolicyConditions struct {
+	ContentLengthMin  int64         `json:"contentLengthMin"`  // 最小文件大小（字节）
+	ContentLengthMax  int64         `json:"contentLengthMax"`  // 最大文件大小（字节），0 表示不限制
+	ContentTypePrefix string        `json:"contentTypePrefix"` // Content-Type 前缀，如 "image/"
+	Expires           time.Duration `json:"expires"`           // 有效期，0 表示使用后端默认预签名有效期
+}
+
+// PostPolicy 表单直传所需的提交地址与表单字段
+type PostPolicy struct {
+	URL    string            `json:"url"`    // 表单提交地址
+	Fields map[string]string `json:"fields"` // 需要与文件一起提交的表单字段（file 字段须放在最后）
+}
+
 // StorageService 对象存储服务接口
 type StorageService interface {
 	// ===== 基础文件操作 =====
@@ -75,7 +104,7 @@ type StorageService interface {
 	// DeleteObject 删除单个对象
 	DeleteObject(bucketName, fileKey string) error
 
-	// DeleteObjects 批量删除对象
+	// DeleteObjects 批量删除对象，返回删除失败的对象key；请求本身失败时返回 error
 	DeleteObjects(bucketName string, fileKeys []string) ([]string, error)
 
 	// ===== 文件管理操作 =====
@@ -117,6 +146,10 @@ type StorageService interface {
 	// PreSignDeleteObject 生成预签名删除URL
 	PreSignDeleteObject(bucketName, fileKey string) (string, error)
 
+	// PreSignPostPolicy 生成浏览器表单直传的 POST Policy，对象键限定在 keyPrefix 之下。
+	// 表单中的 key 默认为 keyPrefix + PostPolicyFilename，客户端也可以改写为 keyPrefix 下的任意键
+	PreSignPostPolicy(bucketName, keyPrefix string, conditions PostPolicyConditions) (*PostPolicy, error)
+
 	// ===== 高级功能 =====
 
 	// SetObjectACL 设置对象访问控制列表
@@ -132,16 +165,17 @@ type StorageService interface {
 	GenerateDownloadURL(bucketName, fileKey string) string
 }
 
-func GetContentType(fileName string) string {
-	contentType := "image/jpeg" // 默认为jpeg
-	if strings.HasSuffix(strings.ToLower(fileName), ".png") {
-		contentType = "image/png"
-	} else if strings.HasSuffix(strings.ToLower(fileName), ".gif") {
-		contentType = "image/gif"
-	} else if strings.HasSuffix(strings.ToLower(fileName), ".webp") {
-		contentType = "image/webp"
-	} else if strings.HasSuffix(strings.ToLower(fileName), ".svg") {
-		contentType = "image/svg+xml"
-	}
-	return contentType
+// OptionsUploader 支持上传时指定对象属性的存储实现
+type OptionsUploader interface {
+	// UploadObjectWithOptions 上传文件并设置对象属性
+	UploadObjectWithOptions(bucketName, fileKey string, data []byte, options UploadObjectOptions) error
+
+	// UploadObjectStreamWithOptions 流式上传文件并设置对象属性
+	UploadObjectStreamWithOptions(bucketName, fileKey string, file io.Reader, options UploadObjectOptions) error
+}
+
+// StreamGetter 支持流式读取对象的存储实现
+type StreamGetter interface {
+	// GetObjectStream 获取文件内容的读取流，调用方负责关闭
+	GetObjectStream(bucketName, fileKey string) (io.ReadCloser, error)
 }
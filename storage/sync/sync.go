@@ -0,0 +1,464 @@
+// Package sync 在任意两个 StorageService 实现之间同步对象，用于跨云（S3、GCS、TOS）迁移。
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	stdsync "sync"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+)
+
+const (
+	defaultWorkers   = 8
+	defaultPageSize  = 1000
+	deleteBatchSize  = 1000
+	checkpointSuffix = ".tmp"
+)
+
+// ErrIncomplete 部分对象同步失败，失败明细见 Report.Failures
+var ErrIncomplete = errors.New("sync: some objects failed")
+
+// Endpoint 同步的一端：存储服务 + 存储桶 + 前缀
+type Endpoint struct {
+	Service storage.StorageService
+	Bucket  string
+	Prefix  string
+}
+
+// CompareMode 判断目标对象是否需要更新的比较方式，可按位组合
+type CompareMode int
+
+const (
+	CompareSize    CompareMode = 1 << iota // 比较大小
+	CompareETag                            // 比较 ETag（仅同类后端间可靠）
+	CompareModTime                         // 源对象比目标对象新时更新
+
+	// CompareDefault 默认比较大小和修改时间
+	CompareDefault = CompareSize | CompareModTime
+)
+
+// ParseCompareMode 解析逗号分隔的比较方式，如 "size,etag,mtime"
+func ParseCompareMode(value string) (CompareMode, error) {
+	var mode CompareMode
+	for _, name := range strings.Split(value, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "":
+		case "size":
+			mode |= CompareSize
+		case "etag":
+			mode |= CompareETag
+		case "mtime":
+			mode |= CompareModTime
+		default:
+			return 0, fmt.Errorf("sync: unknown compare mode %q", name)
+		}
+	}
+	return mode, nil
+}
+
+// ActionType 同步动作类型
+type ActionType string
+
+const (
+	ActionCopy   ActionType = "copy"   // 复制到目标
+	ActionSkip   ActionType = "skip"   // 目标已是最新
+	ActionDelete ActionType = "delete" // 删除目标中多余的对象
+)
+
+// Action 单个对象的同步动作
+type Action struct {
+	Type ActionType `json:"type"`
+	Key  string     `json:"key"` // 相对于 Endpoint.Prefix 的键
+	Size int64      `json:"size"`
+	Err  error      `json:"-"`
+}
+
+// Options 同步选项
+type Options struct {
+	Include          []string    // 只同步这些前缀（相对于源前缀），为空表示全部
+	Exclude          []string    // 排除这些前缀（相对于源前缀）
+	Compare          CompareMode // 比较方式，0 表示 CompareDefault
+	DryRun           bool        // 只输出计划，不做任何修改
+	DeleteExtraneous bool        // 删除目标中源不存在的对象
+	Workers          int         // 并发数，默认 8
+	PageSize         int32       // 每页列举数量，默认 1000
+	CheckpointFile   string      // 断点文件，非空时按页记录进度并在下次运行时续传
+	OnAction         func(Action)
+}
+
+// Report 同步结果统计
+type Report struct {
+	Scanned  int      `json:"scanned"`
+	Copied   int      `json:"copied"`
+	Skipped  int      `json:"skipped"`
+	Deleted  int      `json:"deleted"`
+	Bytes    int64    `json:"bytes"`
+	Failures []Action `json:"-"`
+}
+
+type checkpoint struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	After       string `json:"after"` // 已完成的最后一个源对象键
+}
+
+type syncer struct {
+	src, dst Endpoint
+	opts     Options
+	sameSvc  bool
+
+	mu     stdsync.Mutex
+	report Report
+}
+
+// Run 将 src 下的对象同步到 dst。单个对象失败不会中断同步，全部处理完后返回 ErrIncomplete。
+func Run(ctx context.Context, src, dst Endpoint, opts Options) (*Report, error) {
+	if src.Service == nil || dst.Service == nil {
+		return nil, errors.New("sync: source and destination services are required")
+	}
+	if src.Bucket == "" || dst.Bucket == "" {
+		return nil, errors.New("sync: source and destination buckets are required")
+	}
+	if opts.Compare == 0 {
+		opts.Compare = CompareDefault
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+
+	s := &syncer{src: src, dst: dst, opts: opts, sameSvc: src.Service == dst.Service}
+
+	state := checkpoint{Source: endpointID(src), Destination: endpointID(dst)}
+	if opts.CheckpointFile != "" {
+		resumed, err := loadCheckpoint(opts.CheckpointFile)
+		if err != nil {
+			return nil, err
+		}
+		if resumed != nil && resumed.Source == state.Source && resumed.Destination == state.Destination {
+			state.After = resumed.After
+		}
+	}
+	resumeAfter := state.After
+
+	existing, err := listAll(ctx, dst, opts.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("sync: list destination: %w", err)
+	}
+
+	// 本次运行看到的源对象，用于计算需要删除的多余对象
+	seen := make(map[string]struct{})
+	input := &storage.ListObjectsInput{
+		Bucket:     src.Bucket,
+		Prefix:     src.Prefix,
+		MaxKeys:    opts.PageSize,
+		StartAfter: resumeAfter,
+	}
+	checkpointing := opts.CheckpointFile != "" && !opts.DryRun
+	for {
+		if err := ctx.Err(); err != nil {
+			return &s.report, err
+		}
+		page, err := src.Service.ListObjects(input)
+		if err != nil {
+			return &s.report, fmt.Errorf("sync: list source: %w", err)
+		}
+
+		failuresBefore := s.failureCount()
+		s.syncPage(ctx, page.Objects, existing, seen)
+
+		if len(page.Objects) > 0 && checkpointing {
+			if s.failureCount() > failuresBefore {
+				// 有失败时停止推进断点，下次运行从失败前的位置重新比较
+				checkpointing = false
+			} else {
+				state.After = page.Objects[len(page.Objects)-1].Key
+				if err := saveCheckpoint(opts.CheckpointFile, state); err != nil {
+					return &s.report, err
+				}
+			}
+		}
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+
+	if err := ctx.Err(); err != nil {
+		return &s.report, err
+	}
+	if opts.DeleteExtraneous {
+		if err := s.deleteExtraneous(ctx, existing, seen, resumeAfter); err != nil {
+			return &s.report, err
+		}
+	}
+
+	if len(s.report.Failures) > 0 {
+		return &s.report, ErrIncomplete
+	}
+	if opts.CheckpointFile != "" && !opts.DryRun {
+		if err := os.Remove(opts.CheckpointFile); err != nil && !os.IsNotExist(err) {
+			return &s.report, err
+		}
+	}
+	return &s.report, nil
+}
+
+func (s *syncer) syncPage(ctx context.Context, objects []storage.ObjectInfo, existing map[string]storage.ObjectInfo, seen map[string]struct{}) {
+	sem := make(chan struct{}, s.opts.Workers)
+	var wg stdsync.WaitGroup
+
+	for _, object := range objects {
+		rel := strings.TrimPrefix(object.Key, s.src.Prefix)
+		if !s.included(rel) {
+			continue
+		}
+		seen[rel] = struct{}{}
+
+		s.mu.Lock()
+		s.report.Scanned++
+		s.mu.Unlock()
+
+		if target, ok := existing[rel]; ok && !s.changed(object, target) {
+			s.record(Action{Type: ActionSkip, Key: rel, Size: object.Size})
+			continue
+		}
+		if s.opts.DryRun {
+			s.record(Action{Type: ActionCopy, Key: rel, Size: object.Size})
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(object storage.ObjectInfo, rel string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.record(Action{Type: ActionCopy, Key: rel, Size: object.Size, Err: s.copy(object.Key, s.dst.Prefix+rel)})
+		}(object, rel)
+	}
+	wg.Wait()
+}
+
+// copy 复制单个对象，同一存储服务实例时使用服务端复制，否则从源流式读取后上传，不在内存中缓存整个对象
+func (s *syncer) copy(srcKey, dstKey string) error {
+	if s.sameSvc {
+		return s.dst.Service.CopyObject(&storage.CopyObjectInput{
+			SourceBucket:      s.src.Bucket,
+			SourceKey:         srcKey,
+			DestinationBucket: s.dst.Bucket,
+			DestinationKey:    dstKey,
+		})
+	}
+
+	metadata, err := s.src.Service.GetObjectMetadata(s.src.Bucket, srcKey)
+	if err != nil {
+		return fmt.Errorf("get metadata: %w", err)
+	}
+	reader, err := s.open(srcKey)
+	if err != nil {
+		return fmt.Errorf("get object: %w", err)
+	}
+	defer reader.Close()
+
+	if uploader, ok := s.dst.Service.(storage.OptionsUploader); ok {
+		return uploader.UploadObjectStreamWithOptions(s.dst.Bucket, dstKey, reader, storage.UploadObjectOptions{
+			ContentType: metadata.ContentType,
+			Metadata:    metadata.Metadata,
+		})
+	}
+	if err := s.dst.Service.UploadObjectStream(s.dst.Bucket, dstKey, reader); err != nil {
+		return err
+	}
+	if len(metadata.Metadata) > 0 {
+		return s.dst.Service.SetObjectMetadata(s.dst.Bucket, dstKey, metadata.Metadata)
+	}
+	return nil
+}
+
+// open 打开源对象的读取流，源不支持流式读取时退化为整体读取
+func (s *syncer) open(srcKey string) (io.ReadCloser, error) {
+	if getter, ok := s.src.Service.(storage.StreamGetter); ok {
+		return getter.GetObjectStream(s.src.Bucket, srcKey)
+	}
+	data, err := s.src.Service.GetObject(s.src.Bucket, srcKey)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// deleteExtraneous 删除目标中源不存在的对象。断点之前的键本次未列举，需逐个确认源中是否存在，
+// 确认失败的键记为失败而不删除。
+func (s *syncer) deleteExtraneous(ctx context.Context, existing map[string]storage.ObjectInfo, seen map[string]struct{}, resumeAfter string) error {
+	var keys []string
+	for rel := range existing {
+		if _, ok := seen[rel]; ok || !s.included(rel) {
+			continue
+		}
+		if resumeAfter != "" && s.src.Prefix+rel <= resumeAfter {
+			exists, err := storage.ObjectExists(ctx, s.src.Service, s.src.Bucket, s.src.Prefix+rel)
+			if err != nil {
+				s.record(Action{Type: ActionDelete, Key: rel, Size: existing[rel].Size, Err: fmt.Errorf("check source: %w", err)})
+				continue
+			}
+			if exists {
+				continue
+			}
+		}
+		keys = append(keys, rel)
+	}
+
+	for start := 0; start < len(keys); start += deleteBatchSize {
+		batch := keys[start:min(start+deleteBatchSize, len(keys))]
+		if s.opts.DryRun {
+			for _, rel := range batch {
+				s.record(Action{Type: ActionDelete, Key: rel, Size: existing[rel].Size})
+			}
+			continue
+		}
+
+		fullKeys := make([]string, len(batch))
+		for i, rel := range batch {
+			fullKeys[i] = s.dst.Prefix + rel
+		}
+		failed, err := s.dst.Service.DeleteObjects(s.dst.Bucket, fullKeys)
+		if err != nil {
+			return fmt.Errorf("sync: delete extraneous: %w", err)
+		}
+		failedKeys := make(map[string]struct{}, len(failed))
+		for _, key := range failed {
+			failedKeys[key] = struct{}{}
+		}
+		for i, rel := range batch {
+			action := Action{Type: ActionDelete, Key: rel, Size: existing[rel].Size}
+			if _, ok := failedKeys[fullKeys[i]]; ok {
+				action.Err = errors.New("delete failed")
+			}
+			s.record(action)
+		}
+	}
+	return nil
+}
+
+func (s *syncer) included(rel string) bool {
+	for _, prefix := range s.opts.Exclude {
+		if strings.HasPrefix(rel, prefix) {
+			return false
+		}
+	}
+	if len(s.opts.Include) == 0 {
+		return true
+	}
+	for _, prefix := range s.opts.Include {
+		if strings.HasPrefix(rel, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// changed 按比较方式判断目标对象是否需要更新
+func (s *syncer) changed(source, target storage.ObjectInfo) bool {
+	if s.opts.Compare&CompareSize != 0 && source.Size != target.Size {
+		return true
+	}
+	if s.opts.Compare&CompareETag != 0 && normalizeETag(source.ETag) != normalizeETag(target.ETag) {
+		return true
+	}
+	if s.opts.Compare&CompareModTime != 0 && source.LastModified.After(target.LastModified) {
+		return true
+	}
+	return false
+}
+
+func (s *syncer) record(action Action) {
+	s.mu.Lock()
+	switch {
+	case action.Err != nil:
+		s.report.Failures = append(s.report.Failures, action)
+	case action.Type == ActionCopy:
+		s.report.Copied++
+		s.report.Bytes += action.Size
+	case action.Type == ActionSkip:
+		s.report.Skipped++
+	case action.Type == ActionDelete:
+		s.report.Deleted++
+	}
+	s.mu.Unlock()
+
+	if s.opts.OnAction != nil {
+		s.opts.OnAction(action)
+	}
+}
+
+func (s *syncer) failureCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.report.Failures)
+}
+
+// listAll 列举端点下的全部对象，键为相对前缀的路径
+func listAll(ctx context.Context, endpoint Endpoint, pageSize int32) (map[string]storage.ObjectInfo, error) {
+	objects := make(map[string]storage.ObjectInfo)
+	input := &storage.ListObjectsInput{Bucket: endpoint.Bucket, Prefix: endpoint.Prefix}
+	for object, err := range storage.Objects(ctx, endpoint.Service, input, storage.WithRecursive(true), storage.WithPageSize(pageSize)) {
+		if err != nil {
+			return nil, err
+		}
+		objects[strings.TrimPrefix(object.Key, endpoint.Prefix)] = object
+	}
+	return objects, nil
+}
+
+func normalizeETag(etag string) string {
+	return strings.Trim(etag, `"`)
+}
+
+func endpointID(endpoint Endpoint) string {
+	return endpoint.Bucket + "/" + endpoint.Prefix
+}
+
+func loadCheckpoint(file string) (*checkpoint, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sync: read checkpoint: %w", err)
+	}
+	var state checkpoint
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("sync: parse checkpoint: %w", err)
+	}
+	return &state, nil
+}
+
+// saveCheckpoint 先写临时文件再重命名，避免中断时留下半截文件
+func saveCheckpoint(file string, state checkpoint) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file+checkpointSuffix, data, 0o644); err != nil {
+		return fmt.Errorf("sync: write checkpoint: %w", err)
+	}
+	if err := os.Rename(file+checkpointSuffix, file); err != nil {
+		return fmt.Errorf("sync: write checkpoint: %w", err)
+	}
+	return nil
+}
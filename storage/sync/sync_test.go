@@ -0,0 +1,204 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+func TestRunCopiesAcrossBackendsWithMetadata(t *testing.T) {
+	src := memory.NewMemoryService("")
+	dst := memory.NewMemoryService("")
+	_ = src.UploadObjectWithOptions("a", "assets/x.bin", []byte("hello"), storage.UploadObjectOptions{
+		ContentType: "application/x-test",
+		Metadata:    map[string]string{"owner": "ops"},
+	})
+	_ = src.UploadObject("a", "assets/skip/y.png", []byte("y"))
+	_ = src.UploadObject("a", "other/z.png", []byte("z"))
+
+	report, err := Run(context.Background(),
+		Endpoint{Service: src, Bucket: "a", Prefix: "assets/"},
+		Endpoint{Service: dst, Bucket: "b", Prefix: "migrated/"},
+		Options{Exclude: []string{"skip/"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Copied != 1 || report.Bytes != 5 {
+		t.Fatalf("report = %+v, want one copied object", report)
+	}
+
+	metadata, err := dst.GetObjectMetadata("b", "migrated/x.bin")
+	if err != nil {
+		t.Fatalf("GetObjectMetadata() error = %v", err)
+	}
+	if metadata.ContentType != "application/x-test" || metadata.Metadata["owner"] != "ops" {
+		t.Fatalf("metadata = %+v, want preserved content type and metadata", metadata)
+	}
+	if dst.HeadObject("b", "migrated/skip/y.png") {
+		t.Fatal("excluded object was copied")
+	}
+
+	report, err = Run(context.Background(),
+		Endpoint{Service: src, Bucket: "a", Prefix: "assets/"},
+		Endpoint{Service: dst, Bucket: "b", Prefix: "migrated/"},
+		Options{Exclude: []string{"skip/"}})
+	if err != nil || report.Copied != 0 || report.Skipped != 1 {
+		t.Fatalf("second run report = %+v, err = %v, want skip", report, err)
+	}
+}
+
+func TestRunDryRunAndDeleteExtraneous(t *testing.T) {
+	store := memory.NewMemoryService("")
+	_ = store.UploadObject("src", "keep.png", []byte("1"))
+	_ = store.UploadObject("dst", "stale.png", []byte("2"))
+
+	var actions []Action
+	report, err := Run(context.Background(),
+		Endpoint{Service: store, Bucket: "src"},
+		Endpoint{Service: store, Bucket: "dst"},
+		Options{DryRun: true, DeleteExtraneous: true, OnAction: func(a Action) { actions = append(actions, a) }})
+	if err != nil {
+		t.Fatalf("dry run error = %v", err)
+	}
+	if report.Copied != 1 || report.Deleted != 1 || len(actions) != 2 {
+		t.Fatalf("report = %+v, actions = %+v", report, actions)
+	}
+	if store.HeadObject("dst", "keep.png") || !store.HeadObject("dst", "stale.png") {
+		t.Fatal("dry run modified destination")
+	}
+
+	if _, err := Run(context.Background(),
+		Endpoint{Service: store, Bucket: "src"},
+		Endpoint{Service: store, Bucket: "dst"},
+		Options{DeleteExtraneous: true}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !store.HeadObject("dst", "keep.png") || store.HeadObject("dst", "stale.png") {
+		t.Fatal("destination not mirrored")
+	}
+}
+
+type failingUploads struct {
+	*memory.MemoryService
+	failKey string
+}
+
+func (f *failingUploads) UploadObjectStreamWithOptions(bucketName, fileKey string, file io.Reader, options storage.UploadObjectOptions) error {
+	if fileKey == f.failKey {
+		return errors.New("boom")
+	}
+	return f.MemoryService.UploadObjectStreamWithOptions(bucketName, fileKey, file, options)
+}
+
+// streamOnly 禁止整体读取对象，确保跨后端复制走流式读取
+type streamOnly struct {
+	*memory.MemoryService
+}
+
+func (s *streamOnly) GetObject(bucketName, fileKey string) ([]byte, error) {
+	return nil, errors.New("whole object read")
+}
+
+func TestRunStreamsObjectsAcrossBackends(t *testing.T) {
+	src := &streamOnly{MemoryService: memory.NewMemoryService("")}
+	dst := memory.NewMemoryService("")
+	_ = src.UploadObject("a", "big.bin", []byte("streamed"))
+
+	report, err := Run(context.Background(), Endpoint{Service: src, Bucket: "a"}, Endpoint{Service: dst, Bucket: "b"}, Options{})
+	if err != nil || report.Copied != 1 {
+		t.Fatalf("report = %+v, err = %v, want one streamed copy", report, err)
+	}
+	data, err := dst.GetObject("b", "big.bin")
+	if err != nil || string(data) != "streamed" {
+		t.Fatalf("copied object = %q, %v", data, err)
+	}
+}
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	src := memory.NewMemoryService("")
+	for i := 0; i < 5; i++ {
+		_ = src.UploadObject("a", fmt.Sprintf("k%d", i), []byte("v"))
+	}
+	dst := &failingUploads{MemoryService: memory.NewMemoryService(""), failKey: "k3"}
+	_ = dst.MemoryService.UploadObject("b", "k0", []byte("v"))
+	_ = dst.MemoryService.UploadObject("b", "orphan", []byte("v"))
+	checkpointFile := filepath.Join(t.TempDir(), "sync.json")
+
+	opts := Options{PageSize: 2, Workers: 1, CheckpointFile: checkpointFile, DeleteExtraneous: true}
+	report, err := Run(context.Background(), Endpoint{Service: src, Bucket: "a"}, Endpoint{Service: dst, Bucket: "b"}, opts)
+	if !errors.Is(err, ErrIncomplete) || len(report.Failures) != 1 {
+		t.Fatalf("first run report = %+v, err = %v, want one failure", report, err)
+	}
+	data, err := os.ReadFile(checkpointFile)
+	if err != nil || string(data) != `{"source":"a/","destination":"b/","after":"k1"}` {
+		t.Fatalf("checkpoint = %s, %v", data, err)
+	}
+
+	dst.failKey = ""
+	report, err = Run(context.Background(), Endpoint{Service: src, Bucket: "a"}, Endpoint{Service: dst, Bucket: "b"}, opts)
+	if err != nil {
+		t.Fatalf("resume error = %v", err)
+	}
+	if report.Scanned != 3 {
+		t.Fatalf("resume scanned %d objects, want 3 after checkpoint", report.Scanned)
+	}
+	for i := 0; i < 5; i++ {
+		if !dst.HeadObject("b", fmt.Sprintf("k%d", i)) {
+			t.Fatalf("k%d missing after resume", i)
+		}
+	}
+	if dst.HeadObject("b", "orphan") {
+		t.Fatal("extraneous object not deleted")
+	}
+	if _, err := os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint not removed: %v", err)
+	}
+}
+
+// flakyExists 模拟源存储的存在性检查暂时失败
+type flakyExists struct {
+	*memory.MemoryService
+}
+
+func (f *flakyExists) ObjectExists(ctx context.Context, bucketName, fileKey string) (bool, error) {
+	return false, errors.New("connection reset")
+}
+
+func TestRunKeepsDestinationWhenSourceCheckFails(t *testing.T) {
+	src := &flakyExists{MemoryService: memory.NewMemoryService("")}
+	for i := 0; i < 3; i++ {
+		_ = src.UploadObject("a", fmt.Sprintf("k%d", i), []byte("v"))
+	}
+	dst := memory.NewMemoryService("")
+	_ = dst.UploadObject("b", "k0", []byte("v"))
+	checkpointFile := filepath.Join(t.TempDir(), "sync.json")
+	if err := os.WriteFile(checkpointFile, []byte(`{"source":"a/","destination":"b/","after":"k1"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{CheckpointFile: checkpointFile, DeleteExtraneous: true}
+	report, err := Run(context.Background(), Endpoint{Service: src, Bucket: "a"}, Endpoint{Service: dst, Bucket: "b"}, opts)
+	if !errors.Is(err, ErrIncomplete) || len(report.Failures) != 1 || report.Failures[0].Key != "k0" {
+		t.Fatalf("report = %+v, err = %v, want k0 to fail", report, err)
+	}
+	if report.Deleted != 0 || !dst.HeadObject("b", "k0") {
+		t.Fatal("destination object deleted after a failed source check")
+	}
+}
+
+func TestParseCompareMode(t *testing.T) {
+	mode, err := ParseCompareMode("size, etag")
+	if err != nil || mode != CompareSize|CompareETag {
+		t.Fatalf("ParseCompareMode() = %v, %v", mode, err)
+	}
+	if _, err := ParseCompareMode("crc"); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+}
@@ -3,6 +3,7 @@ package tos
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,7 +16,11 @@ import (
 	"github.com/volcengine/ve-tos-golang-sdk/v2/tos/enum"
 )
 
-const defaultPreSignTTL = 15 * time.Minute
+const (
+	defaultPreSignTTL = 15 * time.Minute
+	// maxDeleteObjects DeleteMultiObjects 单次请求的对象数量上限
+	maxDeleteObjects = 1000
+)
 
 // Config 初始化TOS服务所需的配置
 type Config struct {
@@ -75,33 +80,34 @@ func NewTOSService(cfg Config) (storage.StorageService, error) {
 
 // UploadObject 上传文件到TOS
 func (t *TOSService) UploadObject(bucketName, fileKey string, data []byte) error {
-	input := &v2tos.PutObjectV2Input{
-		PutObjectBasicInput: v2tos.PutObjectBasicInput{
-			Bucket:        bucketName,
-			Key:           fileKey,
-			ContentType:   storage.GetContentType(fileKey),
-			ContentLength: int64(len(data)),
-		},
-		Content: bytes.NewReader(data),
+	return t.UploadObjectWithOptions(bucketName, fileKey, data, storage.UploadObjectOptions{})
+}
+
+// UploadObjectWithOptions 上传文件并设置对象属性
+func (t *TOSService) UploadObjectWithOptions(bucketName, fileKey string, data []byte, options storage.UploadObjectOptions) error {
+	input, err := t.putObjectInput(bucketName, fileKey, bytes.NewReader(data), options)
+	if err != nil {
+		return err
 	}
+	input.ContentLength = int64(len(data))
 
-	_, err := t.client.PutObjectV2(t.ctx, input)
-	return err
+	_, err = t.client.PutObjectV2(t.ctx, input)
+	return mapError(err)
 }
 
 // UploadObjectStream 流式上传文件
 func (t *TOSService) UploadObjectStream(bucketName, fileKey string, file io.Reader) error {
-	input := &v2tos.PutObjectV2Input{
-		PutObjectBasicInput: v2tos.PutObjectBasicInput{
-			Bucket:      bucketName,
-			Key:         fileKey,
-			ContentType: storage.GetContentType(fileKey),
-		},
-		Content: file,
-	}
+	return t.UploadObjectStreamWithOptions(bucketName, fileKey, file, storage.UploadObjectOptions{})
+}
 
-	_, err := t.client.PutObjectV2(t.ctx, input)
-	return err
+// UploadObjectStreamWithOptions 流式上传文件并设置对象属性
+func (t *TOSService) UploadObjectStreamWithOptions(bucketName, fileKey string, file io.Reader, options storage.UploadObjectOptions) error {
+	input, err := t.putObjectInput(bucketName, fileKey, file, options)
+	if err != nil {
+		return err
+	}
+	_, err = t.client.PutObjectV2(t.ctx, input)
+	return mapError(err)
 }
 
 // GetObject 获取文件内容
@@ -111,26 +117,45 @@ func (t *TOSService) GetObject(bucketName, fileKey string) ([]byte, error) {
 		Key:    fileKey,
 	})
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 	defer output.Content.Close()
 
 	return io.ReadAll(output.Content)
 }
 
-// HeadObject 检查对象是否存在
+// GetObjectStream 获取文件内容的读取流
+func (t *TOSService) GetObjectStream(bucketName, fileKey string) (io.ReadCloser, error) {
+	output, err := t.client.GetObjectV2(t.ctx, &v2tos.GetObjectV2Input{
+		Bucket: bucketName,
+		Key:    fileKey,
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return output.Content, nil
+}
+
+// HeadObject 检查对象是否存在，检查失败时同样返回 false，需要区分时使用 ObjectExists
 func (t *TOSService) HeadObject(bucketName, fileKey string) bool {
-	_, err := t.client.HeadObjectV2(t.ctx, &v2tos.HeadObjectV2Input{
+	exists, _ := t.ObjectExists(t.ctx, bucketName, fileKey)
+	return exists
+}
+
+// ObjectExists 检查对象是否存在，对象不存在时返回 false，存储桶不存在等其余错误原样返回
+func (t *TOSService) ObjectExists(ctx context.Context, bucketName, fileKey string) (bool, error) {
+	_, err := t.client.HeadObjectV2(ctx, &v2tos.HeadObjectV2Input{
 		Bucket: bucketName,
 		Key:    fileKey,
 	})
 	if err != nil {
-		if v2tos.Code(err) == codes.NoSuchKey {
-			return false
+		if err = mapError(err); errors.Is(err, storage.ErrNotFound) {
+			return false, nil
 		}
-		return false
+		return false, err
 	}
-	return true
+	return true, nil
 }
 
 // DeleteObject 删除单个对象
@@ -139,7 +164,7 @@ func (t *TOSService) DeleteObject(bucketName, fileKey string) error {
 		Bucket: bucketName,
 		Key:    fileKey,
 	})
-	return err
+	return mapError(err)
 }
 
 // DeleteObjects 批量删除对象，返回删除失败的对象key
@@ -158,7 +183,7 @@ func (t *TOSService) DeleteObjects(bucketName string, fileKeys []string) ([]stri
 		Objects: objects,
 	})
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	var failed []string
@@ -189,7 +214,7 @@ func (t *TOSService) ListObjects(input *storage.ListObjectsInput) (*storage.List
 
 	resp, err := t.client.ListObjectsType2(t.ctx, listInput)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	output := &storage.ListObjectsOutput{
@@ -228,6 +253,7 @@ func (t *TOSService) CopyObject(input *storage.CopyObjectInput) error {
 		SrcKey:            input.SourceKey,
 		ContentType:       input.ContentType,
 		MetadataDirective: enum.MetadataDirectiveCopy,
+		StorageClass:      enum.StorageClassType(input.StorageClass),
 	}
 	if len(input.Metadata) > 0 {
 		copyInput.MetadataDirective = enum.MetadataDirectiveReplace
@@ -235,7 +261,7 @@ func (t *TOSService) CopyObject(input *storage.CopyObjectInput) error {
 	}
 
 	_, err := t.client.CopyObject(t.ctx, copyInput)
-	return err
+	return mapError(err)
 }
 
 // MoveObject 移动对象（先复制后删除）
@@ -259,7 +285,7 @@ func (t *TOSService) GetObjectMetadata(bucketName, fileKey string) (*storage.Obj
 		Key:    fileKey,
 	})
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	meta := &storage.ObjectMetadata{
@@ -284,57 +310,13 @@ func (t *TOSService) CreateFolder(bucketName, folderPath string) error {
 
 // DeleteFolder 删除目录及其所有内容
 func (t *TOSService) DeleteFolder(bucketName, folderPath string) error {
-	prefix := ensureTrailingSlash(folderPath)
-	token := ""
-
-	for {
-		resp, err := t.client.ListObjectsType2(t.ctx, &v2tos.ListObjectsType2Input{
-			Bucket:            bucketName,
-			Prefix:            prefix,
-			ContinuationToken: token,
-			MaxKeys:           1000,
-		})
-		if err != nil {
-			return err
-		}
-
-		var keys []string
-		for _, obj := range resp.Contents {
-			keys = append(keys, obj.Key)
-		}
-
-		if len(keys) > 0 {
-			if _, err := t.DeleteObjects(bucketName, keys); err != nil {
-				return err
-			}
-		}
-
-		if !resp.IsTruncated {
-			break
-		}
-		token = resp.NextContinuationToken
-	}
-
-	return nil
+	// 边列举边删除，每批不超过 DeleteMultiObjects 的 1000 个上限
+	return storage.DeletePrefix(t.ctx, t, bucketName, ensureTrailingSlash(folderPath), maxDeleteObjects)
 }
 
 // ListFolders 列举指定前缀下的“目录”
 func (t *TOSService) ListFolders(bucketName, prefix string) ([]string, error) {
-	resp, err := t.client.ListObjectsType2(t.ctx, &v2tos.ListObjectsType2Input{
-		Bucket:    bucketName,
-		Prefix:    prefix,
-		Delimiter: "/",
-		MaxKeys:   1000,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	folders := make([]string, 0, len(resp.CommonPrefixes))
-	for _, common := range resp.CommonPrefixes {
-		folders = append(folders, common.Prefix)
-	}
-	return folders, nil
+	return storage.ListPrefixes(t.ctx, t, bucketName, prefix)
 }
 
 // ===== 预签名URL操作 =====
@@ -348,7 +330,7 @@ func (t *TOSService) PreSignPutObject(bucketName, fileKey string) (string, error
 		Expires:    int64(t.preSignTTL.Seconds()),
 	})
 	if err != nil {
-		return "", err
+		return "", mapError(err)
 	}
 	return resp.SignedUrl, nil
 }
@@ -381,7 +363,7 @@ func (t *TOSService) PreSignGetObject(bucketName, fileKey string) (string, error
 		Expires:    int64(t.preSignTTL.Seconds()),
 	})
 	if err != nil {
-		return "", err
+		return "", mapError(err)
 	}
 	return resp.SignedUrl, nil
 }
@@ -395,11 +377,62 @@ func (t *TOSService) PreSignDeleteObject(bucketName, fileKey string) (string, er
 		Expires:    int64(t.preSignTTL.Seconds()),
 	})
 	if err != nil {
-		return "", err
+		return "", mapError(err)
 	}
 	return resp.SignedUrl, nil
 }
 
+// PreSignPostPolicy 生成浏览器表单直传的 POST Policy。
+// 表单中的 key 默认为 keyPrefix + ${filename}，客户端也可以改写为 keyPrefix 下的任意键。
+func (t *TOSService) PreSignPostPolicy(bucketName, keyPrefix string, conditions storage.PostPolicyConditions) (*storage.PostPolicy, error) {
+	expires := conditions.Expires
+	if expires <= 0 {
+		expires = t.preSignTTL
+	}
+
+	startsWith := "starts-with"
+	input := &v2tos.PreSingedPostSignatureInput{
+		Bucket:  bucketName,
+		Expires: int64(expires.Seconds()),
+		Conditions: []v2tos.PostSignatureCondition{
+			{Key: "key", Value: keyPrefix, Operator: &startsWith},
+		},
+	}
+	if conditions.ContentLengthMax > 0 {
+		input.ContentLengthRange = &v2tos.ContentLengthRange{
+			RangeStart: conditions.ContentLengthMin,
+			RangeEnd:   conditions.ContentLengthMax,
+		}
+	}
+	if conditions.ContentTypePrefix != "" {
+		input.Conditions = append(input.Conditions, v2tos.PostSignatureCondition{
+			Key: "Content-Type", Value: conditions.ContentTypePrefix, Operator: &startsWith,
+		})
+	}
+
+	output, err := t.client.PreSignedPostSignature(t.ctx, input)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	fields := map[string]string{
+		"key":              keyPrefix + storage.PostPolicyFilename,
+		"policy":           output.Policy,
+		"x-tos-algorithm":  output.Algorithm,
+		"x-tos-credential": output.Credential,
+		"x-tos-date":       output.Date,
+		"x-tos-signature":  output.Signature,
+	}
+	if t.defaultScope.SecurityToken != "" {
+		fields["x-tos-security-token"] = t.defaultScope.SecurityToken
+	}
+
+	return &storage.PostPolicy{
+		URL:    t.bucketURL(bucketName),
+		Fields: fields,
+	}, nil
+}
+
 // ===== 高级功能 =====
 
 // SetObjectACL 设置对象ACL
@@ -412,7 +445,7 @@ func (t *TOSService) SetObjectACL(bucketName, fileKey, acl string) error {
 		Key:    fileKey,
 		ACL:    enum.ACLType(acl),
 	})
-	return err
+	return mapError(err)
 }
 
 // GetObjectACL 获取对象ACL
@@ -422,7 +455,7 @@ func (t *TOSService) GetObjectACL(bucketName, fileKey string) (string, error) {
 		Key:    fileKey,
 	})
 	if err != nil {
-		return "", err
+		return "", mapError(err)
 	}
 
 	for _, grant := range output.Grants {
@@ -448,7 +481,7 @@ func (t *TOSService) SetObjectMetadata(bucketName, fileKey string, metadata map[
 		Key:    fileKey,
 		Meta:   metadata,
 	})
-	return err
+	return mapError(err)
 }
 
 // GenerateDownloadURL 生成下载链接（默认走预签名）
@@ -460,6 +493,214 @@ func (t *TOSService) GenerateDownloadURL(bucketName, fileKey string) string {
 	return url
 }
 
+// PutObjectTags 覆盖设置对象标签
+func (t *TOSService) PutObjectTags(bucketName, fileKey string, tags map[string]string) error {
+	tagSet := v2tos.TagSet{Tags: make([]v2tos.Tag, 0, len(tags))}
+	for key, value := range tags {
+		tagSet.Tags = append(tagSet.Tags, v2tos.Tag{Key: key, Value: value})
+	}
+	_, err := t.client.PutObjectTagging(t.ctx, &v2tos.PutObjectTaggingInput{
+		Bucket: bucketName,
+		Key:    fileKey,
+		TagSet: tagSet,
+	})
+	return mapError(err)
+}
+
+// GetObjectTags 获取对象标签
+func (t *TOSService) GetObjectTags(bucketName, fileKey string) (map[string]string, error) {
+	output, err := t.client.GetObjectTagging(t.ctx, &v2tos.GetObjectTaggingInput{
+		Bucket: bucketName,
+		Key:    fileKey,
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	tags := make(map[string]string, len(output.TagSet.Tags))
+	for _, tag := range output.TagSet.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	return tags, nil
+}
+
+// ===== 版本控制 =====
+
+// SetBucketVersioning 开启或暂停存储桶版本控制
+func (t *TOSService) SetBucketVersioning(bucketName string, enabled bool) error {
+	status := enum.VersioningStatusSuspended
+	if enabled {
+		status = enum.VersioningStatusEnable
+	}
+	_, err := t.client.PutBucketVersioning(t.ctx, &v2tos.PutBucketVersioningInput{
+		Bucket: bucketName,
+		Status: status,
+	})
+	return mapError(err)
+}
+
+// ListObjectVersions 列举前缀下全部对象的版本和删除标记，自动翻页
+func (t *TOSService) ListObjectVersions(bucketName, prefix string) ([]storage.ObjectVersion, error) {
+	input := &v2tos.ListObjectVersionsV2Input{
+		Bucket:                  bucketName,
+		ListObjectVersionsInput: v2tos.ListObjectVersionsInput{Prefix: prefix},
+	}
+
+	versions := []storage.ObjectVersion{}
+	for {
+		output, err := t.client.ListObjectVersionsV2(t.ctx, input)
+		if err != nil {
+			return nil, mapError(err)
+		}
+		for _, version := range output.Versions {
+			versions = append(versions, storage.ObjectVersion{
+				Key:          version.Key,
+				VersionID:    version.VersionID,
+				Size:         version.Size,
+				LastModified: version.LastModified,
+				ETag:         version.ETag,
+				IsLatest:     version.IsLatest,
+			})
+		}
+		for _, marker := range output.DeleteMarkers {
+			versions = append(versions, storage.ObjectVersion{
+				Key:            marker.Key,
+				VersionID:      marker.VersionID,
+				LastModified:   marker.LastModified,
+				IsLatest:       marker.IsLatest,
+				IsDeleteMarker: true,
+			})
+		}
+		if !output.IsTruncated {
+			return versions, nil
+		}
+		input.KeyMarker = output.NextKeyMarker
+		input.VersionIDMarker = output.NextVersionIDMarker
+	}
+}
+
+// GetObjectVersion 获取指定版本的内容
+func (t *TOSService) GetObjectVersion(bucketName, fileKey, versionID string) ([]byte, error) {
+	output, err := t.client.GetObjectV2(t.ctx, &v2tos.GetObjectV2Input{
+		Bucket:    bucketName,
+		Key:       fileKey,
+		VersionID: versionID,
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer output.Content.Close()
+
+	return io.ReadAll(output.Content)
+}
+
+// RestoreObject 将指定版本复制为当前版本，versionID 为空时恢复最近一个非删除标记的版本
+func (t *TOSService) RestoreObject(bucketName, fileKey, versionID string) error {
+	if versionID == "" {
+		versions, err := t.ListObjectVersions(bucketName, fileKey)
+		if err != nil {
+			return err
+		}
+		version, err := storage.FindRestoreVersion(versions, fileKey, "")
+		if err != nil {
+			return err
+		}
+		versionID = version.VersionID
+	}
+
+	_, err := t.client.CopyObject(t.ctx, &v2tos.CopyObjectInput{
+		Bucket:            bucketName,
+		Key:               fileKey,
+		SrcBucket:         bucketName,
+		SrcKey:            fileKey,
+		SrcVersionID:      versionID,
+		MetadataDirective: enum.MetadataDirectiveCopy,
+	})
+	return mapError(err)
+}
+
+// ===== 生命周期管理 =====
+
+// GetBucketLifecycle 获取存储桶生命周期规则，未配置时返回空列表
+func (t *TOSService) GetBucketLifecycle(bucketName string) ([]storage.LifecycleRule, error) {
+	resp, err := t.client.GetBucketLifecycle(t.ctx, &v2tos.GetBucketLifecycleInput{Bucket: bucketName})
+	if err != nil {
+		if v2tos.Code(err) == codes.NoSuchLifecycleConfiguration {
+			return []storage.LifecycleRule{}, nil
+		}
+		return nil, mapError(err)
+	}
+
+	rules := make([]storage.LifecycleRule, 0, len(resp.Rules))
+	for _, tosRule := range resp.Rules {
+		rule := storage.LifecycleRule{
+			ID:      tosRule.ID,
+			Prefix:  tosRule.Prefix,
+			Enabled: tosRule.Status == enum.LifecycleStatusEnabled,
+		}
+		if tosRule.Expiration != nil {
+			rule.ExpirationDays = tosRule.Expiration.Days
+		}
+		for _, transition := range tosRule.Transitions {
+			rule.Transitions = append(rule.Transitions, storage.LifecycleTransition{
+				Days:         transition.Days,
+				StorageClass: string(transition.StorageClass),
+			})
+		}
+		if tosRule.AbortInCompleteMultipartUpload != nil {
+			rule.AbortIncompleteMultipartUploadDays = tosRule.AbortInCompleteMultipartUpload.DaysAfterInitiation
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// PutBucketLifecycle 覆盖设置存储桶生命周期规则
+func (t *TOSService) PutBucketLifecycle(bucketName string, rules []storage.LifecycleRule) error {
+	if len(rules) == 0 {
+		return t.DeleteBucketLifecycle(bucketName)
+	}
+
+	tosRules := make([]v2tos.LifecycleRule, 0, len(rules))
+	for _, rule := range rules {
+		tosRule := v2tos.LifecycleRule{
+			ID:     rule.ID,
+			Prefix: rule.Prefix,
+			Status: enum.LifecycleStatusDisabled,
+		}
+		if rule.Enabled {
+			tosRule.Status = enum.LifecycleStatusEnabled
+		}
+		if rule.ExpirationDays > 0 {
+			tosRule.Expiration = &v2tos.Expiration{Days: rule.ExpirationDays}
+		}
+		for _, transition := range rule.Transitions {
+			tosRule.Transitions = append(tosRule.Transitions, v2tos.Transition{
+				Days:         transition.Days,
+				StorageClass: enum.StorageClassType(transition.StorageClass),
+			})
+		}
+		if rule.AbortIncompleteMultipartUploadDays > 0 {
+			tosRule.AbortInCompleteMultipartUpload = &v2tos.AbortInCompleteMultipartUpload{
+				DaysAfterInitiation: rule.AbortIncompleteMultipartUploadDays,
+			}
+		}
+		tosRules = append(tosRules, tosRule)
+	}
+
+	_, err := t.client.PutBucketLifecycle(t.ctx, &v2tos.PutBucketLifecycleInput{
+		Bucket: bucketName,
+		Rules:  tosRules,
+	})
+	return mapError(err)
+}
+
+// DeleteBucketLifecycle 删除存储桶全部生命周期规则
+func (t *TOSService) DeleteBucketLifecycle(bucketName string) error {
+	_, err := t.client.DeleteBucketLifecycle(t.ctx, &v2tos.DeleteBucketLifecycleInput{Bucket: bucketName})
+	return mapError(err)
+}
+
 // ===== 辅助方法 =====
 
 func metadataToMap(meta v2tos.Metadata) map[string]string {
@@ -474,6 +715,39 @@ func metadataToMap(meta v2tos.Metadata) map[string]string {
 	return result
 }
 
+func (t *TOSService) putObjectInput(bucketName, fileKey string, content io.Reader, options storage.UploadObjectOptions) (*v2tos.PutObjectV2Input, error) {
+	contentType, content, err := storage.PrepareUpload(fileKey, content, options)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &v2tos.PutObjectV2Input{
+		PutObjectBasicInput: v2tos.PutObjectBasicInput{
+			Bucket:       bucketName,
+			Key:          fileKey,
+			ContentType:  contentType,
+			CacheControl: options.CacheControl,
+			Meta:         options.Metadata,
+		},
+		Content: content,
+	}
+	if options.ACL != "" {
+		input.ACL = enum.ACLType(options.ACL)
+	}
+	return input, nil
+}
+
+// bucketURL 返回 virtual-hosted 风格的存储桶访问地址
+func (t *TOSService) bucketURL(bucketName string) string {
+	endpoint := t.defaultScope.Endpoint
+	scheme := "https://"
+	if idx := strings.Index(endpoint, "://"); idx >= 0 {
+		scheme = endpoint[:idx+3]
+		endpoint = endpoint[idx+3:]
+	}
+	return scheme + bucketName + "." + strings.TrimRight(endpoint, "/")
+}
+
 func ensureTrailingSlash(path string) string {
 	if path == "" {
 		return ""
@@ -483,3 +757,11 @@ func ensureTrailingSlash(path string) string {
 	}
 	return path
 }
+
+// mapError 将 TOS SDK 错误归类为 storage.ErrNotFound 等分类错误
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return storage.ClassifyHTTPError(err, v2tos.StatusCode(err), v2tos.Code(err))
+}
@@ -0,0 +1,58 @@
+package tos
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+)
+
+func TestPreSignPostPolicyRestrictsKeyPrefixSizeAndContentType(t *testing.T) {
+	service, err := NewTOSService(Config{
+		Endpoint:  "https://tos-cn-beijing.volces.com",
+		Region:    "cn-beijing",
+		AccessKey: "test-access-key",
+		SecretKey: "test-secret-key",
+	})
+	if err != nil {
+		t.Fatalf("NewTOSService failed: %v", err)
+	}
+
+	policy, err := service.PreSignPostPolicy("polai-private-media-prod", "uploads/user-1/", storage.PostPolicyConditions{
+		ContentLengthMin:  1,
+		ContentLengthMax:  10 << 20,
+		ContentTypePrefix: "image/",
+		Expires:           10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("PreSignPostPolicy failed: %v", err)
+	}
+
+	if got := policy.Fields["key"]; got != "uploads/user-1/${filename}" {
+		t.Fatalf("key field = %q, want prefix with filename placeholder", got)
+	}
+	for _, field := range []string{"policy", "x-tos-signature", "x-tos-credential", "x-tos-algorithm", "x-tos-date"} {
+		if policy.Fields[field] == "" {
+			t.Fatalf("field %s is missing", field)
+		}
+	}
+
+	document, err := base64.StdEncoding.DecodeString(policy.Fields["policy"])
+	if err != nil {
+		t.Fatalf("policy is not base64: %v", err)
+	}
+	for _, condition := range []string{
+		`["starts-with","$key","uploads/user-1/"]`,
+		`["content-length-range",1,10485760]`,
+		`["starts-with","$Content-Type","image/"]`,
+	} {
+		if !strings.Contains(string(document), condition) {
+			t.Fatalf("policy document %s does not contain %s", document, condition)
+		}
+	}
+	if strings.Contains(string(document), `{"key":`) {
+		t.Fatalf("policy document %s pins the exact key", document)
+	}
+}
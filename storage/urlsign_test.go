@@ -0,0 +1,186 @@
+package storage_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+	"github.com/QingsiLiu/baseComponents/utils"
+)
+
+var signNow = time.Unix(1700000000, 0)
+
+func newCloudFrontSigner(t *testing.T) (*storage.CloudFrontSigner, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &storage.CloudFrontSigner{
+		BaseURL:    "https://d111.cloudfront.net",
+		KeyPairID:  "K2JCJMDEHXQW5F",
+		PrivateKey: key,
+		Now:        func() time.Time { return signNow },
+	}, key
+}
+
+func decodeCloudFront(t *testing.T, value string) []byte {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(value))
+	if err != nil {
+		t.Fatalf("decode %q: %v", value, err)
+	}
+	return data
+}
+
+func verifyCloudFront(t *testing.T, key *rsa.PrivateKey, policy []byte, signature string) {
+	t.Helper()
+	digest := sha1.Sum(policy)
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, digest[:], decodeCloudFront(t, signature)); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+}
+
+func TestCloudFrontCannedPolicy(t *testing.T) {
+	signer, key := newCloudFrontSigner(t)
+
+	signed, err := signer.SignURL("bucket", "videos/intro clip.mp4", storage.SignOptions{Expires: 10 * time.Minute})
+	if err != nil {
+		t.Fatalf("SignURL() error = %v", err)
+	}
+	parsed, _ := url.Parse(signed)
+	query := parsed.Query()
+	if query.Get("Expires") != "1700000600" || query.Get("Key-Pair-Id") != "K2JCJMDEHXQW5F" || query.Has("Policy") {
+		t.Fatalf("SignURL() = %s", signed)
+	}
+
+	resource := "https://d111.cloudfront.net/videos/intro%20clip.mp4"
+	policy := `{"Statement":[{"Resource":"` + resource + `","Condition":{"DateLessThan":{"AWS:EpochTime":1700000600}}}]}`
+	verifyCloudFront(t, key, []byte(policy), query.Get("Signature"))
+}
+
+func TestCloudFrontCustomPolicyRestrictsIPAndPath(t *testing.T) {
+	signer, key := newCloudFrontSigner(t)
+
+	signed, err := signer.SignURL("bucket", "videos/1/index.m3u8", storage.SignOptions{
+		ClientIP: "203.0.113.7",
+		Resource: "videos/1/*",
+	})
+	if err != nil {
+		t.Fatalf("SignURL() error = %v", err)
+	}
+	query, _ := url.ParseQuery(signed[strings.Index(signed, "?")+1:])
+	if query.Has("Expires") {
+		t.Fatalf("custom policy url has Expires: %s", signed)
+	}
+	policy := decodeCloudFront(t, query.Get("Policy"))
+	for _, want := range []string{`"Resource":"https://d111.cloudfront.net/videos/1/*"`, `"AWS:SourceIp":"203.0.113.7/32"`, `"AWS:EpochTime":1700003600`} {
+		if !strings.Contains(string(policy), want) {
+			t.Fatalf("policy %s missing %s", policy, want)
+		}
+	}
+	verifyCloudFront(t, key, policy, query.Get("Signature"))
+}
+
+func TestCloudFrontSignCookies(t *testing.T) {
+	signer, _ := newCloudFrontSigner(t)
+
+	if _, err := signer.SignCookies(storage.SignOptions{}); err == nil {
+		t.Fatal("SignCookies() without resource succeeded")
+	}
+	cookies, err := signer.SignCookies(storage.SignOptions{Resource: "videos/*"})
+	if err != nil {
+		t.Fatalf("SignCookies() error = %v", err)
+	}
+	names := make([]string, 0, len(cookies))
+	for _, cookie := range cookies {
+		names = append(names, cookie.Name)
+	}
+	if got := strings.Join(names, ","); got != "CloudFront-Policy,CloudFront-Signature,CloudFront-Key-Pair-Id" {
+		t.Fatalf("cookie names = %s", got)
+	}
+}
+
+func TestTokenSignerTypes(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		signer storage.TokenSigner
+		want   string
+	}{
+		{
+			name:   "B",
+			signer: storage.TokenSigner{Type: storage.TokenAuthB},
+			want:   "https://cdn.example.com/202401020304/" + utils.MD5String("key202401020304/img/a.png") + "/img/a.png",
+		},
+		{
+			name:   "C",
+			signer: storage.TokenSigner{Type: storage.TokenAuthC},
+			want:   "https://cdn.example.com/" + utils.MD5String("key/img/a.png65937d25") + "/65937d25/img/a.png",
+		},
+		{
+			// 配置 TTL 后签发时间前移，使 CDN 在 Expires 后判定过期
+			name:   "C with TTL",
+			signer: storage.TokenSigner{Type: storage.TokenAuthC, TTL: 2 * time.Hour},
+			want:   "https://cdn.example.com/" + utils.MD5String("key/img/a.png65936f15") + "/65936f15/img/a.png",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.signer.BaseURL = "https://cdn.example.com"
+			tt.signer.Key = "key"
+			tt.signer.Now = func() time.Time { return now }
+			got, err := tt.signer.SignURL("bucket", "img/a.png", storage.SignOptions{})
+			if err != nil {
+				t.Fatalf("SignURL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("SignURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenSignerTypeA(t *testing.T) {
+	signer := &storage.TokenSigner{BaseURL: "https://cdn.example.com", Key: "key", UID: "7", Now: func() time.Time { return signNow }}
+
+	signed, err := signer.SignURL("bucket", "img/a.png", storage.SignOptions{Expires: time.Minute})
+	if err != nil {
+		t.Fatalf("SignURL() error = %v", err)
+	}
+	parsed, _ := url.Parse(signed)
+	parts := strings.Split(parsed.Query().Get("auth_key"), "-")
+	if len(parts) != 4 || parts[0] != "1700000060" || parts[2] != "7" {
+		t.Fatalf("SignURL() = %s", signed)
+	}
+	if want := utils.MD5String("/img/a.png-1700000060-" + parts[1] + "-7-key"); parts[3] != want {
+		t.Fatalf("hash = %s, want %s", parts[3], want)
+	}
+
+	if _, err := signer.SignURL("bucket", "img/a.png", storage.SignOptions{ClientIP: "203.0.113.7"}); !errors.Is(err, storage.ErrSignRestrictionUnsupported) {
+		t.Fatalf("SignURL() with ClientIP error = %v, want ErrSignRestrictionUnsupported", err)
+	}
+}
+
+func TestSignDownloadURLsMiddleware(t *testing.T) {
+	signers := storage.BucketSigners{
+		"media": &storage.TokenSigner{BaseURL: "https://cdn.example.com", Type: storage.TokenAuthC, Key: "key", Now: func() time.Time { return signNow }},
+	}
+	svc := storage.Chain(memory.NewMemoryService("http://localhost"), storage.SignDownloadURLs(signers, storage.SignOptions{}))
+
+	if got := svc.GenerateDownloadURL("media", "a.png"); !strings.HasPrefix(got, "https://cdn.example.com/") {
+		t.Fatalf("GenerateDownloadURL() = %q", got)
+	}
+	if got := svc.GenerateDownloadURL("other", "a.png"); got != "" {
+		t.Fatalf("GenerateDownloadURL() for unconfigured bucket = %q, want empty", got)
+	}
+}
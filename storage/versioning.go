@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// NullVersionID 未开启版本控制时写入的对象版本ID（与 S3 一致）
+const NullVersionID = "null"
+
+// ErrVersionNotFound 指定的对象版本不存在或不可恢复
+var ErrVersionNotFound = errors.New("storage: object version not found")
+
+// ObjectTagGetter 支持读取对象标签的存储实现
+type ObjectTagGetter interface {
+	// GetObjectTags 获取对象标签，未设置时返回空 map
+	GetObjectTags(bucketName, fileKey string) (map[string]string, error)
+}
+
+// ObjectTagger 支持读写对象标签的存储实现
+type ObjectTagger interface {
+	ObjectTagGetter
+
+	// PutObjectTags 覆盖设置对象标签
+	PutObjectTags(bucketName, fileKey string, tags map[string]string) error
+}
+
+// ObjectVersion 对象的一个版本
+type ObjectVersion struct {
+	Key            string    `json:"key"`
+	VersionID      string    `json:"versionId"`
+	Size           int64     `json:"size"`
+	LastModified   time.Time `json:"lastModified"`
+	ETag           string    `json:"etag"`
+	IsLatest       bool      `json:"isLatest"`       // 是否为当前版本
+	IsDeleteMarker bool      `json:"isDeleteMarker"` // 删除标记，没有内容
+}
+
+// VersionedStorage 支持对象多版本的存储实现
+type VersionedStorage interface {
+	// SetBucketVersioning 开启或暂停存储桶版本控制
+	SetBucketVersioning(bucketName string, enabled bool) error
+
+	// ListObjectVersions 列举前缀下全部对象的全部版本（含删除标记），自动翻页
+	ListObjectVersions(bucketName, prefix string) ([]ObjectVersion, error)
+
+	// GetObjectVersion 获取指定版本的内容
+	GetObjectVersion(bucketName, fileKey, versionID string) ([]byte, error)
+
+	// RestoreObject 将指定版本复制为当前版本，versionID 为空时恢复最近一个非删除标记的版本
+	RestoreObject(bucketName, fileKey, versionID string) error
+}
+
+// FindRestoreVersion 从版本列表中找出 RestoreObject 要恢复的版本。
+// versionID 为空时返回 fileKey 最近修改的非删除标记版本；删除标记不可恢复。
+func FindRestoreVersion(versions []ObjectVersion, fileKey, versionID string) (ObjectVersion, error) {
+	var found *ObjectVersion
+	for i := range versions {
+		version := &versions[i]
+		if version.Key != fileKey || version.IsDeleteMarker {
+			continue
+		}
+		if versionID != "" {
+			if version.VersionID == versionID {
+				return *version, nil
+			}
+			continue
+		}
+		if found == nil || version.LastModified.After(found.LastModified) {
+			found = version
+		}
+	}
+	if found == nil {
+		if versionID != "" {
+			return ObjectVersion{}, fmt.Errorf("%w: %s@%s", ErrVersionNotFound, fileKey, versionID)
+		}
+		return ObjectVersion{}, fmt.Errorf("%w: %s", ErrVersionNotFound, fileKey)
+	}
+	return *found, nil
+}
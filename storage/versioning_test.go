@@ -0,0 +1,79 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+func TestMemoryVersioningRestoresDeletedObject(t *testing.T) {
+	svc := memory.NewMemoryService("")
+	_ = svc.UploadObject("b", "doc.txt", []byte("v0"))
+	if err := svc.SetBucketVersioning("b", true); err != nil {
+		t.Fatalf("SetBucketVersioning() error = %v", err)
+	}
+	_ = svc.UploadObject("b", "doc.txt", []byte("v1"))
+	_ = svc.PutObjectTags("b", "doc.txt", map[string]string{"stage": "draft"})
+	_ = svc.DeleteObject("b", "doc.txt")
+
+	if svc.HeadObject("b", "doc.txt") {
+		t.Fatal("deleted object should not be current")
+	}
+	versions, err := svc.ListObjectVersions("b", "doc")
+	if err != nil {
+		t.Fatalf("ListObjectVersions() error = %v", err)
+	}
+	if len(versions) != 3 || !versions[0].IsDeleteMarker || !versions[0].IsLatest || versions[2].VersionID != storage.NullVersionID {
+		t.Fatalf("versions = %+v", versions)
+	}
+
+	data, err := svc.GetObjectVersion("b", "doc.txt", storage.NullVersionID)
+	if err != nil || string(data) != "v0" {
+		t.Fatalf("GetObjectVersion(null) = %q, %v", data, err)
+	}
+	if _, err := svc.GetObjectVersion("b", "doc.txt", versions[0].VersionID); !errors.Is(err, storage.ErrVersionNotFound) {
+		t.Fatalf("GetObjectVersion(delete marker) error = %v, want ErrVersionNotFound", err)
+	}
+
+	if err := svc.RestoreObject("b", "doc.txt", ""); err != nil {
+		t.Fatalf("RestoreObject() error = %v", err)
+	}
+	data, _ = svc.GetObject("b", "doc.txt")
+	tags, _ := svc.GetObjectTags("b", "doc.txt")
+	if string(data) != "v1" || tags["stage"] != "draft" {
+		t.Fatalf("restored %q with tags %v, want v1 with stage=draft", data, tags)
+	}
+
+	if err := svc.RestoreObject("b", "doc.txt", storage.NullVersionID); err != nil {
+		t.Fatalf("RestoreObject(null) error = %v", err)
+	}
+	if data, _ = svc.GetObject("b", "doc.txt"); string(data) != "v0" {
+		t.Fatalf("restored %q, want v0", data)
+	}
+	if versions, _ = svc.ListObjectVersions("b", "doc.txt"); len(versions) != 5 {
+		t.Fatalf("restores should add versions, got %+v", versions)
+	}
+}
+
+func TestFindRestoreVersion(t *testing.T) {
+	versions := []storage.ObjectVersion{
+		{Key: "a", VersionID: "1"},
+		{Key: "a", VersionID: "3", IsDeleteMarker: true},
+		{Key: "ab", VersionID: "4"},
+	}
+	versions[0].LastModified = versions[0].LastModified.Add(1)
+	versions[1].LastModified = versions[0].LastModified.Add(1)
+	versions[2].LastModified = versions[0].LastModified.Add(2)
+
+	if version, err := storage.FindRestoreVersion(versions, "a", ""); err != nil || version.VersionID != "1" {
+		t.Fatalf("FindRestoreVersion() = %+v, %v", version, err)
+	}
+	if _, err := storage.FindRestoreVersion(versions, "a", "3"); !errors.Is(err, storage.ErrVersionNotFound) {
+		t.Fatalf("restoring a delete marker error = %v", err)
+	}
+	if _, err := storage.FindRestoreVersion(versions, "b", ""); !errors.Is(err, storage.ErrVersionNotFound) {
+		t.Fatalf("missing key error = %v", err)
+	}
+}
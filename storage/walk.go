@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// DefaultPageSize 遍历时每页列举的默认数量
+const DefaultPageSize = 1000
+
+// SkipAll 由 WalkFunc 返回时立即结束遍历，Walk 返回 nil
+var SkipAll = errors.New("skip everything and stop the walk")
+
+// WalkFunc 遍历回调，非递归遍历时子目录以 IsPrefix 为 true 的条目返回
+type WalkFunc func(object ObjectInfo) error
+
+// WalkOption 遍历选项
+type WalkOption func(*walkConfig)
+
+type walkConfig struct {
+	recursive *bool
+	pageSize  int32
+}
+
+// WithRecursive 设置是否递归遍历子目录。
+// 未设置时按 input.Delimiter 判断：为空即递归；非递归且未指定分隔符时使用 "/"。
+func WithRecursive(recursive bool) WalkOption {
+	return func(c *walkConfig) {
+		c.recursive = &recursive
+	}
+}
+
+// WithPageSize 设置每页列举数量，覆盖 input.MaxKeys
+func WithPageSize(pageSize int32) WalkOption {
+	return func(c *walkConfig) {
+		c.pageSize = pageSize
+	}
+}
+
+// Walk 按键的字典序分页遍历对象，自动处理 ContinuationToken。
+// 回调返回 SkipAll 时提前结束；返回其他错误时中止遍历并返回该错误。
+func Walk(ctx context.Context, svc StorageService, input *ListObjectsInput, fn WalkFunc, opts ...WalkOption) error {
+	if input == nil {
+		return errors.New("storage: list objects input is nil")
+	}
+
+	config := walkConfig{pageSize: input.MaxKeys}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	// 复制一份，避免修改调用方的输入
+	listInput := *input
+	listInput.MaxKeys = config.pageSize
+	if listInput.MaxKeys <= 0 {
+		listInput.MaxKeys = DefaultPageSize
+	}
+	if config.recursive != nil {
+		if *config.recursive {
+			listInput.Delimiter = ""
+		} else if listInput.Delimiter == "" {
+			listInput.Delimiter = "/"
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		page, err := svc.ListObjects(&listInput)
+		if err != nil {
+			return err
+		}
+
+		last := ""
+		for _, object := range mergePage(page) {
+			if err := fn(object); err != nil {
+				if errors.Is(err, SkipAll) {
+					return nil
+				}
+				return err
+			}
+			last = object.Key
+		}
+
+		if !page.IsTruncated {
+			return nil
+		}
+		switch {
+		case page.NextContinuationToken != "":
+			// 部分兼容 S3 的存储会返回带令牌的空页，只要有令牌就继续翻页
+			if page.NextContinuationToken == listInput.ContinuationToken {
+				return fmt.Errorf("storage: list objects returned the same continuation token %q", page.NextContinuationToken)
+			}
+			listInput.ContinuationToken = page.NextContinuationToken
+		case last != "":
+			// 后端未返回分页令牌时，从本页最后一个键之后继续
+			listInput.ContinuationToken = ""
+			listInput.StartAfter = last
+		default:
+			return nil
+		}
+	}
+}
+
+// Objects 返回遍历对象的迭代器，可直接用于 for range。
+// 出错时产出一次 (ObjectInfo{}, err) 后结束。
+func Objects(ctx context.Context, svc StorageService, input *ListObjectsInput, opts ...WalkOption) iter.Seq2[ObjectInfo, error] {
+	return func(yield func(ObjectInfo, error) bool) {
+		err := Walk(ctx, svc, input, func(object ObjectInfo) error {
+			if !yield(object, nil) {
+				return SkipAll
+			}
+			return nil
+		}, opts...)
+		if err != nil {
+			yield(ObjectInfo{}, err)
+		}
+	}
+}
+
+// DeletePrefix 递归删除前缀下的全部对象，每 batchSize 个调用一次 DeleteObjects。
+// 边列举边删除，内存占用与对象总数无关。
+func DeletePrefix(ctx context.Context, svc StorageService, bucketName, prefix string, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = DefaultPageSize
+	}
+
+	keys := make([]string, 0, batchSize)
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		_, err := svc.DeleteObjects(bucketName, keys)
+		keys = keys[:0]
+		return err
+	}
+
+	err := Walk(ctx, svc, &ListObjectsInput{Bucket: bucketName, Prefix: prefix}, func(object ObjectInfo) error {
+		keys = append(keys, object.Key)
+		if len(keys) < batchSize {
+			return nil
+		}
+		return flush()
+	}, WithRecursive(true))
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// ListPrefixes 列举 prefix 下一层的全部“目录”，自动翻页
+func ListPrefixes(ctx context.Context, svc StorageService, bucketName, prefix string) ([]string, error) {
+	folders := []string{}
+	err := Walk(ctx, svc, &ListObjectsInput{Bucket: bucketName, Prefix: prefix}, func(object ObjectInfo) error {
+		if object.IsPrefix {
+			folders = append(folders, object.Key)
+		}
+		return nil
+	}, WithRecursive(false))
+	return folders, err
+}
+
+// mergePage 将一页中的对象与公共前缀按字典序合并，公共前缀作为 IsPrefix 条目
+func mergePage(page *ListObjectsOutput) []ObjectInfo {
+	if len(page.CommonPrefixes) == 0 {
+		return page.Objects
+	}
+
+	merged := make([]ObjectInfo, 0, len(page.Objects)+len(page.CommonPrefixes))
+	i, j := 0, 0
+	for i < len(page.Objects) || j < len(page.CommonPrefixes) {
+		if j == len(page.CommonPrefixes) || (i < len(page.Objects) && page.Objects[i].Key < page.CommonPrefixes[j]) {
+			merged = append(merged, page.Objects[i])
+			i++
+			continue
+		}
+		merged = append(merged, ObjectInfo{Key: page.CommonPrefixes[j], IsDir: true, IsPrefix: true})
+		j++
+	}
+	return merged
+}
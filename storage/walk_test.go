@@ -0,0 +1,183 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/memory"
+)
+
+type countingService struct {
+	*memory.MemoryService
+	listCalls    int
+	deleteCalls  []int
+	listMaxKeys  int32
+	listDelimits []string
+}
+
+func (c *countingService) ListObjects(input *storage.ListObjectsInput) (*storage.ListObjectsOutput, error) {
+	c.listCalls++
+	c.listMaxKeys = input.MaxKeys
+	c.listDelimits = append(c.listDelimits, input.Delimiter)
+	return c.MemoryService.ListObjects(input)
+}
+
+func (c *countingService) DeleteObjects(bucketName string, fileKeys []string) ([]string, error) {
+	c.deleteCalls = append(c.deleteCalls, len(fileKeys))
+	return c.MemoryService.DeleteObjects(bucketName, fileKeys)
+}
+
+func newCountingService(t *testing.T, keys ...string) *countingService {
+	t.Helper()
+	svc := &countingService{MemoryService: memory.NewMemoryService("")}
+	for _, key := range keys {
+		if err := svc.UploadObject("b", key, []byte(key)); err != nil {
+			t.Fatalf("UploadObject(%q) error = %v", key, err)
+		}
+	}
+	return svc
+}
+
+func TestWalkFollowsPagination(t *testing.T) {
+	var keys []string
+	for i := 0; i < 25; i++ {
+		keys = append(keys, fmt.Sprintf("data/%02d.bin", i))
+	}
+	svc := newCountingService(t, keys...)
+
+	var got []string
+	err := storage.Walk(context.Background(), svc, &storage.ListObjectsInput{Bucket: "b", Prefix: "data/"}, func(object storage.ObjectInfo) error {
+		got = append(got, object.Key)
+		return nil
+	}, storage.WithPageSize(10))
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if len(got) != 25 || got[0] != keys[0] || got[24] != keys[24] {
+		t.Fatalf("walked %d keys (%v), want all 25 in order", len(got), got)
+	}
+	if svc.listCalls != 3 || svc.listMaxKeys != 10 {
+		t.Fatalf("list calls = %d, max keys = %d, want 3 pages of 10", svc.listCalls, svc.listMaxKeys)
+	}
+}
+
+// emptyPages 在每页真实结果之前插入一个带令牌的空页，模拟部分兼容 S3 的存储
+type emptyPages struct {
+	*memory.MemoryService
+}
+
+func (e *emptyPages) ListObjects(input *storage.ListObjectsInput) (*storage.ListObjectsOutput, error) {
+	if token, ok := strings.CutPrefix(input.ContinuationToken, "empty:"); ok {
+		listInput := *input
+		listInput.ContinuationToken = token
+		return e.MemoryService.ListObjects(&listInput)
+	}
+	return &storage.ListObjectsOutput{IsTruncated: true, NextContinuationToken: "empty:" + input.ContinuationToken}, nil
+}
+
+func TestWalkContinuesPastEmptyTruncatedPages(t *testing.T) {
+	svc := &emptyPages{MemoryService: memory.NewMemoryService("")}
+	for i := 0; i < 5; i++ {
+		_ = svc.UploadObject("b", fmt.Sprintf("data/%d.bin", i), []byte("x"))
+	}
+
+	var got []string
+	err := storage.Walk(context.Background(), svc, &storage.ListObjectsInput{Bucket: "b", Prefix: "data/"}, func(object storage.ObjectInfo) error {
+		got = append(got, object.Key)
+		return nil
+	}, storage.WithPageSize(2))
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if len(got) != 5 {
+		t.Fatalf("walked %v, want all 5 keys", got)
+	}
+}
+
+func TestWalkNonRecursiveYieldsPrefixesInOrder(t *testing.T) {
+	svc := newCountingService(t, "a.txt", "dir/x", "dir/y", "m.txt", "sub/z")
+
+	var got []string
+	err := storage.Walk(context.Background(), svc, &storage.ListObjectsInput{Bucket: "b"}, func(object storage.ObjectInfo) error {
+		if object.IsPrefix {
+			got = append(got, "prefix:"+object.Key)
+		} else {
+			got = append(got, object.Key)
+		}
+		return nil
+	}, storage.WithRecursive(false), storage.WithPageSize(2))
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	want := []string{"a.txt", "prefix:dir/", "m.txt", "prefix:sub/"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("walk = %v, want %v", got, want)
+	}
+	if svc.listDelimits[0] != "/" {
+		t.Fatalf("delimiter = %q, want /", svc.listDelimits[0])
+	}
+}
+
+func TestObjectsIteratorStopsAndReportsErrors(t *testing.T) {
+	svc := newCountingService(t, "1", "2", "3")
+
+	count := 0
+	for _, err := range storage.Objects(context.Background(), svc, &storage.ListObjectsInput{Bucket: "b"}, storage.WithPageSize(1)) {
+		if err != nil {
+			t.Fatalf("iterator error = %v", err)
+		}
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 || svc.listCalls != 2 {
+		t.Fatalf("count = %d, list calls = %d, want early stop after 2", count, svc.listCalls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range storage.Objects(ctx, svc, &storage.ListObjectsInput{Bucket: "b"}) {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+	}
+}
+
+func TestDeletePrefixBatches(t *testing.T) {
+	var keys []string
+	for i := 0; i < 7; i++ {
+		keys = append(keys, fmt.Sprintf("folder/%d", i))
+	}
+	svc := newCountingService(t, append(keys, "folder-sibling", "other/1")...)
+
+	if err := storage.DeletePrefix(context.Background(), svc, "b", "folder/", 3); err != nil {
+		t.Fatalf("DeletePrefix() error = %v", err)
+	}
+	if fmt.Sprint(svc.deleteCalls) != "[3 3 1]" {
+		t.Fatalf("delete batches = %v, want [3 3 1]", svc.deleteCalls)
+	}
+	if svc.HeadObject("b", "folder/0") || !svc.HeadObject("b", "folder-sibling") || !svc.HeadObject("b", "other/1") {
+		t.Fatal("DeletePrefix removed the wrong objects")
+	}
+}
+
+func TestListPrefixesSpansPages(t *testing.T) {
+	var keys []string
+	for i := 0; i < 1500; i++ {
+		keys = append(keys, fmt.Sprintf("users/%04d/avatar.png", i))
+	}
+	svc := newCountingService(t, append(keys, "users/readme.txt")...)
+
+	folders, err := storage.ListPrefixes(context.Background(), svc, "b", "users/")
+	if err != nil {
+		t.Fatalf("ListPrefixes() error = %v", err)
+	}
+	if len(folders) != 1500 || folders[1499] != "users/1499/" {
+		t.Fatalf("got %d folders, want 1500", len(folders))
+	}
+}
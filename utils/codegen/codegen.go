@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/token"
+	"go/types"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// config 生成参数
+type config struct {
+	dir       string   // 常量所在的包目录
+	typeName  string   // 错误码常量的类型
+	output    string   // 注册代码文件
+	doc       string   // Markdown 文档
+	jsonDoc   string   // JSON 文档
+	reference string   // Coder.Reference()
+	args      []string // 命令行参数，写入生成文件的头部
+}
+
+// errorCode 带注释的错误码常量
+type errorCode struct {
+	Name    string `json:"name"`
+	Code    int    `json:"code"`
+	HTTP    int    `json:"http"`
+	Message string `json:"message"`
+
+	pos token.Position
+}
+
+// annotationPattern 匹配 "- 404: User not found." 形式的注释（常量名之后的部分）
+var annotationPattern = regexp.MustCompile(`^\s+-\s+(\d+):\s*(.*?)\.?\s*$`)
+
+// reservedCodes errors 包保留的错误码
+var reservedCodes = map[int]string{
+	0: "reserved",
+	1: "ErrUnknown",
+}
+
+// generate 解析包中的错误码常量，校验后写入注册代码和文档
+func generate(cfg config) ([]errorCode, error) {
+	output := ""
+	if cfg.output != "" {
+		var err error
+		if output, err = filepath.Abs(cfg.output); err != nil {
+			return nil, err
+		}
+	}
+
+	pkg, err := loadPackage(cfg.dir, output)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := collect(pkg, cfg.typeName, output)
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(codes); err != nil {
+		return nil, err
+	}
+
+	header := fmt.Sprintf("Code generated by \"codegen %s\"; DO NOT EDIT.", strings.Join(cfg.args, " "))
+	if cfg.output != "" {
+		src, err := renderCode(header, pkg.Name, cfg.reference, codes)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(cfg.output, src, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.doc != "" {
+		if err := os.WriteFile(cfg.doc, renderMarkdown(header, codes), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.jsonDoc != "" {
+		data, err := json.MarshalIndent(codes, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(cfg.jsonDoc, append(data, '\n'), 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// loadPackage 加载并类型检查包。之前生成的注册代码可能引用了已删除的常量，其中的错误被忽略
+func loadPackage(dir, output string) (*packages.Package, error) {
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:  dir,
+	}, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%s: expected 1 package, found %d", dir, len(pkgs))
+	}
+
+	pkg := pkgs[0]
+	var errs []error
+	for _, e := range pkg.Errors {
+		if !inFile(e, output) {
+			errs = append(errs, e)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return pkg, nil
+}
+
+// inFile 判断错误是否位于 file 中。go list 报告的编译错误不带位置，消息中为相对路径
+func inFile(e packages.Error, file string) bool {
+	if file == "" {
+		return false
+	}
+	if e.Pos != "" {
+		return strings.HasPrefix(e.Pos, file+":")
+	}
+
+	return strings.Contains(e.Msg, "/"+filepath.Base(file)+":")
+}
+
+// collect 收集指定类型且带注释的常量，按错误码排序
+func collect(pkg *packages.Package, typeName, output string) ([]errorCode, error) {
+	var (
+		codes []errorCode
+		errs  []error
+	)
+	for _, file := range pkg.Syntax {
+		if filename := pkg.Fset.Position(file.Pos()).Filename; output != "" && filename == output {
+			continue
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vspec := spec.(*ast.ValueSpec)
+				comments := []*ast.CommentGroup{vspec.Doc, vspec.Comment}
+				if !gen.Lparen.IsValid() {
+					comments = append(comments, gen.Doc)
+				}
+				for _, name := range vspec.Names {
+					obj, ok := pkg.TypesInfo.Defs[name].(*types.Const)
+					if !ok || !matchType(obj, pkg.Types, typeName) {
+						continue
+					}
+					code, ok, err := parseConst(obj, comments)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("%s: %w", pkg.Fset.Position(name.Pos()), err))
+					}
+					if ok {
+						code.pos = pkg.Fset.Position(name.Pos())
+						codes = append(codes, code)
+					}
+				}
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+
+	return codes, nil
+}
+
+// matchType 判断常量类型，int 同时匹配无类型整数常量
+func matchType(obj *types.Const, pkg *types.Package, typeName string) bool {
+	if typeName == "int" {
+		if basic, ok := obj.Type().(*types.Basic); ok && basic.Kind() == types.UntypedInt {
+			return true
+		}
+	}
+
+	return types.TypeString(obj.Type(), types.RelativeTo(pkg)) == typeName
+}
+
+// parseConst 从常量的注释中解析 HTTP 状态码和对外消息，没有注释时 ok 为 false
+func parseConst(obj *types.Const, comments []*ast.CommentGroup) (errorCode, bool, error) {
+	for _, group := range comments {
+		if group == nil {
+			continue
+		}
+		for _, line := range strings.Split(group.Text(), "\n") {
+			status, message, ok, err := parseAnnotation(obj.Name(), line)
+			if err != nil {
+				return errorCode{}, false, err
+			}
+			if !ok {
+				continue
+			}
+			value, exact := constant.Int64Val(obj.Val())
+			if !exact || int64(int(value)) != value {
+				return errorCode{}, false, fmt.Errorf("%s: value %s overflows int", obj.Name(), obj.Val())
+			}
+
+			return errorCode{Name: obj.Name(), Code: int(value), HTTP: status, Message: message}, true, nil
+		}
+	}
+
+	return errorCode{}, false, nil
+}
+
+// parseAnnotation 解析 "ErrUserNotFound - 404: User not found." 形式的一行注释，
+// 行首不是 "<常量名> -" 时 ok 为 false，格式错误时返回错误
+func parseAnnotation(name, line string) (status int, message string, ok bool, err error) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(line), name)
+	if !found || !strings.HasPrefix(strings.TrimLeft(rest, " \t"), "-") {
+		return 0, "", false, nil
+	}
+	m := annotationPattern.FindStringSubmatch(rest)
+	if m == nil || m[2] == "" {
+		return 0, "", false, fmt.Errorf("%s: malformed annotation %q, want \"%s - <http status>: <message>\"", name, strings.TrimSpace(line), name)
+	}
+	status, err = strconv.Atoi(m[1])
+	if err != nil {
+		return 0, "", false, fmt.Errorf("%s: invalid HTTP status %q", name, m[1])
+	}
+
+	return status, m[2], true, nil
+}
+
+// validate 检查错误码重复、保留错误码和无效的 HTTP 状态码，返回全部问题
+func validate(codes []errorCode) error {
+	var errs []error
+	seen := map[int]errorCode{}
+	for _, c := range codes {
+		if reserved, ok := reservedCodes[c.Code]; ok {
+			errs = append(errs, fmt.Errorf("%s: %s: code %d is reserved by the errors package (%s)", c.pos, c.Name, c.Code, reserved))
+		}
+		if prev, ok := seen[c.Code]; ok {
+			errs = append(errs, fmt.Errorf("%s: %s: duplicate code %d, already used by %s at %s", c.pos, c.Name, c.Code, prev.Name, prev.pos))
+		} else {
+			seen[c.Code] = c
+		}
+		if c.HTTP < 100 || c.HTTP > 599 || http.StatusText(c.HTTP) == "" {
+			errs = append(errs, fmt.Errorf("%s: %s: invalid HTTP status %d", c.pos, c.Name, c.HTTP))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+var codeTemplate = template.Must(template.New("code").Parse(`// {{.Header}}
+
+package {{.Package}}
+
+import "github.com/QingsiLiu/baseComponents/errors"
+
+func init() {
+{{- range .Codes}}
+	errors.MustRegister(errors.NewCoder({{.Name}}, {{.HTTP}}, {{printf "%q" .Message}}, {{printf "%q" $.Reference}}))
+{{- end}}
+}
+`))
+
+// renderCode 生成在 init 中注册全部错误码的代码
+func renderCode(header, pkgName, reference string, codes []errorCode) ([]byte, error) {
+	var buf bytes.Buffer
+	err := codeTemplate.Execute(&buf, map[string]interface{}{
+		"Header":    header,
+		"Package":   pkgName,
+		"Reference": reference,
+		"Codes":     codes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// renderMarkdown 生成 Markdown 错误码文档
+func renderMarkdown(header string, codes []errorCode) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# 错误码\n\n<!-- %s -->\n\n", header)
+	buf.WriteString("| 标识符 | 错误码 | HTTP 状态码 | 描述 |\n")
+	buf.WriteString("| ------ | ------ | ----------- | ---- |\n")
+	for _, c := range codes {
+		message := strings.ReplaceAll(c.Message, "|", `\|`)
+		fmt.Fprintf(&buf, "| %s | %d | %d | %s |\n", c.Name, c.Code, c.HTTP, message)
+	}
+
+	return buf.Bytes()
+}
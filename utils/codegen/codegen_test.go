@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixture 将 testdata 中的包复制到独立模块的临时目录
+func fixture(t *testing.T, name string) string {
+	dir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("testdata", name, "code.go"))
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "code.go"), src, 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/"+name+"\n\ngo 1.24\n"), 0o644))
+
+	return dir
+}
+
+func TestGenerate(t *testing.T) {
+	dir := fixture(t, "code")
+	cfg := config{
+		dir:       dir,
+		typeName:  "int",
+		output:    filepath.Join(dir, "code_generated.go"),
+		doc:       filepath.Join(dir, "error_code.md"),
+		jsonDoc:   filepath.Join(dir, "error_code.json"),
+		reference: "https://example.com/error_code.md",
+		args:      []string{"-type=int"},
+	}
+	// 过期的生成代码引用了已删除的常量
+	assert.Nil(t, os.WriteFile(cfg.output, []byte("package code\n\nvar _ = ErrRemoved\n"), 0o644))
+
+	codes, err := generate(cfg)
+	assert.Nil(t, err)
+	names := make([]string, 0, len(codes))
+	for _, c := range codes {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"ErrUserNotFound", "ErrUserAlreadyExist", "ErrPasswordIncorrect", "ErrQuotaExceeded"}, names)
+
+	src, err := os.ReadFile(cfg.output)
+	assert.Nil(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), cfg.output, src, 0)
+	assert.Nil(t, err)
+	code := string(src)
+	assert.True(t, strings.HasPrefix(code, `// Code generated by "codegen -type=int"; DO NOT EDIT.`))
+	assert.Contains(t, code, `errors.MustRegister(errors.NewCoder(ErrUserNotFound, 404, "User not found", "https://example.com/error_code.md"))`)
+	assert.Contains(t, code, `errors.NewCoder(ErrQuotaExceeded, 429, "Quota of {{.limit}} requests exceeded"`)
+	assert.NotContains(t, code, "errInternal")
+	assert.NotContains(t, code, "StatusDone")
+
+	doc, err := os.ReadFile(cfg.doc)
+	assert.Nil(t, err)
+	assert.Contains(t, string(doc), "| ErrUserAlreadyExist | 120002 | 400 | User already exist |\n")
+	assert.Contains(t, string(doc), `| ErrPasswordIncorrect | 120003 | 401 | Password was incorrect \| retry |`)
+
+	data, err := os.ReadFile(cfg.jsonDoc)
+	assert.Nil(t, err)
+	var decoded []errorCode
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Len(t, decoded, 4)
+	assert.Equal(t, errorCode{Name: "ErrQuotaExceeded", Code: 120101, HTTP: 429, Message: "Quota of {{.limit}} requests exceeded"}, decoded[3])
+
+	// 重新生成时忽略上次生成的代码
+	_, err = generate(cfg)
+	assert.Nil(t, err)
+
+	// 其他类型，不生成文件
+	assert.Nil(t, os.Remove(cfg.output))
+	cfg.typeName, cfg.output, cfg.doc, cfg.jsonDoc = "Status", "", "", ""
+	codes, err = generate(cfg)
+	assert.Nil(t, err)
+	assert.Len(t, codes, 1)
+	assert.Equal(t, "StatusDone", codes[0].Name)
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	dir := fixture(t, "invalid")
+	output := filepath.Join(dir, "code_generated.go")
+	_, err := generate(config{dir: dir, typeName: "int", output: output})
+	assert.NotNil(t, err)
+	msg := err.Error()
+	assert.Contains(t, msg, "ErrB: duplicate code 120001, already used by ErrA")
+	assert.Contains(t, msg, "ErrC: invalid HTTP status 999")
+	assert.Contains(t, msg, "ErrUnknown: code 1 is reserved")
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestParseAnnotation(t *testing.T) {
+	tests := []struct {
+		line    string
+		status  int
+		message string
+		ok      bool
+		err     bool
+	}{
+		{"ErrUserNotFound - 404: User not found.", 404, "User not found", true, false},
+		{"  ErrUserNotFound  -  500:Internal error  ", 500, "Internal error", true, false},
+		{"ErrUserNotFound 用户不存在", 0, "", false, false},
+		{"ErrUserNotFoundTwice - 404: x", 0, "", false, false},
+		{"other comment", 0, "", false, false},
+		{"ErrUserNotFound - not found", 0, "", false, true},
+		{"ErrUserNotFound - 404:", 0, "", false, true},
+	}
+
+	for _, tt := range tests {
+		status, message, ok, err := parseAnnotation("ErrUserNotFound", tt.line)
+		assert.Equal(t, tt.status, status, tt.line)
+		assert.Equal(t, tt.message, message, tt.line)
+		assert.Equal(t, tt.ok, ok, tt.line)
+		assert.Equal(t, tt.err, err != nil, tt.line)
+	}
+}
@@ -0,0 +1,54 @@
+// codegen 根据带注释的错误码常量生成 errors 包的注册代码和错误码文档。
+//
+// 常量注释格式为 "<常量名> - <HTTP 状态码>: <对外消息>"，例如：
+//
+//	// ErrUserNotFound - 404: User not found.
+//	ErrUserNotFound int = iota + 120001
+//
+// 在常量所在的包中通过 go generate 调用：
+//
+//	//go:generate go run github.com/QingsiLiu/baseComponents/utils/codegen -type=int -doc=error_code.md
+//
+// 错误码重复、与 errors 包保留的错误码冲突或 HTTP 状态码无效时生成失败。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	// 命令行参数
+	cfg := config{}
+	flag.StringVar(&cfg.typeName, "type", "int", "错误码常量的类型，int 同时匹配无类型整数常量")
+	flag.StringVar(&cfg.output, "output", "code_generated.go", "生成的注册代码文件，为空则不生成")
+	flag.StringVar(&cfg.doc, "doc", "", "生成的 Markdown 错误码文档，为空则不生成")
+	flag.StringVar(&cfg.jsonDoc, "json", "", "生成的 JSON 错误码文档，为空则不生成")
+	flag.StringVar(&cfg.reference, "reference", "", "错误码的参考文档地址，即 Coder.Reference()")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: codegen [flags] [包目录，默认为当前目录]\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	// 验证参数
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cfg.dir = "."
+	if flag.NArg() == 1 {
+		cfg.dir = flag.Arg(0)
+	}
+	cfg.args = os.Args[1:]
+
+	codes, err := generate(cfg)
+	if err != nil {
+		log.Fatalf("生成错误码失败: %v", err)
+	}
+
+	log.Printf("已生成 %d 个错误码", len(codes))
+}
@@ -0,0 +1,26 @@
+package code
+
+//go:generate go run github.com/QingsiLiu/baseComponents/utils/codegen -type=int -doc=error_code.md
+
+// 用户模块错误码
+const (
+	// ErrUserNotFound - 404: User not found.
+	ErrUserNotFound int = iota + 120001
+
+	// ErrUserAlreadyExist - 400: User already exist.
+	ErrUserAlreadyExist
+
+	// errInternal 未注释的常量不会注册
+	errInternal
+)
+
+// ErrQuotaExceeded - 429: Quota of {{.limit}} requests exceeded.
+const ErrQuotaExceeded = 120101
+
+const ErrPasswordIncorrect = 120003 // ErrPasswordIncorrect - 401: Password was incorrect | retry.
+
+// Status 其他类型的常量
+type Status int
+
+// StatusDone - 200: Done.
+const StatusDone Status = 130001
@@ -0,0 +1,12 @@
+package invalid
+
+const (
+	// ErrA - 404: A.
+	ErrA = 120001
+	// ErrB - 400: B.
+	ErrB = 120001
+	// ErrC - 999: C.
+	ErrC = 120002
+	// ErrUnknown - 500: Unknown.
+	ErrUnknown = 1
+)
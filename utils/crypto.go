@@ -217,62 +217,74 @@ func AESDecryptString(ciphertext, key string) (string, error) {
 	return string(decrypted), nil
 }
 
-// EncryptPrompt 使用AES-GCM对称加密算法加密字符串
-func EncryptPrompt(plainText, key string) (string, error) {
+// AESGCMEncrypt 使用AES-GCM加密，返回随机nonce与密文的拼接，additionalData 可为空
+func AESGCMEncrypt(plaintext, key, additionalData []byte) ([]byte, error) {
 	// 创建密码区块
-	block, err := aes.NewCipher([]byte(key))
+	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// 创建GCM模式
 	aesGCM, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// 创建随机nonce
 	nonce := make([]byte, aesGCM.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// 加密
-	cipherText := aesGCM.Seal(nonce, nonce, []byte(plainText), nil)
-
-	// Base64编码以便于传输
-	return base64.StdEncoding.EncodeToString(cipherText), nil
+	return aesGCM.Seal(nonce, nonce, plaintext, additionalData), nil
 }
 
-// DecryptPrompt 解密被AES-GCM加密的字符串
-func DecryptPrompt(encryptedText, key string) (string, error) {
-	// Base64解码
-	cipherText, err := base64.StdEncoding.DecodeString(encryptedText)
-	if err != nil {
-		return "", err
-	}
-
+// AESGCMDecrypt 解密 AESGCMEncrypt 的输出，additionalData 需与加密时一致
+func AESGCMDecrypt(ciphertext, key, additionalData []byte) ([]byte, error) {
 	// 创建密码区块
-	block, err := aes.NewCipher([]byte(key))
+	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// 创建GCM模式
 	aesGCM, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// 获取nonce大小
 	nonceSize := aesGCM.NonceSize()
-	if len(cipherText) < nonceSize {
-		return "", errors.New("加密文本长度不足")
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("加密文本长度不足")
 	}
 
 	// 提取nonce并解密
-	nonce, cipherTextBytes := cipherText[:nonceSize], cipherText[nonceSize:]
-	plainTextBytes, err := aesGCM.Open(nil, nonce, cipherTextBytes, nil)
+	nonce, ciphertextBytes := ciphertext[:nonceSize], ciphertext[nonceSize:]
+	return aesGCM.Open(nil, nonce, ciphertextBytes, additionalData)
+}
+
+// EncryptPrompt 使用AES-GCM对称加密算法加密字符串
+func EncryptPrompt(plainText, key string) (string, error) {
+	cipherText, err := AESGCMEncrypt([]byte(plainText), []byte(key), nil)
+	if err != nil {
+		return "", err
+	}
+
+	// Base64编码以便于传输
+	return base64.StdEncoding.EncodeToString(cipherText), nil
+}
+
+// DecryptPrompt 解密被AES-GCM加密的字符串
+func DecryptPrompt(encryptedText, key string) (string, error) {
+	// Base64解码
+	cipherText, err := base64.StdEncoding.DecodeString(encryptedText)
+	if err != nil {
+		return "", err
+	}
+
+	plainTextBytes, err := AESGCMDecrypt(cipherText, []byte(key), nil)
 	if err != nil {
 		return "", err
 	}
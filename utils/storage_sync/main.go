@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/QingsiLiu/baseComponents/storage"
+	"github.com/QingsiLiu/baseComponents/storage/gcs"
+	"github.com/QingsiLiu/baseComponents/storage/s3"
+	storagesync "github.com/QingsiLiu/baseComponents/storage/sync"
+	"github.com/QingsiLiu/baseComponents/storage/tos"
+)
+
+type backendFlags struct {
+	s3Region, s3Endpoint, s3AccessKey, s3SecretKey string
+	s3PathStyle                                    bool
+	gcsProject, gcsCredentials                     string
+	tosEndpoint, tosRegion, tosAccessKey           string
+	tosSecretKey                                   string
+}
+
+func main() {
+	// 命令行参数
+	src := flag.String("src", "", "源地址，例如：s3://bucket/prefix、gs://bucket/prefix、tos://bucket/prefix")
+	dst := flag.String("dst", "", "目标地址，格式同 -src")
+	include := flag.String("include", "", "只同步这些前缀（相对于源前缀），多个用逗号分隔")
+	exclude := flag.String("exclude", "", "排除这些前缀（相对于源前缀），多个用逗号分隔")
+	compare := flag.String("compare", "size,mtime", "比较方式：size、etag、mtime，多个用逗号分隔")
+	dryRun := flag.Bool("dry-run", false, "只输出同步计划，不做任何修改")
+	deleteExtraneous := flag.Bool("delete", false, "删除目标中源不存在的对象")
+	workers := flag.Int("workers", 8, "并发数")
+	checkpoint := flag.String("checkpoint", "", "断点文件路径，中断后使用同一文件可续传")
+	verbose := flag.Bool("v", false, "输出跳过的对象")
+
+	var backends backendFlags
+	flag.StringVar(&backends.s3Region, "s3-region", os.Getenv("AWS_REGION"), "S3 区域")
+	flag.StringVar(&backends.s3Endpoint, "s3-endpoint", "", "S3 兼容服务的 endpoint，为空使用 AWS")
+	flag.StringVar(&backends.s3AccessKey, "s3-access-key", "", "S3 AccessKey，为空使用默认凭据链")
+	flag.StringVar(&backends.s3SecretKey, "s3-secret-key", "", "S3 SecretKey")
+	flag.BoolVar(&backends.s3PathStyle, "s3-path-style", false, "S3 使用 path-style 访问")
+	flag.StringVar(&backends.gcsProject, "gcs-project", os.Getenv("GOOGLE_CLOUD_PROJECT"), "GCS 项目ID")
+	flag.StringVar(&backends.gcsCredentials, "gcs-credentials", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"), "GCS 凭据文件")
+	flag.StringVar(&backends.tosEndpoint, "tos-endpoint", os.Getenv("TOS_ENDPOINT"), "TOS endpoint")
+	flag.StringVar(&backends.tosRegion, "tos-region", os.Getenv("TOS_REGION"), "TOS 区域")
+	flag.StringVar(&backends.tosAccessKey, "tos-access-key", os.Getenv("TOS_ACCESS_KEY"), "TOS AccessKey")
+	flag.StringVar(&backends.tosSecretKey, "tos-secret-key", os.Getenv("TOS_SECRET_KEY"), "TOS SecretKey")
+
+	flag.Parse()
+
+	// 验证参数
+	if *src == "" || *dst == "" {
+		fmt.Println("必须提供源地址和目标地址，使用 -src 和 -dst 参数")
+		flag.Usage()
+		os.Exit(1)
+	}
+	compareMode, err := storagesync.ParseCompareMode(*compare)
+	if err != nil {
+		log.Fatalf("比较方式无效: %v", err)
+	}
+
+	// 同一后端只创建一个实例，以便使用服务端复制
+	services := make(map[string]storage.StorageService)
+	srcEndpoint, err := parseEndpoint(*src, backends, services)
+	if err != nil {
+		log.Fatalf("解析源地址失败: %v", err)
+	}
+	dstEndpoint, err := parseEndpoint(*dst, backends, services)
+	if err != nil {
+		log.Fatalf("解析目标地址失败: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *dryRun {
+		log.Println("dry-run 模式，不会修改目标存储")
+	}
+	log.Printf("开始同步: %s -> %s", *src, *dst)
+
+	report, err := storagesync.Run(ctx, srcEndpoint, dstEndpoint, storagesync.Options{
+		Include:          splitList(*include),
+		Exclude:          splitList(*exclude),
+		Compare:          compareMode,
+		DryRun:           *dryRun,
+		DeleteExtraneous: *deleteExtraneous,
+		Workers:          *workers,
+		CheckpointFile:   *checkpoint,
+		OnAction: func(action storagesync.Action) {
+			switch {
+			case action.Err != nil:
+				log.Printf("失败 %s %s: %v", action.Type, action.Key, action.Err)
+			case action.Type != storagesync.ActionSkip || *verbose:
+				log.Printf("%s %s (%d 字节)", action.Type, action.Key, action.Size)
+			}
+		},
+	})
+	if report != nil {
+		log.Printf("同步完成: 扫描 %d，复制 %d（%d 字节），跳过 %d，删除 %d，失败 %d",
+			report.Scanned, report.Copied, report.Bytes, report.Skipped, report.Deleted, len(report.Failures))
+	}
+	if err != nil {
+		if errors.Is(err, storagesync.ErrIncomplete) && *checkpoint != "" {
+			log.Printf("可使用相同的 -checkpoint 重新运行以续传")
+		}
+		log.Fatalf("同步失败: %v", err)
+	}
+}
+
+// parseEndpoint 解析 scheme://bucket/prefix 形式的地址
+func parseEndpoint(raw string, backends backendFlags, services map[string]storage.StorageService) (storagesync.Endpoint, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return storagesync.Endpoint{}, err
+	}
+	if parsed.Host == "" {
+		return storagesync.Endpoint{}, fmt.Errorf("地址缺少存储桶: %s", raw)
+	}
+
+	service, ok := services[parsed.Scheme]
+	if !ok {
+		service, err = newService(parsed.Scheme, backends)
+		if err != nil {
+			return storagesync.Endpoint{}, err
+		}
+		services[parsed.Scheme] = service
+	}
+
+	return storagesync.Endpoint{
+		Service: service,
+		Bucket:  parsed.Host,
+		Prefix:  strings.TrimPrefix(parsed.Path, "/"),
+	}, nil
+}
+
+func newService(scheme string, backends backendFlags) (storage.StorageService, error) {
+	switch scheme {
+	case "s3":
+		return s3.NewS3ServiceWithOptions(s3.S3Options{
+			Region:          backends.s3Region,
+			Endpoint:        backends.s3Endpoint,
+			AccessKeyID:     backends.s3AccessKey,
+			SecretAccessKey: backends.s3SecretKey,
+			UsePathStyle:    backends.s3PathStyle,
+		})
+	case "gs", "gcs":
+		return gcs.NewGCSClient(backends.gcsProject, backends.gcsCredentials)
+	case "tos":
+		return tos.NewTOSService(tos.Config{
+			Endpoint:  backends.tosEndpoint,
+			Region:    backends.tosRegion,
+			AccessKey: backends.tosAccessKey,
+			SecretKey: backends.tosSecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", scheme)
+	}
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}